@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"api/config"
+)
+
+type contextKey string
+
+const bypassContextKey = contextKey("cache_bypass")
+
+// Cache stores the encoded results of the searches by collection
+// the values are bytes so a cache shared by the instances of the API can implement it
+// the implementations must be safe for concurrent use
+type Cache interface {
+	// Get returns the value of the key in the collection, false if it is missing or expired
+	Get(ctx context.Context, collection, key string) ([]byte, bool)
+	// RecordBypass counts a search that skipped Get, the callers check the bypass of the context
+	RecordBypass()
+	// Generation returns the number of invalidations of the collection, it is read before the search of a value
+	Generation(ctx context.Context, collection string) uint64
+	// Set stores the value of the key in the collection if the collection was not invalidated after the generation
+	// the values of the searches started before an invalidation are stale, they are not stored
+	Set(ctx context.Context, collection, key string, generation uint64, value []byte)
+	// Invalidate deletes every value of the collection and starts a new generation
+	Invalidate(ctx context.Context, collection string)
+	// Stats returns the counters of the cache
+	Stats() Stats
+}
+
+// Stats are the counters of a cache since the API started
+type Stats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Bypasses      int64 `json:"bypasses"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
+	Entries       int   `json:"entries"`
+	Capacity      int   `json:"capacity"`
+}
+
+// WithBypass returns a context whose searches skip the lookup in the cache, their results are still stored
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassContextKey, true)
+}
+
+// IsBypassed checks if the context was created by WithBypass
+func IsBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassContextKey).(bool)
+	return bypass
+}
+
+var (
+	searchCache     Cache
+	searchCacheOnce sync.Once
+)
+
+// Search returns the cache of the searches of the config, nil if it is disabled
+func Search() Cache {
+	searchCacheOnce.Do(func() {
+		cfg := config.GetConfig().SearchCache
+		if lru := NewLRU(cfg.Size, cfg.TTL); lru != nil {
+			searchCache = lru
+		}
+	})
+
+	return searchCache
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in process Cache that evicts the least recently used values when it is full
+type LRU struct {
+	size  int
+	ttl   time.Duration
+	mu    sync.Mutex
+	items map[lruKey]*list.Element
+	order *list.List // front is the most recently used
+	stats Stats
+	now   func() time.Time
+	// generations are the invalidations of the collections
+	generations map[string]uint64
+}
+
+// lruKey identifies a value by its collection to invalidate them together
+type lruKey struct {
+	collection string
+	key        string
+}
+
+// lruEntry is a value of the LRU with its expiration
+type lruEntry struct {
+	key       lruKey
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRU creates an LRU of size values kept for ttl, nil if size is 0
+// a ttl of 0 keeps the values until they are evicted or invalidated
+func NewLRU(size int, ttl time.Duration) *LRU {
+	if size <= 0 {
+		return nil
+	}
+
+	return &LRU{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[lruKey]*list.Element),
+		order: list.New(),
+		stats: Stats{Capacity: size},
+		now:   time.Now,
+
+		generations: make(map[string]uint64),
+	}
+}
+
+// Get implements Cache interface
+func (c *LRU) Get(_ context.Context, collection, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[lruKey{collection, key}]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if c.ttl > 0 && !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+
+	return entry.value, true
+}
+
+// RecordBypass implements Cache interface
+func (c *LRU) RecordBypass() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Bypasses++
+}
+
+// Generation implements Cache interface
+func (c *LRU) Generation(_ context.Context, collection string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generations[collection]
+}
+
+// Set implements Cache interface
+func (c *LRU) Set(_ context.Context, collection, key string, generation uint64, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generations[collection] {
+		return
+	}
+
+	id := lruKey{collection, key}
+	expiresAt := c.now().Add(c.ttl)
+
+	if element, ok := c.items[id]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[id] = c.order.PushFront(&lruEntry{key: id, value: value, expiresAt: expiresAt})
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Invalidate implements Cache interface
+func (c *LRU) Invalidate(_ context.Context, collection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, element := range c.items {
+		if id.collection == collection {
+			c.remove(element)
+		}
+	}
+	c.generations[collection]++
+	c.stats.Invalidations++
+}
+
+// Stats implements Cache interface
+func (c *LRU) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+
+	return stats
+}
+
+// remove deletes the element of the list and the map
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU(2, time.Minute)
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(ctx, "emails_hillary", "a", 0, []byte("1"))
+	lru.Set(ctx, "emails_hillary", "b", 0, []byte("2"))
+
+	if value, ok := lru.Get(ctx, "emails_hillary", "a"); !ok || string(value) != "1" {
+		t.Fatalf("expected a hit of a, got %q %v", value, ok)
+	}
+	if _, ok := lru.Get(ctx, "dnc_emails", "a"); ok {
+		t.Error("the keys of other collections must not match")
+	}
+
+	// b is the least recently used
+	lru.Set(ctx, "emails_hillary", "c", 0, []byte("3"))
+	if _, ok := lru.Get(ctx, "emails_hillary", "b"); ok {
+		t.Error("b must be evicted")
+	}
+
+	lru.RecordBypass()
+
+	now = now.Add(time.Minute)
+	if _, ok := lru.Get(ctx, "emails_hillary", "c"); ok {
+		t.Error("c must be expired")
+	}
+
+	expected := Stats{Hits: 1, Misses: 3, Bypasses: 1, Evictions: 1, Entries: 1, Capacity: 2}
+	if stats := lru.Stats(); stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+
+	if NewLRU(0, time.Minute) != nil {
+		t.Error("a size of 0 must disable the cache")
+	}
+}
+
+func TestLRUInvalidate(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU(10, 0)
+
+	lru.Set(ctx, "emails_hillary", "a", 0, []byte("1"))
+	lru.Set(ctx, "emails_hillary", "b", 0, []byte("2"))
+	lru.Set(ctx, "dnc_emails", "a", 0, []byte("3"))
+
+	lru.Invalidate(ctx, "emails_hillary")
+
+	if _, ok := lru.Get(ctx, "emails_hillary", "a"); ok {
+		t.Error("the values of the collection must be invalidated")
+	}
+	if value, ok := lru.Get(ctx, "dnc_emails", "a"); !ok || string(value) != "3" {
+		t.Error("the values of the other collections must be kept")
+	}
+
+	if stats := lru.Stats(); stats.Entries != 1 || stats.Invalidations != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// the value of a search started before the invalidation is stale
+	lru.Set(ctx, "emails_hillary", "a", 0, []byte("1"))
+	if _, ok := lru.Get(ctx, "emails_hillary", "a"); ok {
+		t.Error("the value of a previous generation must not be stored")
+	}
+
+	generation := lru.Generation(ctx, "emails_hillary")
+	if generation != 1 || lru.Generation(ctx, "dnc_emails") != 0 {
+		t.Errorf("unexpected generation %d", generation)
+	}
+	lru.Set(ctx, "emails_hillary", "a", generation, []byte("1"))
+	if _, ok := lru.Get(ctx, "emails_hillary", "a"); !ok {
+		t.Error("the value of the current generation must be stored")
+	}
+}
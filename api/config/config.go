@@ -1,28 +1,103 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Database drivers supported
+const (
+	DriverCockroach = "cockroach"
+	DriverSQLite    = "sqlite"
+)
+
 // Config stores the configuration for the API
 type Config struct {
-	Host            string // Database host
-	Port            int    // Database port
-	User            string // Database user
-	Password        string // Database password
-	DBName          string // Database name
-	SSLMode         bool   // Database SSL mode
-	ClientHost      string // Client host
-	SchemaName      string // Schema name
-	MailsTable      string // Mails table name
-	MailSearchTable string // Mail search table name
-	LogLevel        string // Log level
-	LogDB           bool   // Log database
-	ApiPort         int    // API port
+	Driver            string // Database driver: cockroach or sqlite
+	DBPath            string // File of the sqlite database
+	Host              string // Database host
+	Port              int    // Database port
+	User              string // Database user
+	Password          string // Database password
+	DBName            string // Database name
+	SSLMode           bool   // Database SSL mode
+	ClientHost        string // Client host
+	SchemaName        string // Schema name of the default collection
+	MailsTable        string // Mails table name
+	MailSearchTable   string // Mail search table name
+	SearchConfigTable string // Text search config table name
+	EntitiesTable     string // Entities of the emails table name
+	TagsTable         string // Tags of the emails table name
+	SavedSearchTable  string // Saved searches table name
+	SavedMatchesTable string // Emails matched by the saved searches table name
+	AnnotationsTable  string // Annotations of the emails table name
+	FoldersTable      string // Folders of emails table name
+	FolderItemsTable  string // Emails of the folders table name
+	DataVersionTable  string // Version of the searchable data changed by the indexer table name
+	LogLevel          string // Log level
+	LogDB             bool   // Log database
+	ApiPort           int    // API port
+	HTTP              HTTPConfig
+	Auth              AuthConfig
+	RateLimit         RateLimitConfig
+	SearchCache       SearchCacheConfig
+}
+
+// HTTPConfig stores the limits of the HTTP server
+type HTTPConfig struct {
+	ReadTimeout       time.Duration // Time to read the whole request, body included
+	ReadHeaderTimeout time.Duration // Time to read the headers of the request
+	WriteTimeout      time.Duration // Time to write the response, it bounds the duration of a search
+	IdleTimeout       time.Duration // Time to keep an idle keep-alive connection
+	ShutdownTimeout   time.Duration // Time to drain the requests in flight on shutdown
+	MaxHeaderBytes    int           // Max size of the headers of a request
+	MaxBodyBytes      int64         // Max size of the body of a request
+}
+
+// AuthConfig stores the credentials accepted by the API
+// the tokens are accepted if their key is configured, HS256 with JWTSecret and RS256 with JWTPublicKeyFile
+type AuthConfig struct {
+	Enabled          bool     // Require credentials in the requests, without it every request has all the scopes
+	APIKeys          []APIKey // Static keys of the clients
+	JWTSecret        string   // Secret of the HS256 tokens
+	JWTPublicKeyFile string   // PEM file with the public key of the RS256 tokens
+	JWTIssuers       []string // Accepted issuers of the tokens, empty to accept any issuer
+	JWTAudience      string   // Audience required in the tokens, empty to not check it
+}
+
+// APIKey is a static key of a client with its scopes
+type APIKey struct {
+	Name   string
+	Key    string
+	Scopes []string
+}
+
+// RateLimitConfig stores the limits of the requests, the rates are by client, an API key or an IP
+type RateLimitConfig struct {
+	Default      RateLimit     // Limit of every endpoint of /api
+	Search       RateLimit     // Limit of the searches, the most expensive queries
+	Export       RateLimit     // Limit of the exports of the folders
+	Unauthorized RateLimit     // Limit of the requests with credentials missing or not valid by IP
+	MaxSearches  int           // Searches and exports running at the same time in the API, 0 to not limit them
+	SearchWait   time.Duration // Time a search waits for a free slot before it is rejected
+}
+
+// RateLimit is a token bucket, PerMinute requests by minute with bursts up to Burst, PerMinute 0 disables it
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// SearchCacheConfig stores the cache of the search results
+type SearchCacheConfig struct {
+	Size          int           // Searches kept in the cache, 0 disables it
+	TTL           time.Duration // Time a search is kept in the cache
+	CheckInterval time.Duration // Interval to check the data version of the indexer that invalidates the cache
 }
 
 var config *Config
@@ -41,18 +116,29 @@ func LoadConfig() (*Config, error) {
 
 	ssl := sslstr == "true"
 	config = &Config{
-		Host:            getEnv("DB_HOST", "localhost"),
-		Port:            port,
-		User:            getEnv("DB_USER", "root"),
-		Password:        getEnv("DB_PASSWORD", ""),
-		DBName:          getEnv("DB_NAME", "defaultdb"),
-		SSLMode:         ssl,
-		ClientHost:      getEnv("CLIENT_HOST", "localhost:5173"),
-		SchemaName:      "emails_hillary",
-		MailsTable:      "emails",
-		MailSearchTable: "emails_search",
-		LogLevel:        strings.ToLower(getEnv("LOG_LEVEL", "info")),
-		LogDB:           strings.ToLower(getEnv("LOG_DB", "false")) == "true",
+		Driver:            strings.ToLower(getEnv("DB_DRIVER", DriverCockroach)),
+		DBPath:            getEnv("DB_PATH", "../indexer/data/hillary.db"),
+		Host:              getEnv("DB_HOST", "localhost"),
+		Port:              port,
+		User:              getEnv("DB_USER", "root"),
+		Password:          getEnv("DB_PASSWORD", ""),
+		DBName:            getEnv("DB_NAME", "defaultdb"),
+		SSLMode:           ssl,
+		ClientHost:        getEnv("CLIENT_HOST", "localhost:5173"),
+		SchemaName:        getEnv("DB_SCHEMA", "emails_hillary"),
+		MailsTable:        "emails",
+		MailSearchTable:   "emails_search",
+		SearchConfigTable: "search_config",
+		EntitiesTable:     "email_entities",
+		TagsTable:         "email_tags",
+		SavedSearchTable:  "saved_searches",
+		SavedMatchesTable: "saved_search_matches",
+		AnnotationsTable:  "email_annotations",
+		FoldersTable:      "folders",
+		FolderItemsTable:  "folder_items",
+		DataVersionTable:  "data_version",
+		LogLevel:          strings.ToLower(getEnv("LOG_LEVEL", "info")),
+		LogDB:             strings.ToLower(getEnv("LOG_DB", "false")) == "true",
 	}
 
 	// config apiPort
@@ -64,6 +150,45 @@ func LoadConfig() (*Config, error) {
 
 	config.ApiPort = apiPort
 
+	config.HTTP = HTTPConfig{
+		ReadTimeout:       getEnvDuration("HTTP_READ_TIMEOUT", 10*time.Second),
+		ReadHeaderTimeout: getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:      getEnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       getEnvDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		ShutdownTimeout:   getEnvDuration("HTTP_SHUTDOWN_TIMEOUT", 30*time.Second),
+		MaxHeaderBytes:    int(getEnvInt64("HTTP_MAX_HEADER_BYTES", 1<<20)),
+		MaxBodyBytes:      getEnvInt64("HTTP_MAX_BODY_BYTES", 1<<20),
+	}
+
+	apiKeys, err := parseAPIKeys(getEnv("AUTH_API_KEYS", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	config.Auth = AuthConfig{
+		Enabled:          strings.ToLower(getEnv("AUTH_ENABLED", "false")) == "true",
+		APIKeys:          apiKeys,
+		JWTSecret:        getEnv("AUTH_JWT_HS256_SECRET", ""),
+		JWTPublicKeyFile: getEnv("AUTH_JWT_RS256_PUBLIC_KEY_FILE", ""),
+		JWTIssuers:       splitList(getEnv("AUTH_JWT_ISSUERS", "")),
+		JWTAudience:      getEnv("AUTH_JWT_AUDIENCE", ""),
+	}
+
+	config.RateLimit = RateLimitConfig{
+		Default:      RateLimit{PerMinute: getEnvInt("RATE_LIMIT_DEFAULT", 600), Burst: getEnvInt("RATE_LIMIT_DEFAULT_BURST", 100)},
+		Search:       RateLimit{PerMinute: getEnvInt("RATE_LIMIT_SEARCH", 120), Burst: getEnvInt("RATE_LIMIT_SEARCH_BURST", 20)},
+		Export:       RateLimit{PerMinute: getEnvInt("RATE_LIMIT_EXPORT", 10), Burst: getEnvInt("RATE_LIMIT_EXPORT_BURST", 2)},
+		Unauthorized: RateLimit{PerMinute: getEnvInt("RATE_LIMIT_UNAUTHORIZED", 30), Burst: getEnvInt("RATE_LIMIT_UNAUTHORIZED_BURST", 10)},
+		MaxSearches:  getEnvInt("SEARCH_MAX_CONCURRENT", 50),
+		SearchWait:   getEnvDuration("SEARCH_QUEUE_TIMEOUT", time.Second),
+	}
+
+	config.SearchCache = SearchCacheConfig{
+		Size:          getEnvInt("SEARCH_CACHE_SIZE", 1000),
+		TTL:           getEnvDuration("SEARCH_CACHE_TTL", 5*time.Minute),
+		CheckInterval: getEnvDuration("SEARCH_CACHE_CHECK_INTERVAL", 10*time.Second),
+	}
+
 	if config.MailsTable == "" {
 		panic("MAILS_TABLE not specified")
 	}
@@ -71,6 +196,31 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// Table returns the name of a table of the default collection
+func (c *Config) Table(name string) string {
+	return c.CollectionTable(c.SchemaName, name)
+}
+
+// CollectionTable returns the name of a table of the collection
+// SQLite has no schemas, the indexer prefixes the tables with the schema name
+// The schema is quoted like the indexer creates it, so the collections with uppercase letters keep their case
+func (c *Config) CollectionTable(collection, name string) string {
+	if c.Driver == DriverSQLite {
+		return collection + "_" + name
+	}
+
+	return `"` + collection + `".` + name
+}
+
+// CollectionsTable returns the name of the registry of collections created by the indexer
+func (c *Config) CollectionsTable() string {
+	if c.Driver == DriverSQLite {
+		return "collections"
+	}
+
+	return "public.collections"
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key string, defaultValue string) string {
 	value := os.Getenv(key)
@@ -80,6 +230,70 @@ func getEnv(key string, defaultValue string) string {
 	return value
 }
 
+// parseAPIKeys parses the keys separated by ; with the format name:key:scope,scope
+// ex: frontend:k3y:read;ops:0ther-k3y:read,write
+func parseAPIKeys(value string) ([]APIKey, error) {
+	keys := make([]APIKey, 0)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("AUTH_API_KEYS entry not valid, expected name:key:scopes")
+		}
+
+		keys = append(keys, APIKey{
+			Name:   strings.TrimSpace(parts[0]),
+			Key:    strings.TrimSpace(parts[1]),
+			Scopes: splitList(parts[2]),
+		})
+	}
+
+	return keys, nil
+}
+
+// splitList returns the values separated by commas without spaces and empty values
+func splitList(value string) []string {
+	values := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
+
+// getEnvDuration gets an environment variable as a duration, ex: 30s, or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt gets an environment variable as a number, 0 included, or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt64 gets an environment variable as a positive number or returns a default value
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
 func GetConfig() *Config {
 
 	if config == nil {
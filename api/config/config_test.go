@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestCollectionTable(t *testing.T) {
+	cases := []struct {
+		driver     string
+		collection string
+		want       string
+	}{
+		{DriverCockroach, "mails", `"mails".emails`},
+		{DriverCockroach, "MyCorpus", `"MyCorpus".emails`},
+		{DriverSQLite, "MyCorpus", "MyCorpus_emails"},
+	}
+
+	for _, c := range cases {
+		cfg := &Config{Driver: c.driver}
+		if got := cfg.CollectionTable(c.collection, "emails"); got != c.want {
+			t.Errorf("CollectionTable(%q) with %s = %q, want %q", c.collection, c.driver, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// AnnotationController handles the annotations of the emails
+type AnnotationController struct {
+	AnnotationService services.AnnotationService
+	CollectionService services.CollectionService
+}
+
+// NewAnnotationController creates a new AnnotationController
+func NewAnnotationController(annotationService services.AnnotationService, collectionService services.CollectionService) *AnnotationController {
+	return &AnnotationController{
+		AnnotationService: annotationService,
+		CollectionService: collectionService,
+	}
+}
+
+// ListAnnotations returns the annotations of the email of the {id} URL param
+func (c *AnnotationController) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	empty := models.AnnotationResponse{Annotations: []models.Annotation{}, Total: 0}
+
+	collection, emailID, ok := c.resolveEmail(w, r, empty)
+	if !ok {
+		return
+	}
+
+	annotations, err := c.AnnotationService.ListAnnotations(r.Context(), collection, emailID)
+	if err != nil {
+		writeAnnotationError(w, r, err, empty)
+		return
+	}
+
+	status := models.StatusSuccess
+	if len(annotations.Annotations) == 0 {
+		status = models.StatusNoData
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(status, *annotations, ""))
+}
+
+// CreateAnnotation adds the annotation of the body to the email of the {id} URL param
+func (c *AnnotationController) CreateAnnotation(w http.ResponseWriter, r *http.Request) {
+	var empty *models.Annotation
+
+	collection, emailID, ok := c.resolveEmail(w, r, empty)
+	if !ok {
+		return
+	}
+
+	request, ok := decodeAnnotationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	annotation, err := c.AnnotationService.CreateAnnotation(r.Context(), collection, emailID, request)
+	if err != nil {
+		writeAnnotationError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, annotation, ""))
+}
+
+// UpdateAnnotation replaces the annotation of the {annotationId} URL param with the body
+func (c *AnnotationController) UpdateAnnotation(w http.ResponseWriter, r *http.Request) {
+	var empty *models.Annotation
+
+	collection, emailID, ok := c.resolveEmail(w, r, empty)
+	if !ok {
+		return
+	}
+
+	id, ok := parseAnnotationID(w, r)
+	if !ok {
+		return
+	}
+
+	request, ok := decodeAnnotationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	annotation, err := c.AnnotationService.UpdateAnnotation(r.Context(), collection, emailID, id, request)
+	if err != nil {
+		writeAnnotationError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, annotation, ""))
+}
+
+// DeleteAnnotation removes the annotation of the {annotationId} URL param
+func (c *AnnotationController) DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	var empty *models.Annotation
+
+	collection, emailID, ok := c.resolveEmail(w, r, empty)
+	if !ok {
+		return
+	}
+
+	id, ok := parseAnnotationID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.AnnotationService.DeleteAnnotation(r.Context(), collection, emailID, id); err != nil {
+		writeAnnotationError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, empty, ""))
+}
+
+// resolveEmail returns the collection and the email id of the request
+// writes a 404 response if the collection is not registered and a 400 if the id is not valid
+func (c *AnnotationController) resolveEmail(w http.ResponseWriter, r *http.Request, empty any) (string, uint32, bool) {
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return "", 0, false
+	}
+
+	emailID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil || emailID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The email id is not valid"))
+		return "", 0, false
+	}
+
+	return collection, uint32(emailID), true
+}
+
+// parseAnnotationID reads the {annotationId} URL param, writes a 400 response if it is not valid
+func parseAnnotationID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "annotationId"), 10, 64)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse[*models.Annotation](models.StatusError, nil, "The annotation id is not valid"))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// decodeAnnotationRequest reads and validates the body, writes a 400 response if it is not valid
+// with authentication the author is the subject of the credentials
+func decodeAnnotationRequest(w http.ResponseWriter, r *http.Request) (models.AnnotationRequest, bool) {
+	var request models.AnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse[*models.Annotation](models.StatusError, nil, "The request is not valid"))
+		return request, false
+	}
+
+	if subject, ok := authenticatedSubject(r); ok {
+		request.Author = subject
+	}
+
+	if message := request.Normalize().Validate(); message != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse[*models.Annotation](models.StatusError, nil, message))
+		return request, false
+	}
+
+	return request, true
+}
+
+// writeAnnotationError writes the response of an error of the AnnotationService
+func writeAnnotationError[T any](w http.ResponseWriter, r *http.Request, err error, empty T) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case errors.Is(err, services.ErrEmailNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The email does not exist"))
+	case errors.Is(err, services.ErrAnnotationNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The annotation does not exist"))
+	case errors.Is(err, services.ErrAnnotationRange):
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The range is outside the content of the email"))
+	default:
+		writeServiceError(w, r, err, empty)
+	}
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+
+	"api/cache"
+	"api/models"
+
+	"github.com/go-chi/render"
+)
+
+// CacheController handles the operations of the cache of the searches
+type CacheController struct {
+	Cache cache.Cache
+}
+
+// NewCacheController creates a new CacheController, c is nil if the cache is disabled
+func NewCacheController(c cache.Cache) *CacheController {
+	return &CacheController{
+		Cache: c,
+	}
+}
+
+// GetStats returns the hits, the misses and the size of the cache, all of them are 0 if it is disabled
+func (c *CacheController) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats := cache.Stats{}
+	if c.Cache != nil {
+		stats = c.Cache.Stats()
+	}
+
+	response := models.NewResponse(models.StatusSuccess, stats, "")
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, response)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"net/http"
+
+	"api/config"
+	"api/logger"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CollectionController handles collection-related operations
+type CollectionController struct {
+	CollectionService services.CollectionService
+}
+
+// NewCollectionController creates a new CollectionController
+func NewCollectionController(collectionService services.CollectionService) *CollectionController {
+	return &CollectionController{
+		CollectionService: collectionService,
+	}
+}
+
+// ListCollections returns the collections created by the indexer
+func (c *CollectionController) ListCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := c.CollectionService.ListCollections(r.Context())
+	if err != nil {
+		response := models.NewResponse(models.StatusError, models.CollectionResponse{
+			Collections: []models.Collection{},
+		}, "Internal Server Error")
+
+		logger.Logger().Error().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Err(err).
+			Msg(err.Error())
+
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, response)
+		return
+	}
+
+	if len(collections) == 0 {
+		response := models.NewResponse(models.StatusNoData, models.CollectionResponse{
+			Collections: []models.Collection{},
+		}, "")
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, response)
+		return
+	}
+
+	response := models.NewResponse(models.StatusSuccess, models.CollectionResponse{Collections: collections}, "")
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, response)
+}
+
+// resolveCollection returns the collection of the {name} URL param, without it the default collection
+// writes a 404 response with the empty data if the collection is not registered
+func resolveCollection[T any](w http.ResponseWriter, r *http.Request, collectionService services.CollectionService, empty T) (string, bool) {
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		return config.GetConfig().SchemaName, true
+	}
+
+	exists, err := collectionService.CollectionExists(r.Context(), name)
+	if err != nil {
+		logger.Logger().Error().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Str("collection", name).
+			Err(err).
+			Msg("cannot retrieve collection")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "Internal Server Error"))
+		return "", false
+	}
+
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The collection does not exist"))
+		return "", false
+	}
+
+	return name, true
+}
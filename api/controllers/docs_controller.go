@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+
+	"api/logger"
+	"api/models"
+	"api/openapi"
+
+	"github.com/go-chi/render"
+)
+
+// DocsController serves the OpenAPI document of the API and its docs page
+type DocsController struct {
+	Spec *openapi.Spec
+}
+
+// NewDocsController creates a new DocsController
+func NewDocsController(spec *openapi.Spec) *DocsController {
+	return &DocsController{
+		Spec: spec,
+	}
+}
+
+// GetOpenAPI returns the OpenAPI document as it is maintained in the openapi package
+func (c *DocsController) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.Document())
+}
+
+// GetDocs returns the docs page of the OpenAPI document
+func (c *DocsController) GetDocs(w http.ResponseWriter, r *http.Request) {
+	var page bytes.Buffer
+	if err := openapi.WriteDocs(&page, c.Spec); err != nil {
+		logger.Logger().Error().Err(err).Msg("Cannot write the docs page")
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, models.NewResponse[any](models.StatusError, nil, "Cannot write the docs page"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(page.Bytes())
+}
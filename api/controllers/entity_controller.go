@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"api/middleware"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/render"
+)
+
+// EntityController handles the entities extracted by the indexer
+type EntityController struct {
+	EntityService     services.EntityService
+	CollectionService services.CollectionService
+}
+
+// NewEntityController creates a new EntityController
+func NewEntityController(entityService services.EntityService, collectionService services.CollectionService) *EntityController {
+	return &EntityController{
+		EntityService:     entityService,
+		CollectionService: collectionService,
+	}
+}
+
+// ListEntities returns the people, organizations and places mentioned in the emails
+// filters by ?type= and by the start of the name with ?q=
+func (c *EntityController) ListEntities(w http.ResponseWriter, r *http.Request) {
+	empty := models.EntityResponse{Entities: []models.Entity{}, Total: 0}
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	entityType := strings.ToLower(r.URL.Query().Get("type"))
+	if entityType != "" && !models.IsValidEntityType(entityType) {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The type must be person, organization or place"))
+		return
+	}
+
+	pagination := middleware.GetPaginationFromContext(r.Context())
+	filter := models.EntityFilter{
+		Type:  entityType,
+		Query: r.URL.Query().Get("q"),
+		Page:  pagination.Page,
+		Limit: pagination.Limit,
+	}
+
+	entities, err := c.EntityService.ListEntities(r.Context(), collection, filter)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		writeServiceError(w, r, err, empty)
+		return
+	}
+
+	if len(entities.Entities) == 0 {
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, models.NewResponse(models.StatusNoData, models.EntityResponse{Entities: []models.Entity{}, Total: entities.Total}, ""))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, *entities, ""))
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"api/exporter"
+	"api/logger"
+	"api/middleware"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// changeItemsFunc adds or removes the emails of a folder
+type changeItemsFunc func(ctx context.Context, collection string, id int64, request models.FolderItemsRequest) (*models.Folder, error)
+
+// FolderController handles the folders of emails curated by the users
+type FolderController struct {
+	FolderService     services.FolderService
+	CollectionService services.CollectionService
+}
+
+// NewFolderController creates a new FolderController
+func NewFolderController(folderService services.FolderService, collectionService services.CollectionService) *FolderController {
+	return &FolderController{
+		FolderService:     folderService,
+		CollectionService: collectionService,
+	}
+}
+
+// CreateFolder creates an empty folder with the name of the body
+func (c *FolderController) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	var empty *models.Folder
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	var request models.FolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The request is not valid"))
+		return
+	}
+
+	if !request.Normalize().IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The name is required, up to 100 characters"))
+		return
+	}
+
+	folder, err := c.FolderService.CreateFolder(r.Context(), collection, request)
+	if err != nil {
+		writeFolderError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, folder, ""))
+}
+
+// ListFolders returns the folders of the collection with their number of emails
+func (c *FolderController) ListFolders(w http.ResponseWriter, r *http.Request) {
+	empty := models.FolderResponse{Folders: []models.Folder{}, Total: 0}
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	folders, err := c.FolderService.ListFolders(r.Context(), collection, middleware.GetPaginationFromContext(r.Context()))
+	if err != nil {
+		writeFolderError(w, r, err, empty)
+		return
+	}
+
+	status := models.StatusSuccess
+	if len(folders.Folders) == 0 {
+		status = models.StatusNoData
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(status, *folders, ""))
+}
+
+// GetFolder returns the folder of the {id} URL param with a page of its emails in their order
+func (c *FolderController) GetFolder(w http.ResponseWriter, r *http.Request) {
+	empty := models.FolderMailsResponse{Mails: []models.Email{}}
+
+	collection, id, ok := c.resolveFolder(w, r, empty)
+	if !ok {
+		return
+	}
+
+	mails, err := c.FolderService.ListFolderMails(r.Context(), collection, id, middleware.GetPaginationFromContext(r.Context()))
+	if err != nil {
+		writeFolderError(w, r, err, empty)
+		return
+	}
+
+	status := models.StatusSuccess
+	if len(mails.Mails) == 0 {
+		status = models.StatusNoData
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(status, *mails, ""))
+}
+
+// DeleteFolder removes the folder of the {id} URL param
+func (c *FolderController) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	var empty *models.Folder
+
+	collection, id, ok := c.resolveFolder(w, r, empty)
+	if !ok {
+		return
+	}
+
+	if err := c.FolderService.DeleteFolder(r.Context(), collection, id); err != nil {
+		writeFolderError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, empty, ""))
+}
+
+// AddItems adds the emails of the body to the folder of the {id} URL param
+func (c *FolderController) AddItems(w http.ResponseWriter, r *http.Request) {
+	c.changeItems(w, r, c.FolderService.AddItems)
+}
+
+// RemoveItems removes the emails of the body from the folder of the {id} URL param
+func (c *FolderController) RemoveItems(w http.ResponseWriter, r *http.Request) {
+	c.changeItems(w, r, c.FolderService.RemoveItems)
+}
+
+// ReorderItems moves the emails of the body to the start of the folder of the {id} URL param
+func (c *FolderController) ReorderItems(w http.ResponseWriter, r *http.Request) {
+	var empty *models.Folder
+
+	collection, id, ok := c.resolveFolder(w, r, empty)
+	if !ok {
+		return
+	}
+
+	var request models.FolderOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The request is not valid"))
+		return
+	}
+
+	if message := request.Validate(); message != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, message))
+		return
+	}
+
+	folder, err := c.FolderService.ReorderItems(r.Context(), collection, id, request.IDs)
+	if err != nil {
+		writeFolderError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, folder, ""))
+}
+
+// ExportFolder streams the emails of the folder of the {id} URL param in the ?format= (jsonl, csv or html) as a download
+func (c *FolderController) ExportFolder(w http.ResponseWriter, r *http.Request) {
+	var empty *models.Folder
+
+	collection, id, ok := c.resolveFolder(w, r, empty)
+	if !ok {
+		return
+	}
+
+	format, err := exporter.NewFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The format must be jsonl, csv or html"))
+		return
+	}
+
+	folder, err := c.FolderService.GetFolder(r.Context(), collection, id)
+	if err != nil {
+		writeFolderError(w, r, err, empty)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="folder-%d.%s"`, folder.ID, format))
+	w.WriteHeader(http.StatusOK)
+
+	// the status is already sent, the errors are only logged
+	writer, err := exporter.NewWriter(format, w, folder.Name)
+	if err == nil {
+		err = c.FolderService.StreamFolderMails(r.Context(), collection, id, writer.Write)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logger.Logger().Error().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int64("folder", id).
+			Err(err).
+			Msg("cannot export folder")
+	}
+}
+
+// changeItems validates the folder id and the body and responds with the folder after the change
+func (c *FolderController) changeItems(w http.ResponseWriter, r *http.Request, change changeItemsFunc) {
+	var empty *models.Folder
+
+	collection, id, ok := c.resolveFolder(w, r, empty)
+	if !ok {
+		return
+	}
+
+	var request models.FolderItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The request is not valid"))
+		return
+	}
+
+	if message := request.Validate(); message != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, message))
+		return
+	}
+
+	folder, err := change(r.Context(), collection, id, request)
+	if err != nil {
+		writeFolderError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, folder, ""))
+}
+
+// resolveFolder returns the collection and the folder id of the request
+// writes a 404 response if the collection is not registered and a 400 if the id is not valid
+func (c *FolderController) resolveFolder(w http.ResponseWriter, r *http.Request, empty any) (string, int64, bool) {
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return "", 0, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The folder id is not valid"))
+		return "", 0, false
+	}
+
+	return collection, id, true
+}
+
+// writeFolderError writes the response of an error of the FolderService
+func writeFolderError[T any](w http.ResponseWriter, r *http.Request, err error, empty T) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case errors.Is(err, services.ErrFolderNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The folder does not exist"))
+	case errors.Is(err, services.ErrFolderExists):
+		w.WriteHeader(http.StatusConflict)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "There is already a folder with this name"))
+	case errors.Is(err, services.ErrFolderFull):
+		w.WriteHeader(http.StatusConflict)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "A folder has up to 10000 emails"))
+	case errors.Is(err, services.ErrFolderQueryTooLarge):
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The query matches more than 10000 emails"))
+	case errors.Is(err, services.ErrFolderItemNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The emails are not in the folder"))
+	case errors.Is(err, services.ErrEmailNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The email does not exist"))
+	default:
+		writeServiceError(w, r, err, empty)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	"api/logger"
+	"api/middleware"
 	"api/models"
 	"api/services"
 
@@ -15,22 +17,26 @@ import (
 
 // MailController handles mail-related operations
 type MailController struct {
-	MailService services.EmailService
+	MailService       services.EmailService
+	CollectionService services.CollectionService
 }
 
 // NewMailController creates a new MailController
-func NewMailController(mailService services.EmailService) *MailController {
+func NewMailController(mailService services.EmailService, collectionService services.CollectionService) *MailController {
 	return &MailController{
-		MailService: mailService,
+		MailService:       mailService,
+		CollectionService: collectionService,
 	}
 }
 
 // SearchMails searches for emails based on a query
+// the collection is read from the {name} URL param, without it the default collection is searched
 func (c *MailController) SearchMails(w http.ResponseWriter, r *http.Request) {
-	cancelationToken, cancel := context.WithCancel(r.Context())
-	defer cancel()
+	collection, ok := c.resolveCollection(w, r)
+	if !ok {
+		return
+	}
 
-	var apiError *models.ApiError
 	var query models.QuerySearch
 
 	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
@@ -50,9 +56,76 @@ func (c *MailController) SearchMails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	c.searchMails(w, r, collection, query)
+}
+
+// ListMails searches for emails based on the query params, the GET variant of SearchMails
+// ?q=&type=&from=&to=&dateFrom=&dateTo=&order= with the page and the limit of the Pagination middleware
+// the dates are days as 2012-09-11 or RFC3339 times, the times are days in UTC, both are included
+func (c *MailController) ListMails(w http.ResponseWriter, r *http.Request) {
+	empty := models.MailResponse{Mails: []models.Email{}, Total: 0}
+
+	collection, ok := c.resolveCollection(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	pagination := middleware.GetPaginationFromContext(r.Context())
+	query := models.QuerySearch{
+		Query:      params.Get("q"),
+		TypeSearch: models.TypeSearch(strings.ToUpper(params.Get("type"))),
+		OrderBy:    models.OrderBy(strings.ToLower(params.Get("order"))),
+		From:       params.Get("from"),
+		To:         params.Get("to"),
+		Page:       pagination.Page,
+		Limit:      pagination.Limit,
+	}
+
+	if query.TypeSearch != "" && !query.TypeSearch.Validate() {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The type must be AND or OR"))
+		return
+	}
+
+	if query.OrderBy != "" && !query.OrderBy.Validate() {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The order must be asc or desc"))
+		return
+	}
+
+	var err error
+	if query.DateFrom, err = models.ParseDateParam(params.Get("dateFrom")); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The dateFrom must be a day as 2012-09-11 or an RFC3339 time as 2012-09-11T08:00:00Z"))
+		return
+	}
+
+	if query.DateTo, err = models.ParseDateParam(params.Get("dateTo")); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The dateTo must be a day as 2012-09-11 or an RFC3339 time as 2012-09-11T08:00:00Z"))
+		return
+	}
+
+	if query.DateFrom != nil && query.DateTo != nil && query.DateFrom.After(query.DateTo.Time) {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The dateFrom must be before the dateTo"))
+		return
+	}
+
+	c.searchMails(w, r, collection, query)
+}
+
+// searchMails runs the search of the query in the collection and writes the response
+func (c *MailController) searchMails(w http.ResponseWriter, r *http.Request, collection string, query models.QuerySearch) {
+	cancelationToken, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var apiError *models.ApiError
+
 	query.Normalize()
 
-	getEmailsResponse, err := c.MailService.SearchEmails(cancelationToken, query)
+	getEmailsResponse, err := c.MailService.SearchEmails(cancelationToken, collection, query)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			return
@@ -108,3 +181,9 @@ func (c *MailController) SearchMails(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	render.JSON(w, r, response)
 }
+
+// resolveCollection returns the collection of the request
+// writes a 404 response if the collection is not registered
+func (c *MailController) resolveCollection(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return resolveCollection(w, r, c.CollectionService, models.MailResponse{Mails: []models.Email{}, Total: 0})
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api/middleware"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// SavedSearchController handles the searches saved by the users and their new matches
+type SavedSearchController struct {
+	SavedSearchService services.SavedSearchService
+	CollectionService  services.CollectionService
+}
+
+// NewSavedSearchController creates a new SavedSearchController
+func NewSavedSearchController(savedSearchService services.SavedSearchService, collectionService services.CollectionService) *SavedSearchController {
+	return &SavedSearchController{
+		SavedSearchService: savedSearchService,
+		CollectionService:  collectionService,
+	}
+}
+
+// CreateSavedSearch saves the QuerySearch of the body with its owner and name
+// with authentication the owner is the subject of the credentials, the matches are recorded by the indexer after the next run
+func (c *SavedSearchController) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	var empty *models.SavedSearch
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	var request models.SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The request is not valid"))
+		return
+	}
+
+	if subject, ok := authenticatedSubject(r); ok {
+		request.Owner = subject
+	}
+
+	if !request.Normalize().IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The owner and the name are required, up to 100 characters"))
+		return
+	}
+
+	search, err := c.SavedSearchService.CreateSavedSearch(r.Context(), collection, request)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		if errors.Is(err, services.ErrSavedSearchExists) {
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The owner already has a saved search with this name"))
+			return
+		}
+
+		writeServiceError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, search, ""))
+}
+
+// ListSavedSearches returns the saved searches, of the ?owner= if it is present
+// with authentication only the searches of the subject of the credentials are returned
+func (c *SavedSearchController) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	empty := models.SavedSearchResponse{SavedSearches: []models.SavedSearch{}, Total: 0}
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	owner := strings.TrimSpace(r.URL.Query().Get("owner"))
+	if subject, ok := authenticatedSubject(r); ok {
+		owner = subject
+	}
+	searches, err := c.SavedSearchService.ListSavedSearches(r.Context(), collection, owner, middleware.GetPaginationFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		writeServiceError(w, r, err, empty)
+		return
+	}
+
+	if len(searches.SavedSearches) == 0 {
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, models.NewResponse(models.StatusNoData, models.SavedSearchResponse{SavedSearches: []models.SavedSearch{}, Total: searches.Total}, ""))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, *searches, ""))
+}
+
+// NewMatches returns the emails matched by the saved search of the {id} URL param since the last check
+// the returned emails are marked as seen, ?limit= is the maximum of emails by check
+// with authentication the searches of other owners are not found
+func (c *SavedSearchController) NewMatches(w http.ResponseWriter, r *http.Request) {
+	empty := models.NewMatchesResponse{Mails: []models.Email{}}
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The saved search id is not valid"))
+		return
+	}
+
+	owner, _ := authenticatedSubject(r)
+	matches, err := c.SavedSearchService.NewMatches(r.Context(), collection, id, owner, middleware.GetPaginationFromContext(r.Context()).Limit)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		if errors.Is(err, services.ErrSavedSearchNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The saved search does not exist"))
+			return
+		}
+
+		writeServiceError(w, r, err, empty)
+		return
+	}
+
+	status := models.StatusSuccess
+	if len(matches.Mails) == 0 {
+		status = models.StatusNoData
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(status, *matches, ""))
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api/logger"
+	"api/middleware"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// changeTagsFunc adds or removes the tags of an email
+type changeTagsFunc func(ctx context.Context, collection string, emailID uint32, tags []string) ([]string, error)
+
+// TagController handles the tags added to the emails
+type TagController struct {
+	TagService        services.TagService
+	CollectionService services.CollectionService
+}
+
+// NewTagController creates a new TagController
+func NewTagController(tagService services.TagService, collectionService services.CollectionService) *TagController {
+	return &TagController{
+		TagService:        tagService,
+		CollectionService: collectionService,
+	}
+}
+
+// AddTags adds the tags of the body to the email of the {id} URL param
+func (c *TagController) AddTags(w http.ResponseWriter, r *http.Request) {
+	c.changeTags(w, r, c.TagService.AddTags)
+}
+
+// RemoveTags removes the tags of the body from the email of the {id} URL param
+func (c *TagController) RemoveTags(w http.ResponseWriter, r *http.Request) {
+	c.changeTags(w, r, c.TagService.RemoveTags)
+}
+
+// ListTags returns the tags of the collection with the number of emails
+func (c *TagController) ListTags(w http.ResponseWriter, r *http.Request) {
+	empty := models.TagResponse{Tags: []models.Tag{}, Total: 0}
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	tags, err := c.TagService.ListTags(r.Context(), collection, middleware.GetPaginationFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		writeServiceError(w, r, err, empty)
+		return
+	}
+
+	if len(tags.Tags) == 0 {
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, models.NewResponse(models.StatusNoData, models.TagResponse{Tags: []models.Tag{}, Total: tags.Total}, ""))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, *tags, ""))
+}
+
+// changeTags validates the email id and the body and responds with the tags of the email after the change
+func (c *TagController) changeTags(w http.ResponseWriter, r *http.Request, change changeTagsFunc) {
+	empty := models.EmailTagsResponse{Tags: []string{}}
+
+	collection, ok := resolveCollection(w, r, c.CollectionService, empty)
+	if !ok {
+		return
+	}
+
+	emailID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil || emailID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The email id is not valid"))
+		return
+	}
+	empty.EmailID = uint32(emailID)
+
+	var body models.TagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The request is not valid"))
+		return
+	}
+
+	tags, valid := models.NormalizeTags(body.Tags)
+	if !valid || len(tags) == 0 || len(tags) > models.MaxTagsPerRequest {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The tags must be 1 to 20 names of up to 50 lower case letters, numbers, - or _"))
+		return
+	}
+
+	emailTags, err := change(r.Context(), collection, uint32(emailID), tags)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		if errors.Is(err, services.ErrEmailNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, models.NewResponse(models.StatusError, empty, "The email does not exist"))
+			return
+		}
+
+		writeServiceError(w, r, err, empty)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, models.NewResponse(models.StatusSuccess, models.EmailTagsResponse{EmailID: uint32(emailID), Tags: emailTags}, ""))
+}
+
+// authenticatedSubject returns the subject of the principal of the request, false without authentication
+// the owners and the authors of the authenticated requests are their subjects and not the values of the body
+func authenticatedSubject(r *http.Request) (string, bool) {
+	principal, ok := middleware.GetPrincipalFromContext(r.Context())
+	if !ok || principal.Method == models.AuthMethodNone {
+		return "", false
+	}
+
+	return principal.Subject, true
+}
+
+// writeServiceError logs the error of a service and writes a 500 response with the message of the ApiError
+func writeServiceError[T any](w http.ResponseWriter, r *http.Request, err error, empty T) {
+	message := "Internal Server Error"
+	var apiError *models.ApiError
+	if errors.As(err, &apiError) {
+		message = apiError.Message
+	}
+
+	logger.Logger().Error().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Err(err).
+		Msg(message)
+
+	w.WriteHeader(http.StatusInternalServerError)
+	render.JSON(w, r, models.NewResponse(models.StatusError, empty, message))
+}
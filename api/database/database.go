@@ -9,6 +9,7 @@ import (
 
 	apiLogger "api/logger"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -21,6 +22,8 @@ var (
 
 // DatabaseConfig stores the configuration for the database
 type DatabaseConfig struct {
+	Driver     string // cockroach or sqlite
+	Path       string // file of the sqlite database
 	Host       string
 	Port       int
 	User       string
@@ -31,10 +34,34 @@ type DatabaseConfig struct {
 }
 
 // isValid check if the Host, port, user and db name are not empty
+// for sqlite only the path is required
 func (dc *DatabaseConfig) isValid() bool {
+	if dc.Driver == config.DriverSQLite {
+		return dc.Path != ""
+	}
+
 	return dc.Host != "" && dc.Port != 0 && dc.User != "" && dc.DBName != ""
 }
 
+// dialector returns the gorm dialector and the table prefix of the driver
+// SQLite has no schemas, the tables are prefixed with the schema name
+func (dc *DatabaseConfig) dialector() (gorm.Dialector, string) {
+	if dc.Driver == config.DriverSQLite {
+		dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dc.Path)
+		return sqlite.Open(dsn), dc.SchemaName + "_"
+	}
+
+	sslMode := "disable"
+	if dc.SSLMode {
+		sslMode = "require"
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s search_path='\"%s\"'",
+		dc.Host, dc.Port, dc.User, dc.Password, dc.DBName, sslMode, dc.SchemaName)
+
+	return postgres.Open(dsn), dc.SchemaName + "."
+}
+
 // InitDB initializes the database connection
 func InitDB(cfg DatabaseConfig) (*gorm.DB, error) {
 
@@ -42,11 +69,6 @@ func InitDB(cfg DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("invalid database configuration")
 	}
 
-	sslMode := "disable"
-	if cfg.SSLMode {
-		sslMode = "require"
-	}
-
 	var newLogger logger.Interface = nil
 	fmt.Println("LogDB: ", config.GetConfig().LogDB)
 	if config.GetConfig().LogDB {
@@ -62,12 +84,11 @@ func InitDB(cfg DatabaseConfig) (*gorm.DB, error) {
 		)
 	}
 
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s search_path=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, sslMode, cfg.SchemaName)
+	dialector, tablePrefix := cfg.dialector()
 
-	DB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	DB, err := gorm.Open(dialector, &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
-			TablePrefix: cfg.SchemaName + ".",
+			TablePrefix: tablePrefix,
 		},
 		Logger: newLogger,
 	})
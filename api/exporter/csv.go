@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"api/models"
+)
+
+var csvHeader = []string{"id", "date", "subject", "from", "to", "content"}
+
+// CSVWriter writes the emails as rows of a CSV file with header
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+// NewCSVWriter writes the header and returns a CSVWriter that writes to w
+func NewCSVWriter(w io.Writer) (*CSVWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, fmt.Errorf("error writing csv header: %w", err)
+	}
+
+	return &CSVWriter{writer: writer}, nil
+}
+
+// Write writes the email as a CSV row
+func (w *CSVWriter) Write(email models.Email) error {
+	record := []string{
+		strconv.FormatUint(uint64(email.ID), 10),
+		email.Date.UTC().Format(time.RFC3339),
+		email.Subject,
+		email.From,
+		email.To,
+		email.Content,
+	}
+
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("error writing email %d: %w", email.ID, err)
+	}
+
+	return nil
+}
+
+// Close flushes the writer
+func (w *CSVWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("error flushing export: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"api/models"
+)
+
+// Format represents the output format of an export
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+	FormatHTML  Format = "html"
+)
+
+// NewFormat creates a Format from a string, jsonl if it is empty
+// returns an error if the format is not supported
+func NewFormat(format string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(format)))
+	if f == "" {
+		return FormatJSONL, nil
+	}
+
+	if !f.IsValid() {
+		return "", fmt.Errorf("format not supported: %s", format)
+	}
+
+	return f, nil
+}
+
+func (f Format) IsValid() bool {
+	return f == FormatJSONL ||
+		f == FormatCSV ||
+		f == FormatHTML
+}
+
+// ContentType returns the media type of the responses of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// Writer writes emails to an export destination
+// Write: writes one email
+// Close: writes the end of the export and flushes the pending data, the destination is not closed
+type Writer interface {
+	Write(email models.Email) error
+	Close() error
+}
+
+// NewWriter creates the writer of the format
+// title: name of the export, it is the title of the HTML document
+func NewWriter(format Format, w io.Writer, title string) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return NewJSONLWriter(w), nil
+	case FormatCSV:
+		return NewCSVWriter(w)
+	case FormatHTML:
+		return NewHTMLWriter(w, title)
+	default:
+		return nil, fmt.Errorf("format not supported: %s", format)
+	}
+}
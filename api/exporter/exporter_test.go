@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"api/models"
+)
+
+var testEmail = models.Email{
+	ID:      42,
+	Date:    time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC),
+	Subject: "Libya <update>",
+	From:    "Jake Sullivan",
+	To:      "H",
+	Content: "<script>alert(1)</script>\nFrom the embassy",
+}
+
+func writeExport(t *testing.T, format Format) string {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer, err := NewWriter(format, &buffer, "Libya & Benghazi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Write(testEmail); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.String()
+}
+
+func TestNewFormat(t *testing.T) {
+	ttc := []struct {
+		input    string
+		expected Format
+		valid    bool
+	}{
+		{"", FormatJSONL, true},
+		{" CSV ", FormatCSV, true},
+		{"html", FormatHTML, true},
+		{"mbox", "", false},
+	}
+
+	for _, tt := range ttc {
+		format, err := NewFormat(tt.input)
+		if (err == nil) != tt.valid || format != tt.expected {
+			t.Errorf("NewFormat(%q) = %q, %v", tt.input, format, err)
+		}
+	}
+}
+
+func TestJSONLWriter(t *testing.T) {
+	var email models.Email
+	if err := json.Unmarshal([]byte(writeExport(t, FormatJSONL)), &email); err != nil {
+		t.Fatal(err)
+	}
+
+	if email.ID != testEmail.ID || email.Content != testEmail.Content {
+		t.Errorf("unexpected email %+v", email)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(writeExport(t, FormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 || records[0][0] != "id" || records[1][0] != "42" || records[1][5] != testEmail.Content {
+		t.Errorf("unexpected records %v", records)
+	}
+}
+
+func TestHTMLWriter(t *testing.T) {
+	document := writeExport(t, FormatHTML)
+
+	for _, expected := range []string{"<title>Libya &amp; Benghazi</title>", "Libya &lt;update&gt;", "&lt;script&gt;", `id="email-42"`, "<p>1 emails</p>", "</html>"} {
+		if !strings.Contains(document, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+
+	if strings.Contains(document, "<script>") {
+		t.Error("the content of the email must be escaped")
+	}
+}
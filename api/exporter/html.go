@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"api/models"
+)
+
+// htmlTemplates are the parts of the HTML document, the emails are escaped by html/template
+var htmlTemplates = template.Must(template.New("html").Parse(`{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 0 auto; padding: 1rem; }
+article { border-top: 1px solid #ccc; padding: 1rem 0; }
+dl { display: grid; grid-template-columns: max-content auto; gap: 0.25rem 1rem; }
+dt { font-weight: bold; }
+pre { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Exported on {{.Date}}</p>
+{{end}}{{define "email"}}<article id="email-{{.ID}}">
+<h2>{{.Subject}}</h2>
+<dl>
+<dt>Id</dt><dd>{{.ID}}</dd>
+<dt>Date</dt><dd>{{.Date.UTC.Format "2006-01-02 15:04:05 MST"}}</dd>
+<dt>From</dt><dd>{{.From}}</dd>
+<dt>To</dt><dd>{{.To}}</dd>
+</dl>
+<pre>{{.Content}}</pre>
+</article>
+{{end}}{{define "footer"}}<p>{{.}} emails</p>
+</body>
+</html>
+{{end}}`))
+
+// HTMLWriter writes the emails as a single HTML document that can be opened without the API
+type HTMLWriter struct {
+	buffer *bufio.Writer
+	emails int
+}
+
+// NewHTMLWriter writes the header of the document and returns a HTMLWriter that writes to w
+func NewHTMLWriter(w io.Writer, title string) (*HTMLWriter, error) {
+	buffer := bufio.NewWriter(w)
+	header := struct {
+		Title string
+		Date  string
+	}{Title: title, Date: time.Now().UTC().Format("2006-01-02 15:04 MST")}
+
+	if err := htmlTemplates.ExecuteTemplate(buffer, "header", header); err != nil {
+		return nil, fmt.Errorf("error writing html header: %w", err)
+	}
+
+	return &HTMLWriter{buffer: buffer}, nil
+}
+
+// Write writes the email as an article of the document
+func (w *HTMLWriter) Write(email models.Email) error {
+	if err := htmlTemplates.ExecuteTemplate(w.buffer, "email", email); err != nil {
+		return fmt.Errorf("error writing email %d: %w", email.ID, err)
+	}
+
+	w.emails++
+	return nil
+}
+
+// Close writes the end of the document and flushes the buffer
+func (w *HTMLWriter) Close() error {
+	if err := htmlTemplates.ExecuteTemplate(w.buffer, "footer", w.emails); err != nil {
+		return fmt.Errorf("error writing html footer: %w", err)
+	}
+
+	if err := w.buffer.Flush(); err != nil {
+		return fmt.Errorf("error flushing export: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"api/models"
+)
+
+// JSONLWriter writes one email per line in JSON format
+type JSONLWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewJSONLWriter returns a JSONLWriter that writes to w
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	buffer := bufio.NewWriter(w)
+	return &JSONLWriter{buffer: buffer, encoder: json.NewEncoder(buffer)}
+}
+
+// Write writes the email as a JSON line
+func (w *JSONLWriter) Write(email models.Email) error {
+	if err := w.encoder.Encode(email); err != nil {
+		return fmt.Errorf("error encoding email %d: %w", email.ID, err)
+	}
+
+	return nil
+}
+
+// Close flushes the buffer
+func (w *JSONLWriter) Close() error {
+	if err := w.buffer.Flush(); err != nil {
+		return fmt.Errorf("error flushing export: %w", err)
+	}
+
+	return nil
+}
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"api/config"
 	"api/database"
 	"api/logger"
@@ -18,6 +23,8 @@ func main() {
 
 	// Setup routes
 	db, err := database.InitDB(database.DatabaseConfig{
+		Driver:     config.Driver,
+		Path:       config.DBPath,
 		Host:       config.Host,
 		Port:       config.Port,
 		User:       config.User,
@@ -31,9 +38,32 @@ func main() {
 		panic(err)
 	}
 
+	// the pool is closed after the requests in flight are drained
 	defer database.CloseDB()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	server := server.NewServer(db, config.ApiPort)
-	server.Start()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			logger.Logger().Error().Err(err).Msg("Server stopped")
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	logger.Logger().Info().Msg("Shutting down the server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.HTTP.ShutdownTimeout)
+	defer cancel()
 
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Logger().Error().Err(err).Msg("Failed to drain the requests in flight")
+	}
 }
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"api/config"
+	"api/models"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	principalContextKey = contextKey("principal")
+	// APIKeyHeader is the header of the API keys, they are also accepted as Bearer tokens
+	APIKeyHeader = "X-API-Key"
+	// minJWTSecretLength is the length in bytes of the secret of the HS256 tokens
+	minJWTSecretLength = 32
+)
+
+// errInvalidCredentials is returned when the API key or the token are not accepted
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator checks the API keys and the JWTs of the requests
+type Authenticator struct {
+	enabled   bool
+	apiKeys   []apiKey
+	jwt       jwtVerifier
+	anonymous models.Principal
+}
+
+// apiKey is a configured key stored as its SHA-256 to compare it in constant time
+type apiKey struct {
+	hash      [sha256.Size]byte
+	principal models.Principal
+}
+
+// NewAuthenticator creates the Authenticator of the config
+// returns an error if a scope is not valid, the secret is too short or the public key can't be read
+func NewAuthenticator(cfg config.AuthConfig) (*Authenticator, error) {
+	auth := &Authenticator{
+		enabled: cfg.Enabled,
+		jwt: jwtVerifier{
+			issuers:  cfg.JWTIssuers,
+			audience: cfg.JWTAudience,
+			now:      time.Now,
+		},
+		// without authentication every request has all the scopes, as before the authentication existed
+		anonymous: models.Principal{Subject: "anonymous", Method: models.AuthMethodNone, Scopes: []string{models.ScopeAdmin}},
+	}
+
+	for _, key := range cfg.APIKeys {
+		for _, scope := range key.Scopes {
+			if !models.IsValidScope(scope) {
+				return nil, fmt.Errorf("scope %q of the API key %s not valid", scope, key.Name)
+			}
+		}
+
+		auth.apiKeys = append(auth.apiKeys, apiKey{
+			hash:      sha256.Sum256([]byte(key.Key)),
+			principal: models.Principal{Subject: key.Name, Method: models.AuthMethodAPIKey, Scopes: key.Scopes},
+		})
+	}
+
+	if cfg.JWTSecret != "" {
+		if len(cfg.JWTSecret) < minJWTSecretLength {
+			return nil, fmt.Errorf("AUTH_JWT_HS256_SECRET must have at least %d bytes", minJWTSecretLength)
+		}
+		auth.jwt.secret = []byte(cfg.JWTSecret)
+	}
+
+	if cfg.JWTPublicKeyFile != "" {
+		key, err := readRSAPublicKey(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		auth.jwt.publicKey = key
+	}
+
+	if auth.enabled && len(auth.apiKeys) == 0 && auth.jwt.secret == nil && auth.jwt.publicKey == nil {
+		return nil, errors.New("AUTH_ENABLED needs AUTH_API_KEYS, AUTH_JWT_HS256_SECRET or AUTH_JWT_RS256_PUBLIC_KEY_FILE")
+	}
+
+	return auth, nil
+}
+
+// Authenticate is a middleware that puts the principal of the credentials of the request in the context
+// the credentials are an API key in X-API-Key or a Bearer token, a JWT or an API key
+// responds 401 if the credentials are missing or not valid, when the authentication is disabled every request is anonymous
+// every 401 takes a token of the IP in failures, an IP without tokens gets a 429 before its credentials are checked,
+// the credentials can't be guessed faster than the limit, a nil limiter doesn't limit them
+func Authenticate(auth *Authenticator, failures *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !auth.enabled {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey, auth.anonymous)))
+				return
+			}
+
+			if failures != nil {
+				if ok, retryAfter := failures.Check(ipKey(r)); !ok {
+					writeTooManyRequests(w, r, retryAfter, "Too many requests with credentials not valid, retry later")
+					return
+				}
+			}
+
+			unauthorized := func(message string) {
+				if failures != nil {
+					failures.Allow(ipKey(r))
+				}
+				writeUnauthorized(w, r, message)
+			}
+
+			credentials := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+			if credentials == "" {
+				scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+				if found && strings.EqualFold(scheme, "Bearer") {
+					credentials = strings.TrimSpace(token)
+				}
+			}
+
+			if credentials == "" {
+				unauthorized("The credentials are required")
+				return
+			}
+
+			principal, err := auth.Principal(credentials)
+			if err != nil {
+				unauthorized("The credentials are not valid")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey, principal)))
+		})
+	}
+}
+
+// RequireScope is a middleware that responds 403 if the principal of the context doesn't have the scope
+// and 401 if there is no principal, it must be used after Authenticate
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := GetPrincipalFromContext(r.Context())
+			if !ok {
+				writeUnauthorized(w, r, "The credentials are required")
+				return
+			}
+
+			if !principal.HasScope(scope) {
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, models.NewResponse[any](models.StatusError, nil, "The credentials don't have the scope "+scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetPrincipalFromContext retrieves the principal put in the context by Authenticate
+func GetPrincipalFromContext(ctx context.Context) (models.Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey).(models.Principal)
+	return principal, ok
+}
+
+// Principal returns the principal of an API key or a JWT
+// the credentials are compared with the API keys first, the values with the three parts of a JWT that are not a key are verified as tokens
+func (a *Authenticator) Principal(credentials string) (models.Principal, error) {
+	hash := sha256.Sum256([]byte(credentials))
+	for _, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], key.hash[:]) == 1 {
+			return key.principal, nil
+		}
+	}
+
+	if strings.Count(credentials, ".") == 2 {
+		return a.jwt.verify(credentials)
+	}
+
+	return models.Principal{}, errInvalidCredentials
+}
+
+// writeUnauthorized writes a 401 response with the Bearer challenge
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	render.JSON(w, r, models.NewResponse[any](models.StatusError, nil, message))
+}
+
+// readRSAPublicKey reads the RSA public key of a PEM file
+func readRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read AUTH_JWT_RS256_PUBLIC_KEY_FILE: %w", err)
+	}
+
+	key, err := parseRSAPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("AUTH_JWT_RS256_PUBLIC_KEY_FILE not valid: %w", err)
+	}
+
+	return key, nil
+}
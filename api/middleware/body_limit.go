@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"api/models"
+
+	"github.com/go-chi/render"
+)
+
+// BodyLimit rejects the requests with a body bigger than limit bytes
+// the requests without Content-Length are cut at the limit, decoding the rest of the body fails
+func BodyLimit(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, models.NewResponse[any](models.StatusError, nil, "The request body is too large"))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	handler := BodyLimit(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		body   io.Reader
+		length int64
+		status int
+	}{
+		{"small body", strings.NewReader("{}"), 2, http.StatusOK},
+		{"content length too large", strings.NewReader(`{"from":"someone"}`), 18, http.StatusRequestEntityTooLarge},
+		{"unknown length too large", strings.NewReader(`{"from":"someone"}`), -1, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/mails/search", tt.body)
+			r.ContentLength = tt.length
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
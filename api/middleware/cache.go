@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"api/cache"
+	"api/config"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// CacheBypass is a middleware that bypasses the cache of the searches of the requests with Cache-Control no-cache or no-store
+// the results of the bypassed searches are still stored in the cache, the next searches get them
+func CacheBypass(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive == "no-cache" || directive == "no-store" {
+				r = r.WithContext(cache.WithBypass(r.Context()))
+				break
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// InvalidateCache is a middleware that invalidates the cache of the collection of the request after a successful response
+// it is used in the endpoints that change the tags, the annotations or the folders returned and filtered by the searches
+// the collection is read from the {name} URL param, without it the default collection, a nil cache doesn't invalidate
+func InvalidateCache(c cache.Cache) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if c == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() >= 400 {
+				return
+			}
+
+			collection := chi.URLParam(r, "name")
+			if collection == "" {
+				collection = config.GetConfig().SchemaName
+			}
+			c.Invalidate(r.Context(), collection)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api/cache"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestCacheBypass(t *testing.T) {
+	handler := CacheBypass(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cache.IsBypassed(r.Context()) {
+			w.Header().Set("X-Bypassed", "true")
+		}
+	}))
+
+	tests := []struct {
+		value    string
+		bypassed bool
+	}{
+		{"", false},
+		{"max-age=0", false},
+		{"no-cache", true},
+		{"max-age=0, No-Store", true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/mails/search", nil)
+		r.Header.Set("Cache-Control", tt.value)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+		if bypassed := w.Header().Get("X-Bypassed") == "true"; bypassed != tt.bypassed {
+			t.Errorf("Cache-Control %q: expected bypass %v, got %v", tt.value, tt.bypassed, bypassed)
+		}
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(10, time.Minute)
+
+	router := chi.NewRouter()
+	router.With(InvalidateCache(lru)).Post("/collections/{name}/mails/{id}/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	lru.Set(ctx, "dnc_emails", "libya", 0, []byte("{}"))
+	lru.Set(ctx, "emails_hillary", "libya", 0, []byte("{}"))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/collections/dnc_emails/mails/1/tags?fail=true", nil))
+	if _, ok := lru.Get(ctx, "dnc_emails", "libya"); !ok {
+		t.Fatal("the failed requests must not invalidate the cache")
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/collections/dnc_emails/mails/1/tags", nil))
+	if _, ok := lru.Get(ctx, "dnc_emails", "libya"); ok {
+		t.Error("the cache of the collection must be invalidated")
+	}
+	if _, ok := lru.Get(ctx, "emails_hillary", "libya"); !ok {
+		t.Error("the cache of the other collections must be kept")
+	}
+}
@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"api/models"
+)
+
+// jwtLeeway is the clock difference accepted in the expiration and the start of the tokens
+const jwtLeeway = 30 * time.Second
+
+// jwtVerifier verifies the signature and the claims of the HS256 and RS256 tokens
+// an algorithm is only accepted if its key is configured
+type jwtVerifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	issuers   []string
+	audience  string
+	now       func() time.Time
+}
+
+// jwtHeader is the header of a token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+}
+
+// jwtClaims are the claims of a token read by the API
+// the scopes are in scope separated by spaces or in the scp list
+type jwtClaims struct {
+	Issuer    string      `json:"iss"`
+	Subject   string      `json:"sub"`
+	Audience  jwtAudience `json:"aud"`
+	ExpiresAt *float64    `json:"exp"`
+	NotBefore *float64    `json:"nbf"`
+	Scope     string      `json:"scope"`
+	Scp       []string    `json:"scp"`
+}
+
+// jwtAudience is the aud claim, a string or a list of strings
+type jwtAudience []string
+
+// UnmarshalJSON reads the audience as a string or a list
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+
+	return nil
+}
+
+// verify checks the token and returns its principal
+// the token must have sub and exp, iss must be one of the issuers and aud must have the audience if they are configured
+func (v jwtVerifier) verify(token string) (models.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return models.Principal{}, errInvalidCredentials
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return models.Principal{}, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return models.Principal{}, fmt.Errorf("%w: signature encoding", errInvalidCredentials)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Algorithm == "HS256" && v.secret != nil:
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return models.Principal{}, fmt.Errorf("%w: signature", errInvalidCredentials)
+		}
+	case header.Algorithm == "RS256" && v.publicKey != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return models.Principal{}, fmt.Errorf("%w: signature", errInvalidCredentials)
+		}
+	default:
+		return models.Principal{}, fmt.Errorf("%w: algorithm %q not accepted", errInvalidCredentials, header.Algorithm)
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return models.Principal{}, err
+	}
+
+	if err := v.checkClaims(claims); err != nil {
+		return models.Principal{}, err
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	scopes = append(scopes, claims.Scp...)
+
+	return models.Principal{Subject: claims.Subject, Method: models.AuthMethodJWT, Scopes: scopes}, nil
+}
+
+// checkClaims checks the subject, the dates, the issuer and the audience of the token
+func (v jwtVerifier) checkClaims(claims jwtClaims) error {
+	now := v.now()
+
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: missing sub", errInvalidCredentials)
+	}
+
+	if claims.ExpiresAt == nil || now.After(unixTime(*claims.ExpiresAt).Add(jwtLeeway)) {
+		return fmt.Errorf("%w: expired", errInvalidCredentials)
+	}
+
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(unixTime(*claims.NotBefore)) {
+		return fmt.Errorf("%w: not valid yet", errInvalidCredentials)
+	}
+
+	if len(v.issuers) > 0 && !slices.Contains(v.issuers, claims.Issuer) {
+		return fmt.Errorf("%w: issuer %q", errInvalidCredentials, claims.Issuer)
+	}
+
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) {
+		return fmt.Errorf("%w: audience", errInvalidCredentials)
+	}
+
+	return nil
+}
+
+// decodeJWTPart decodes a base64url JSON part of a token
+func decodeJWTPart(part string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: encoding", errInvalidCredentials)
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("%w: json", errInvalidCredentials)
+	}
+
+	return nil
+}
+
+// unixTime converts the seconds of a NumericDate to a time
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// parseRSAPublicKey parses a PEM public key, PKIX (PUBLIC KEY), PKCS #1 (RSA PUBLIC KEY) or a certificate
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := certificate.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("the certificate doesn't have an RSA key")
+		}
+
+		return key, nil
+	default:
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("the key is not an RSA key")
+		}
+
+		return key, nil
+	}
+}
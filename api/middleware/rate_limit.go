@@ -0,0 +1,256 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"api/config"
+	"api/models"
+
+	"github.com/go-chi/render"
+)
+
+// bucketSweepInterval is the interval to delete the buckets of the clients that are not sending requests
+const bucketSweepInterval = time.Minute
+
+// RateLimiter limits the requests of every client with a token bucket
+type RateLimiter struct {
+	rate      float64 // tokens by second
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket stores the tokens left of a client and the last time they were refilled
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter creates the RateLimiter of the limit, nil if the limit is disabled
+func NewRateLimiter(limit config.RateLimit) *RateLimiter {
+	if limit.PerMinute <= 0 {
+		return nil
+	}
+
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    float64(limit.PerMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token of the client, it returns false with the time until the next token if there are none
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// Check returns false with the time until the next token if the client has no tokens, it doesn't take one
+func (l *RateLimiter) Check(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	return true, 0
+}
+
+// refill returns the bucket of the client with the tokens of the time since its last refill, it must be called with the lock
+func (l *RateLimiter) refill(key string) *bucket {
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	return b
+}
+
+// sweep deletes the buckets that are full again, a new bucket of the client is the same
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ConcurrencyLimiter limits the requests running at the same time in the whole API
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewConcurrencyLimiter creates a ConcurrencyLimiter of limit requests, nil if limit is 0
+// a request waits up to wait for a free slot
+func NewConcurrencyLimiter(limit int, wait time.Duration) *ConcurrencyLimiter {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &ConcurrencyLimiter{slots: make(chan struct{}, limit), wait: wait}
+}
+
+// acquire takes a slot, it returns false if there is no free slot before the wait or ctx is done
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.wait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees the slot of a request
+func (l *ConcurrencyLimiter) release() {
+	<-l.slots
+}
+
+// RateLimit is a middleware that responds 429 when the client has no tokens in the limiter, a nil limiter doesn't limit
+// the clients are the principals of Authenticate, the IP without authentication
+func RateLimit(limiter *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := limiter.Allow(clientKey(r)); !ok {
+				writeTooManyRequests(w, r, retryAfter, "Too many requests, retry later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ConcurrencyLimit is a middleware that responds 429 when the limiter has no free slot, a nil limiter doesn't limit
+func ConcurrencyLimit(limiter *ConcurrencyLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.acquire(r.Context()) {
+				writeTooManyRequests(w, r, time.Second, "Too many searches running, retry later")
+				return
+			}
+			defer limiter.release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RouteLimits are the limits of the groups of routes, the limiters are shared by the routes of every collection
+// Default is for every endpoint, Search and Export add their rate and the concurrency limit
+// Unauthorized limits the requests rejected by Authenticate by IP, nil if it is disabled
+type RouteLimits struct {
+	Default      func(http.Handler) http.Handler
+	Search       func(http.Handler) http.Handler
+	Export       func(http.Handler) http.Handler
+	Unauthorized *RateLimiter
+}
+
+// NewRouteLimits creates the RouteLimits of the config
+func NewRouteLimits(cfg config.RateLimitConfig) *RouteLimits {
+	concurrency := ConcurrencyLimit(NewConcurrencyLimiter(cfg.MaxSearches, cfg.SearchWait))
+	search := RateLimit(NewRateLimiter(cfg.Search))
+	export := RateLimit(NewRateLimiter(cfg.Export))
+
+	return &RouteLimits{
+		Default: RateLimit(NewRateLimiter(cfg.Default)),
+		Search: func(next http.Handler) http.Handler {
+			return search(concurrency(next))
+		},
+		Export: func(next http.Handler) http.Handler {
+			return export(concurrency(next))
+		},
+		Unauthorized: NewRateLimiter(cfg.Unauthorized),
+	}
+}
+
+// clientKey returns the principal of the request or its IP
+func clientKey(r *http.Request) string {
+	if principal, ok := GetPrincipalFromContext(r.Context()); ok && principal.Method != models.AuthMethodNone {
+		return principal.Method + ":" + principal.Subject
+	}
+
+	return ipKey(r)
+}
+
+// ipKey returns the IP of the connection of the request
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// writeTooManyRequests writes a 429 response with the seconds to wait, at least 1, in Retry-After and in the data
+func writeTooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	render.JSON(w, r, models.NewResponse(models.StatusError, models.RateLimitResponse{RetryAfter: seconds}, message))
+}
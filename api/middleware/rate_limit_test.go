@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api/config"
+	"api/models"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimit{PerMinute: 60, Burst: 2})
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("ip:10.0.0.1"); !ok {
+			t.Fatalf("request %d of the burst was rejected", i+1)
+		}
+	}
+
+	ok, retryAfter := limiter.Allow("ip:10.0.0.1")
+	if ok || retryAfter != time.Second {
+		t.Errorf("expected a rejection for 1s, got %v %s", ok, retryAfter)
+	}
+
+	if ok, _ := limiter.Allow("ip:10.0.0.2"); !ok {
+		t.Error("the other clients must have their own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := limiter.Allow("ip:10.0.0.1"); !ok {
+		t.Error("the bucket must be refilled after a second")
+	}
+
+	if NewRateLimiter(config.RateLimit{PerMinute: 0}) != nil {
+		t.Error("a rate of 0 must disable the limiter")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(NewRateLimiter(config.RateLimit{PerMinute: 1, Burst: 1}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/mails/search", nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		if key != "" {
+			r = r.WithContext(context.WithValue(r.Context(), principalContextKey, models.Principal{Subject: key, Method: models.AuthMethodAPIKey}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := request(""); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	w := request("")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected status 429 with Retry-After 60, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	var body models.Response[models.RateLimitResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Msg != models.StatusError || body.Data.RetryAfter != 60 {
+		t.Errorf("unexpected body %+v", body)
+	}
+
+	// the API keys have their own bucket from the same IP
+	if w := request("frontend"); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for the API key, got %d", w.Code)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := ConcurrencyLimit(NewConcurrencyLimiter(1, 10*time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/mails/search", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/mails/search", nil))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected status 429 while the slot is taken, got %d", w.Code)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected status 200 for the running search, got %d", code)
+	}
+}
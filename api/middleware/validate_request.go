@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"api/models"
+	"api/openapi"
+
+	"github.com/go-chi/render"
+)
+
+// ValidateRequest is a middleware that rejects the JSON bodies that don't match the schema of their operation in the spec
+// the body is read and given again to the handler, the requests of the paths and methods not in the spec pass as they are
+func ValidateRequest(spec *openapi.Spec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			operation, ok := spec.FindOperation(r.Method, r.URL.Path)
+			if !ok || operation.JSONBody() == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+					render.JSON(w, r, models.NewResponse[any](models.StatusError, nil, "The request body is too large"))
+					return
+				}
+
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, models.NewResponse[any](models.StatusError, nil, "The request is not valid"))
+				return
+			}
+
+			if err := spec.ValidateJSON(operation.JSONBody(), body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				render.JSON(w, r, models.NewResponse[any](models.StatusError, nil, "The request is not valid: body "+err.Error()))
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"api/openapi"
+)
+
+func TestValidateRequest(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received string
+	handler := BodyLimit(64)(ValidateRequest(spec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		length   int64
+		status   int
+		expected string
+	}{
+		{"valid body", http.MethodPost, "/api/mails/search", `{"query":"libya","limit":20}`, 28, http.StatusOK, `{"query":"libya","limit":20}`},
+		{"valid body of a collection", http.MethodPost, "/api/collections/dnc_emails/folders", `{"name":"Libya"}`, 16, http.StatusOK, `{"name":"Libya"}`},
+		{"unknown field of the search", http.MethodPost, "/api/mails/search", `{"querys":"libya"}`, 18, http.StatusOK, `{"querys":"libya"}`},
+		{"unknown field", http.MethodPost, "/api/folders", `{"names":"Libya"}`, 17, http.StatusBadRequest, ""},
+		{"required field", http.MethodPost, "/api/mails/1/annotations", `{"author":"analyst"}`, 20, http.StatusBadRequest, ""},
+		{"empty body", http.MethodPut, "/api/folders/1/items/order", "", 0, http.StatusBadRequest, ""},
+		{"body too large", http.MethodPost, "/api/mails/search", `{"query":"` + strings.Repeat("a", 64) + `"}`, -1, http.StatusRequestEntityTooLarge, ""},
+		{"operation without body", http.MethodGet, "/api/tags", "", 0, http.StatusOK, ""},
+		{"path not in the document", http.MethodPost, "/api/unknown", `not json`, 8, http.StatusOK, "not json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.ContentLength = tt.length
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if received != tt.expected {
+				t.Errorf("the handler received %q, expected %q", received, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of the annotations
+const (
+	maxAnnotationAuthorLength = 100
+	maxAnnotationNoteLength   = 10000
+)
+
+// Annotation represents a note on an email or on a range of characters of its content
+// Start and End are the range of characters of the content, nil if the note is on the whole email
+type Annotation struct {
+	ID        int64     `json:"id"`
+	EmailID   uint32    `json:"emailId"`
+	Author    string    `json:"author"`
+	Note      string    `json:"note"`
+	Start     *int      `json:"start,omitempty" gorm:"column:start_offset"`
+	End       *int      `json:"end,omitempty" gorm:"column:end_offset"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AnnotationRequest is the body to create or update an annotation
+type AnnotationRequest struct {
+	Author string `json:"author"`
+	Note   string `json:"note"`
+	Start  *int   `json:"start"`
+	End    *int   `json:"end"`
+}
+
+// AnnotationResponse is the response type for annotation operations
+type AnnotationResponse struct {
+	Annotations []Annotation `json:"annotations"`
+	Total       int64        `json:"total"`
+}
+
+// Normalize removes the spaces around the author and the note
+func (r *AnnotationRequest) Normalize() *AnnotationRequest {
+	r.Author = strings.TrimSpace(r.Author)
+	r.Note = strings.TrimSpace(r.Note)
+	return r
+}
+
+// Validate returns the message of the first field that is not valid, empty if the request is valid
+// the range is checked against the length of the content by the service
+func (r *AnnotationRequest) Validate() string {
+	if r.Author == "" || utf8.RuneCountInString(r.Author) > maxAnnotationAuthorLength {
+		return "The author is required, up to 100 characters"
+	}
+
+	if r.Note == "" || utf8.RuneCountInString(r.Note) > maxAnnotationNoteLength {
+		return "The note is required, up to 10000 characters"
+	}
+
+	if (r.Start == nil) != (r.End == nil) {
+		return "The range needs the start and the end"
+	}
+
+	if r.Start != nil && (*r.Start < 0 || *r.End <= *r.Start) {
+		return "The start of the range must be positive and lower than the end"
+	}
+
+	return ""
+}
@@ -0,0 +1,33 @@
+package models_test
+
+import (
+	"testing"
+
+	"api/models"
+)
+
+func TestAnnotationRequestValidate(t *testing.T) {
+	zero, four, ten := 0, 4, 10
+
+	ttc := []struct {
+		name    string
+		request models.AnnotationRequest
+		valid   bool
+	}{
+		{"must accept a note on the email", models.AnnotationRequest{Author: "analyst", Note: "follow up"}, true},
+		{"must accept a range", models.AnnotationRequest{Author: "analyst", Note: "follow up", Start: &zero, End: &four}, true},
+		{"must require the author", models.AnnotationRequest{Author: " ", Note: "follow up"}, false},
+		{"must require the note", models.AnnotationRequest{Author: "analyst"}, false},
+		{"must require the end of the range", models.AnnotationRequest{Author: "analyst", Note: "follow up", Start: &four}, false},
+		{"must reject an empty range", models.AnnotationRequest{Author: "analyst", Note: "follow up", Start: &ten, End: &four}, false},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			message := tt.request.Normalize().Validate()
+			if (message == "") != tt.valid {
+				t.Errorf("Validate returned %q, expected valid %v", message, tt.valid)
+			}
+		})
+	}
+}
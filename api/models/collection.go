@@ -0,0 +1,26 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// collectionNamePattern matches the names of the schemas created by the indexer
+var collectionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// Collection represents a corpus of emails indexed in its own schema
+type Collection struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// IsValidCollectionName checks the name can be used as a schema name
+func IsValidCollectionName(name string) bool {
+	return collectionNamePattern.MatchString(name)
+}
+
+// CollectionResponse is the response type for collection operations
+type CollectionResponse struct {
+	Collections []Collection `json:"collections"`
+}
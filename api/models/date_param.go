@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// dateOnlyLayout is the layout of the dates without time of the query params
+const dateOnlyLayout = "2006-01-02"
+
 // DateParam represents a date parameter with validation
 type DateParam struct {
 	time.Time
@@ -31,3 +34,26 @@ func (dp *DateParam) UnmarshalJSON(b []byte) error {
 	dp.Valid = true
 	return nil
 }
+
+// ParseDateParam parses a date of a query param, a day as 2012-09-11 or an RFC3339 time, the time is in UTC
+// an empty value is not a date, it returns nil
+func ParseDateParam(value string) (*DateParam, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(dateOnlyLayout, value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &DateParam{Time: t.UTC(), Valid: true}, nil
+}
+
+// Day returns the day of the date in UTC, the times with an offset are days in UTC as the dates of the emails
+func (dp DateParam) Day() string {
+	return dp.UTC().Format(dateOnlyLayout)
+}
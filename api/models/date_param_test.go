@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateParam(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected *time.Time
+		day      string
+		valid    bool
+	}{
+		{"", nil, "", true},
+		{"2012-09-11", timePtr(time.Date(2012, 9, 11, 0, 0, 0, 0, time.UTC)), "2012-09-11", true},
+		{"2012-09-11T22:00:00Z", timePtr(time.Date(2012, 9, 11, 22, 0, 0, 0, time.UTC)), "2012-09-11", true},
+		{"2012-09-11T22:00:00-04:00", timePtr(time.Date(2012, 9, 12, 2, 0, 0, 0, time.UTC)), "2012-09-12", true},
+		{"11/09/2012", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			date, err := ParseDateParam(tt.value)
+			if (err == nil) != tt.valid {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if tt.expected == nil {
+				if date != nil {
+					t.Errorf("expected no date, got %v", date)
+				}
+				return
+			}
+
+			if date == nil || !date.Valid || !date.Equal(*tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, date)
+			}
+
+			if date != nil && date.Day() != tt.day {
+				t.Errorf("expected the day %s, got %s", tt.day, date.Day())
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
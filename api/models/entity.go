@@ -0,0 +1,66 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Types of the entities extracted by the indexer
+const (
+	EntityTypePerson       = "person"
+	EntityTypeOrganization = "organization"
+	EntityTypePlace        = "place"
+)
+
+// entityFilterPattern matches the entity: filters of a query, entity:Libya or entity:"Cheryl Mills"
+var entityFilterPattern = regexp.MustCompile(`entity:(?:"([^"]*)"|(\S+))`)
+
+// Entity represents a person, an organization or a place mentioned in the emails
+// Emails is the number of emails that mention the entity
+type Entity struct {
+	Entity string `json:"entity"`
+	Type   string `json:"type"`
+	Emails int64  `json:"emails"`
+}
+
+// EntityFilter represents the filters to list entities
+// Type: only this type, ignored if empty
+// Query: entities starting with the query, ignored if empty
+type EntityFilter struct {
+	Type  string
+	Query string
+	Page  int
+	Limit int
+}
+
+// EntityResponse is the response type for entity operations
+type EntityResponse struct {
+	Entities []Entity `json:"entities"`
+	Total    int64    `json:"total"`
+}
+
+// IsValidEntityType checks the type is person, organization or place
+func IsValidEntityType(entityType string) bool {
+	return entityType == EntityTypePerson ||
+		entityType == EntityTypeOrganization ||
+		entityType == EntityTypePlace
+}
+
+// ParseEntityFilters removes the entity: filters from the query and returns them
+// the underscores of the unquoted filters are spaces, entity:Cheryl_Mills is entity:"Cheryl Mills"
+func ParseEntityFilters(query string) (string, []string) {
+	entities := make([]string, 0)
+	for _, match := range entityFilterPattern.FindAllStringSubmatch(query, -1) {
+		entity := match[1]
+		if entity == "" {
+			entity = strings.ReplaceAll(match[2], "_", " ")
+		}
+
+		if entity = strings.TrimSpace(entity); entity != "" {
+			entities = append(entities, entity)
+		}
+	}
+
+	query = entityFilterPattern.ReplaceAllString(query, " ")
+	return strings.Join(strings.Fields(query), " "), entities
+}
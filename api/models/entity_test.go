@@ -0,0 +1,36 @@
+package models_test
+
+import (
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+func TestParseEntityFilters(t *testing.T) {
+	ttc := []struct {
+		name             string
+		query            string
+		expectedQuery    string
+		expectedEntities []string
+	}{
+		{"must keep the query without filters", "libya embassy", "libya embassy", []string{}},
+		{"must read the unquoted filter", "entity:Libya embassy", "embassy", []string{"Libya"}},
+		{"must read the quoted filter", `benghazi entity:"Cheryl Mills"`, "benghazi", []string{"Cheryl Mills"}},
+		{"must replace the underscores", "entity:Cheryl_Mills", "", []string{"Cheryl Mills"}},
+		{"must read every filter", `entity:Libya call entity:"State Department"`, "call", []string{"Libya", "State Department"}},
+		{"must ignore the empty filter", `entity:"" call`, "call", []string{}},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			query, entities := models.ParseEntityFilters(tt.query)
+			if query != tt.expectedQuery {
+				t.Errorf("ParseEntityFilters returned query %q, expected %q", query, tt.expectedQuery)
+			}
+			if !reflect.DeepEqual(entities, tt.expectedEntities) {
+				t.Errorf("ParseEntityFilters returned entities %v, expected %v", entities, tt.expectedEntities)
+			}
+		})
+	}
+}
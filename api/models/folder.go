@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxFolderItems is the number of emails of a folder
+	MaxFolderItems = 10000
+	// MaxFolderIDsPerRequest is the number of email ids that can be added, removed or reordered in a request
+	MaxFolderIDsPerRequest = 1000
+	// maxFolderNameLength is the length of the name of a folder
+	maxFolderNameLength = 100
+)
+
+// Folder represents a named set of emails curated by the users
+// Items is the number of emails of the folder
+type Folder struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Items     int64     `json:"items"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FolderRequest is the body to create a folder
+type FolderRequest struct {
+	Name string `json:"name"`
+}
+
+// FolderItemsRequest is the body to add or remove emails of a folder
+// IDs are the ids of the emails, or Query selects all the emails that match the search
+type FolderItemsRequest struct {
+	IDs   []uint32     `json:"ids"`
+	Query *QuerySearch `json:"query,omitempty"`
+}
+
+// FolderOrderRequest is the body to reorder the emails of a folder
+// IDs are moved to the start of the folder in their order
+type FolderOrderRequest struct {
+	IDs []uint32 `json:"ids"`
+}
+
+// FolderResponse is the response type for folder operations
+type FolderResponse struct {
+	Folders []Folder `json:"folders"`
+	Total   int64    `json:"total"`
+}
+
+// FolderMailsResponse is the response type with the emails of a folder in their order
+type FolderMailsResponse struct {
+	Folder Folder  `json:"folder"`
+	Mails  []Email `json:"mails"`
+	Total  int64   `json:"total"`
+}
+
+// Normalize removes the spaces around the name
+func (r *FolderRequest) Normalize() *FolderRequest {
+	r.Name = strings.TrimSpace(r.Name)
+	return r
+}
+
+// IsValid checks the name is not empty and has up to 100 characters
+func (r *FolderRequest) IsValid() bool {
+	return r.Name != "" && utf8.RuneCountInString(r.Name) <= maxFolderNameLength
+}
+
+// Validate returns the message of the error of the request, empty if the request is valid
+func (r *FolderItemsRequest) Validate() string {
+	if (len(r.IDs) == 0) == (r.Query == nil) {
+		return "The ids or the query are required, not both"
+	}
+
+	return validateFolderIDs(r.IDs, r.Query == nil)
+}
+
+// Validate returns the message of the error of the request, empty if the request is valid
+func (r *FolderOrderRequest) Validate() string {
+	return validateFolderIDs(r.IDs, true)
+}
+
+// validateFolderIDs checks the number of ids of a request and that they are not 0
+func validateFolderIDs(ids []uint32, required bool) string {
+	if required && len(ids) == 0 {
+		return "The ids are required"
+	}
+
+	if len(ids) > MaxFolderIDsPerRequest {
+		return "Up to 1000 ids by request"
+	}
+
+	for _, id := range ids {
+		if id == 0 {
+			return "The email ids are not valid"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,36 @@
+package models_test
+
+import (
+	"testing"
+
+	"api/models"
+)
+
+func TestFolderItemsRequestValidate(t *testing.T) {
+	tooMany := make([]uint32, models.MaxFolderIDsPerRequest+1)
+	for i := range tooMany {
+		tooMany[i] = uint32(i + 1)
+	}
+
+	ttc := []struct {
+		name    string
+		request models.FolderItemsRequest
+		valid   bool
+	}{
+		{"must accept the ids", models.FolderItemsRequest{IDs: []uint32{1, 2}}, true},
+		{"must accept a query", models.FolderItemsRequest{Query: &models.QuerySearch{Query: "libya"}}, true},
+		{"must require the ids or the query", models.FolderItemsRequest{}, false},
+		{"must reject the ids with a query", models.FolderItemsRequest{IDs: []uint32{1}, Query: &models.QuerySearch{}}, false},
+		{"must reject the id 0", models.FolderItemsRequest{IDs: []uint32{0}}, false},
+		{"must limit the ids", models.FolderItemsRequest{IDs: tooMany}, false},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			message := tt.request.Validate()
+			if (message == "") != tt.valid {
+				t.Errorf("Validate returned %q, expected valid %v", message, tt.valid)
+			}
+		})
+	}
+}
@@ -7,13 +7,16 @@ import (
 )
 
 // Email represents an email
+// Annotated is true if the email has annotations and Folders has the ids of its folders, they are only set in the search results
 type Email struct {
-	ID      uint32    `json:"id"`
-	Date    time.Time `json:"date"`
-	Subject string    `json:"subject"`
-	From    string    `json:"from"`
-	To      string    `json:"to"`
-	Content string    `json:"content"`
+	ID        uint32    `json:"id"`
+	Date      time.Time `json:"date"`
+	Subject   string    `json:"subject"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Content   string    `json:"content"`
+	Annotated bool      `json:"annotated" gorm:"-"`
+	Folders   []int64   `json:"folders,omitempty" gorm:"-"`
 }
 
 // EmailRank represents an email with rank to inverted index search
@@ -49,5 +52,5 @@ func (e *Email) Validate() error {
 // TableName returns the table name for the model
 // used in gorm to get data
 func (Email) TableName() string {
-	return config.GetConfig().SchemaName + "." + config.GetConfig().MailsTable
+	return config.GetConfig().Table(config.GetConfig().MailsTable)
 }
@@ -0,0 +1,52 @@
+package models
+
+// Scopes of the principals, every scope includes the previous ones: read < write < admin
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// Methods of authentication of the principals
+const (
+	AuthMethodNone   = "none"
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// scopeLevels is the order of the scopes, a scope grants the scopes with a lower level
+var scopeLevels = map[string]int{
+	ScopeRead:  1,
+	ScopeWrite: 2,
+	ScopeAdmin: 3,
+}
+
+// Principal represents the client authenticated in a request
+// Subject is the name of the API key or the sub of the token
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"`
+	Scopes  []string `json:"scopes"`
+}
+
+// HasScope checks if one of the scopes of the principal grants the scope
+func (p Principal) HasScope(scope string) bool {
+	required, ok := scopeLevels[scope]
+	if !ok {
+		return false
+	}
+
+	for _, granted := range p.Scopes {
+		if scopeLevels[granted] >= required {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsValidScope checks if the scope is one of the scopes of the API
+func IsValidScope(scope string) bool {
+	_, ok := scopeLevels[scope]
+	return ok
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"api/config"
+	"strings"
 )
 
 type TypeSearch string
@@ -32,6 +33,13 @@ type QuerySearch struct {
 	Date       *DateParam `json:"date"`
 	OrderBy    OrderBy    `json:"orderBy"` // ASC or DESC
 	DateSearch DateSearch `json:"dateSearch"`
+	Entities   []string   `json:"entities"`   // Entities mentioned in the emails, also read from the entity: filters of the query
+	Tags       []string   `json:"tags"`       // Tags of the emails, all of them are required
+	Annotation string     `json:"annotation"` // Text of the annotations of the emails, without case
+	From       string     `json:"from"`       // Text of the sender, without case
+	To         string     `json:"to"`         // Text of the recipients, without case
+	DateFrom   *DateParam `json:"dateFrom"`   // First day of the emails, included
+	DateTo     *DateParam `json:"dateTo"`     // Last day of the emails, included
 }
 
 func NewQuerySearch(query string, typeSearch TypeSearch, orderBy OrderBy, page int, limit int, dateSearch DateSearch) *QuerySearch {
@@ -57,6 +65,16 @@ func (qs *QuerySearch) Normalize() *QuerySearch {
 	if qs.Query == "" {
 		qs.Query = ""
 	}
+
+	query, entities := ParseEntityFilters(qs.Query)
+	qs.Query = query
+	qs.Entities = append(qs.Entities, entities...)
+
+	// the tags that are not valid can't match any email, they are kept to return no emails
+	for i, tag := range qs.Tags {
+		qs.Tags[i] = NormalizeTag(tag)
+	}
+
 	if !qs.TypeSearch.Validate() {
 		qs.TypeSearch = TypeSearchAND
 	}
@@ -73,6 +91,18 @@ func (qs *QuerySearch) Normalize() *QuerySearch {
 		qs.Limit = 1
 	}
 
+	qs.From = strings.TrimSpace(qs.From)
+	qs.To = strings.TrimSpace(qs.To)
+
+	// the dates that are not valid don't filter the emails
+	if qs.DateFrom != nil && !qs.DateFrom.Valid {
+		qs.DateFrom = nil
+	}
+
+	if qs.DateTo != nil && !qs.DateTo.Valid {
+		qs.DateTo = nil
+	}
+
 	if qs.DateSearch.Date != nil {
 		if qs.DateSearch.Date.Valid {
 			if !qs.DateSearch.Operator.Validate() {
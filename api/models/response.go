@@ -33,3 +33,9 @@ type MailResponse struct {
 	Mails []Email `json:"mails"`
 	Total int64   `json:"total"`
 }
+
+// RateLimitResponse is the data of the responses of the requests rejected by the limits
+// RetryAfter is the number of seconds to wait, as the Retry-After header
+type RateLimitResponse struct {
+	RetryAfter int `json:"retryAfter"`
+}
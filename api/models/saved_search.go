@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// maxSavedSearchNameLength is the length of the owner and the name of a saved search
+const maxSavedSearchNameLength = 100
+
+// SavedSearch represents a QuerySearch saved by an owner
+// EvaluatedAt is the last time the indexer recorded the matches, nil until the first run after it was saved
+type SavedSearch struct {
+	ID          int64       `json:"id"`
+	Owner       string      `json:"owner"`
+	Name        string      `json:"name"`
+	Query       QuerySearch `json:"query"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	EvaluatedAt *time.Time  `json:"evaluatedAt,omitempty"`
+}
+
+// SavedSearchRequest is the body to save a search
+type SavedSearchRequest struct {
+	Owner string      `json:"owner"`
+	Name  string      `json:"name"`
+	Query QuerySearch `json:"query"`
+}
+
+// SavedSearchResponse is the response type for saved search operations
+type SavedSearchResponse struct {
+	SavedSearches []SavedSearch `json:"savedSearches"`
+	Total         int64         `json:"total"`
+}
+
+// NewMatchesResponse is the response type with the emails matched by a saved search since the last check
+// Remaining is the number of new matches left for the next check
+type NewMatchesResponse struct {
+	SavedSearch SavedSearch `json:"savedSearch"`
+	Mails       []Email     `json:"mails"`
+	Remaining   int64       `json:"remaining"`
+}
+
+// Normalize removes the spaces around the owner and the name
+func (r *SavedSearchRequest) Normalize() *SavedSearchRequest {
+	r.Owner = strings.TrimSpace(r.Owner)
+	r.Name = strings.TrimSpace(r.Name)
+	return r
+}
+
+// IsValid checks the owner and the name are not empty and have up to 100 characters
+func (r *SavedSearchRequest) IsValid() bool {
+	return r.Owner != "" && r.Name != "" &&
+		len(r.Owner) <= maxSavedSearchNameLength && len(r.Name) <= maxSavedSearchNameLength
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxTagsPerRequest is the number of tags that can be added or removed in a request
+const MaxTagsPerRequest = 20
+
+// tagPattern is the format of the tags, benghazi-timeline or follow_up
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// Tag represents a label added to the emails
+// Emails is the number of emails with the tag
+type Tag struct {
+	Name   string `json:"name"`
+	Emails int64  `json:"emails"`
+}
+
+// TagResponse is the response type for tag operations
+type TagResponse struct {
+	Tags  []Tag `json:"tags"`
+	Total int64 `json:"total"`
+}
+
+// TagsRequest is the body to add or remove the tags of an email
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// EmailTagsResponse is the response type with the tags of an email
+type EmailTagsResponse struct {
+	EmailID uint32   `json:"emailId"`
+	Tags    []string `json:"tags"`
+}
+
+// NormalizeTag returns the tag in lower case without the spaces around it
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// IsValidTag checks the tag has up to 50 lower case letters, numbers, - or _
+func IsValidTag(tag string) bool {
+	return tagPattern.MatchString(tag)
+}
+
+// NormalizeTags normalizes the tags and removes the duplicates
+// returns false if a tag is not valid
+func NormalizeTags(tags []string) ([]string, bool) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if !IsValidTag(tag) {
+			return nil, false
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, true
+}
@@ -0,0 +1,32 @@
+package models_test
+
+import (
+	"reflect"
+	"testing"
+
+	"api/models"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	ttc := []struct {
+		name     string
+		tags     []string
+		expected []string
+		valid    bool
+	}{
+		{"must lower the tags", []string{" Follow-Up ", "benghazi_timeline"}, []string{"follow-up", "benghazi_timeline"}, true},
+		{"must remove the duplicates", []string{"follow-up", "FOLLOW-UP"}, []string{"follow-up"}, true},
+		{"must reject the spaces", []string{"follow up"}, nil, false},
+		{"must reject the empty tag", []string{""}, nil, false},
+		{"must reject the long tag", []string{"a123456789b123456789c123456789d123456789e123456789f"}, nil, false},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, valid := models.NormalizeTags(tt.tags)
+			if valid != tt.valid || !reflect.DeepEqual(tags, tt.expected) {
+				t.Errorf("NormalizeTags returned %v %v, expected %v %v", tags, valid, tt.expected, tt.valid)
+			}
+		})
+	}
+}
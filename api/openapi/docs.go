@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+// docsTemplate is the docs page of the document, it is a single HTML page without scripts
+var docsTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"lower":      strings.ToLower,
+	"schemaName": schemaName,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Info.Title}} {{.Info.Version}}</title>
+<style>
+body { font-family: sans-serif; max-width: 1080px; margin: 0 auto; padding: 1rem; }
+section { border-top: 1px solid #ccc; padding: 1rem 0; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.25rem 0.75rem 0.25rem 0; vertical-align: top; }
+code, pre { background: #f4f4f4; }
+pre { padding: 0.5rem; overflow-x: auto; }
+.method { font-weight: bold; text-transform: uppercase; }
+.get { color: #1a7f37; } .post { color: #0969da; } .put { color: #9a6700; } .delete { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{.Info.Title}} {{.Info.Version}}</h1>
+<p>{{.Info.Description}}</p>
+<p>The paths start with <code>{{.BasePath}}</code>, the document is <a href="{{.BasePath}}/openapi.json">openapi.json</a>.</p>
+<nav>
+<ul>
+{{range .Operations}}<li><a href="#{{.OperationID}}"><span class="method {{lower .Method}}">{{.Method}}</span> {{.Path}}</a> {{.Summary}}</li>
+{{end}}</ul>
+</nav>
+{{range .Operations}}<section id="{{.OperationID}}">
+<h2><span class="method {{lower .Method}}">{{.Method}}</span> {{.Path}}</h2>
+<p>{{.Summary}}</p>
+{{if .Description}}<p>{{.Description}}</p>
+{{end}}{{if .Scope}}<p>Scope: <code>{{.Scope}}</code></p>
+{{end}}{{if .Parameters}}<h3>Parameters</h3>
+<table>
+<tr><th>Name</th><th>In</th><th>Type</th><th>Required</th><th>Description</th></tr>
+{{range .Parameters}}<tr><td><code>{{.Name}}</code></td><td>{{.In}}</td><td>{{schemaName .Schema}}</td><td>{{if .Required}}yes{{else}}no{{end}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+{{end}}{{with .RequestBody}}<h3>Body</h3>
+<ul>
+{{range $type, $media := .Content}}<li><code>{{$type}}</code> {{schemaName $media.Schema}}</li>
+{{end}}</ul>
+{{end}}<h3>Responses</h3>
+<table>
+<tr><th>Status</th><th>Description</th><th>Content</th></tr>
+{{range $status, $response := .Responses}}<tr><td>{{$status}}</td><td>{{$response.Description}}</td><td>{{range $type, $media := $response.Content}}<code>{{$type}}</code> {{schemaName $media.Schema}} {{end}}</td></tr>
+{{end}}</table>
+</section>
+{{end}}<section>
+<h2>Schemas</h2>
+{{range .Schemas}}<h3 id="schema-{{.Name}}">{{.Name}}</h3>
+<pre>{{.JSON}}</pre>
+{{end}}</section>
+</body>
+</html>
+`))
+
+// docsSchema is a component schema as it is written in the document
+type docsSchema struct {
+	Name string
+	JSON string
+}
+
+// WriteDocs writes the docs page of the spec, the schemas are shown as they are written in the document
+func WriteDocs(w io.Writer, spec *Spec) error {
+	var document struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(Document(), &document); err != nil {
+		return fmt.Errorf("cannot parse the OpenAPI document: %w", err)
+	}
+
+	schemas := make([]docsSchema, 0, len(document.Components.Schemas))
+	for name, raw := range document.Components.Schemas {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, raw, "", "  "); err != nil {
+			return fmt.Errorf("cannot format the schema %s: %w", name, err)
+		}
+		schemas = append(schemas, docsSchema{Name: name, JSON: indented.String()})
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Name < schemas[j].Name })
+
+	page := struct {
+		Info       Info
+		BasePath   string
+		Operations []*Operation
+		Schemas    []docsSchema
+	}{Info: spec.Info, BasePath: spec.BasePath(), Operations: spec.Operations(), Schemas: schemas}
+
+	if err := docsTemplate.Execute(w, page); err != nil {
+		return fmt.Errorf("error writing the docs: %w", err)
+	}
+
+	return nil
+}
+
+// schemaName returns a short name of a schema for the docs, the name of its $ref or its type
+// the envelopes of the responses are shown as Response of the schema of data
+func schemaName(schema *Schema) string {
+	if schema == nil {
+		return ""
+	}
+
+	switch {
+	case schema.Ref != "":
+		return strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+	case len(schema.AllOf) == 1:
+		return schemaName(schema.AllOf[0])
+	case schema.Type == "array" && schema.Items != nil:
+		return schemaName(schema.Items) + "[]"
+	case schema.Type == "object" && schema.Properties["data"] != nil && schema.Properties["msg"] != nil:
+		return "Response of " + schemaName(schema.Properties["data"])
+	case schema.Type != "":
+		return schema.Type
+	default:
+		return "any"
+	}
+}
@@ -0,0 +1,246 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// document is the OpenAPI 3 document of the API, it is maintained by hand with the handlers
+//
+//go:embed openapi.json
+var document []byte
+
+// methods are the operations of a path item in the order of the docs
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+
+// Spec is the part of the OpenAPI document used to validate the requests and to render the docs
+type Spec struct {
+	OpenAPI    string                                `json:"openapi"`
+	Info       Info                                  `json:"info"`
+	Servers    []Server                              `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components Components                            `json:"components"`
+
+	operations []*Operation
+}
+
+// Info is the title and the version of the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// Server is the base URL of the paths
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Components are the objects referenced by $ref
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+}
+
+// Operation is a method of a path, the references of its parameters and responses are resolved by Load
+// Scope is the x-scope extension, the scope required by the endpoint
+type Operation struct {
+	Method      string               `json:"-"`
+	Path        string               `json:"-"`
+	OperationID string               `json:"operationId"`
+	Tags        []string             `json:"tags"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+	Scope       string               `json:"x-scope"`
+}
+
+// Parameter is a path, query or header param of an operation
+type Parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of an operation by media type
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation by media type
+type Response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType is the schema of a content
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Document returns the OpenAPI document as it is served
+func Document() []byte {
+	return document
+}
+
+// Load parses the document and resolves the references of the parameters and the responses
+// returns an error if a reference is missing
+func Load() (*Spec, error) {
+	return Parse(document)
+}
+
+// Parse parses an OpenAPI document
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("cannot parse the OpenAPI document: %w", err)
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for _, method := range methods {
+			raw, ok := spec.Paths[path][strings.ToLower(method)]
+			if !ok {
+				continue
+			}
+
+			operation := &Operation{Method: method, Path: path}
+			if err := json.Unmarshal(raw, operation); err != nil {
+				return nil, fmt.Errorf("cannot parse %s %s: %w", method, path, err)
+			}
+
+			if err := spec.resolve(operation); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+
+			spec.operations = append(spec.operations, operation)
+		}
+	}
+
+	return &spec, nil
+}
+
+// resolve replaces the references of the parameters and the responses of the operation by the components
+func (s *Spec) resolve(operation *Operation) error {
+	for i, parameter := range operation.Parameters {
+		if parameter.Ref == "" {
+			continue
+		}
+
+		resolved, ok := s.Components.Parameters[strings.TrimPrefix(parameter.Ref, "#/components/parameters/")]
+		if !ok {
+			return fmt.Errorf("parameter %s not found", parameter.Ref)
+		}
+		operation.Parameters[i] = resolved
+	}
+
+	for status, response := range operation.Responses {
+		if response.Ref == "" {
+			continue
+		}
+
+		resolved, ok := s.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+		if !ok {
+			return fmt.Errorf("response %s not found", response.Ref)
+		}
+		operation.Responses[status] = resolved
+	}
+
+	return nil
+}
+
+// BasePath returns the path of the first server, the prefix of the paths of the document
+func (s *Spec) BasePath() string {
+	if len(s.Servers) == 0 {
+		return ""
+	}
+
+	return strings.TrimSuffix(s.Servers[0].URL, "/")
+}
+
+// Operations returns the operations ordered by path and method
+func (s *Spec) Operations() []*Operation {
+	return s.operations
+}
+
+// FindOperation returns the operation of the method and the path of a request, the path includes the base path
+// the {param} segments of the paths match any segment
+func (s *Spec) FindOperation(method, path string) (*Operation, bool) {
+	path, ok := strings.CutPrefix(path, s.BasePath())
+	if !ok {
+		return nil, false
+	}
+
+	segments := splitPath(path)
+	var found *Operation
+	for _, operation := range s.operations {
+		if operation.Method != method || !matchPath(splitPath(operation.Path), segments) {
+			continue
+		}
+
+		// the static segments win over the params, /folders/{id} is not /folders/export
+		if found == nil || strings.Count(operation.Path, "{") < strings.Count(found.Path, "{") {
+			found = operation
+		}
+	}
+
+	return found, found != nil
+}
+
+// JSONBody returns the schema of the JSON body of the operation, nil if it has no JSON body
+func (o *Operation) JSONBody() *Schema {
+	if o.RequestBody == nil {
+		return nil
+	}
+
+	return o.RequestBody.Content["application/json"].Schema
+}
+
+// splitPath returns the segments of the path without the slashes around it
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+// matchPath checks the segments of a request match the segments of a path of the document
+func matchPath(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+
+	for i, segment := range template {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+
+		if segment != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
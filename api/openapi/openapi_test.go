@@ -0,0 +1,93 @@
+package openapi
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.BasePath() != "/api" {
+		t.Errorf("unexpected base path %s", spec.BasePath())
+	}
+
+	ids := make(map[string]bool)
+	for _, operation := range spec.Operations() {
+		if operation.OperationID == "" || ids[operation.OperationID] {
+			t.Errorf("the operation id of %s %s is missing or repeated", operation.Method, operation.Path)
+		}
+		ids[operation.OperationID] = true
+
+		for status, response := range operation.Responses {
+			if response.Ref != "" || response.Description == "" {
+				t.Errorf("the response %s of %s is not resolved", status, operation.OperationID)
+			}
+		}
+	}
+
+	if _, err := Parse([]byte(`{"paths":{"/tags":{"get":{"responses":{"400":{"$ref":"#/components/responses/Missing"}}}}}}`)); err == nil {
+		t.Error("expected an error for a missing response")
+	}
+}
+
+func TestFindOperation(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ttc := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodPost, "/api/mails/search", "searchMails"},
+		{http.MethodGet, "/api/mails/", "listMails"},
+		{http.MethodPut, "/api/folders/3/items/order", "reorderFolderItems"},
+		{http.MethodDelete, "/api/collections/dnc_emails/mails/7/annotations/2", "collectionDeleteAnnotation"},
+		{http.MethodGet, "/api/folders/3/export", "exportFolder"},
+		{http.MethodPatch, "/api/mails/search", ""},
+		{http.MethodGet, "/api/unknown", ""},
+		{http.MethodGet, "/mails", ""},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			operation, ok := spec.FindOperation(tt.method, tt.path)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("unexpected operation %s", operation.OperationID)
+				}
+				return
+			}
+
+			if !ok || operation.OperationID != tt.expected {
+				t.Errorf("expected %s, got %v", tt.expected, operation)
+			}
+		})
+	}
+}
+
+func TestWriteDocs(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var page bytes.Buffer
+	if err := WriteDocs(&page, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`id="searchMails"`, `/mails/search`, `id="schema-QuerySearch"`, `Response of MailResponse`} {
+		if !strings.Contains(page.String(), expected) {
+			t.Errorf("the docs page doesn't contain %s", expected)
+		}
+	}
+}
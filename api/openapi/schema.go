@@ -0,0 +1,352 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schemas used by the document
+// $ref to the component schemas, type, nullable, enum, the limits of the strings, numbers and arrays,
+// properties, required, additionalProperties false, items, allOf, anyOf and the date-time format
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Deprecated           bool               `json:"deprecated"`
+	Enum                 []any              `json:"enum"`
+	Default              any                `json:"default"`
+	Example              any                `json:"example"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Items                *Schema            `json:"items"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	AllOf                []*Schema          `json:"allOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+}
+
+// ValidationError is a value that doesn't match its schema, Field is the path of the value, ex: query.limit
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// Error implements error interface
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+
+	return e.Field + " " + e.Message
+}
+
+// patterns are the compiled patterns of the schemas
+var patterns sync.Map
+
+// ValidateJSON decodes the JSON data and validates it against the schema
+func (s *Spec) ValidateJSON(schema *Schema, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Message: "is not valid JSON"}
+	}
+
+	if decoder.More() {
+		return &ValidationError{Message: "has data after the JSON value"}
+	}
+
+	return s.Validate(schema, value)
+}
+
+// Validate validates a value decoded with UseNumber against the schema
+func (s *Spec) Validate(schema *Schema, value any) error {
+	return s.validate(schema, value, "")
+}
+
+// validate returns the first error of the value, field is the path of the value
+func (s *Spec) validate(schema *Schema, value any, field string) error {
+	schema, err := s.resolveSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	// a null without type is checked by the allOf schemas, ex: a deprecated $ref
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+
+		if schema.Type != "" {
+			return &ValidationError{field, "must not be null"}
+		}
+	}
+
+	for _, sub := range schema.AllOf {
+		if err := s.validate(sub, value, field); err != nil {
+			return err
+		}
+	}
+
+	// the error of the first schema is returned if no schema matches, it is the main one, ex: a date-time or empty
+	if len(schema.AnyOf) > 0 {
+		var first error
+		for i, sub := range schema.AnyOf {
+			err := s.validate(sub, value, field)
+			if err == nil {
+				first = nil
+				break
+			}
+
+			if i == 0 {
+				first = err
+			}
+		}
+
+		if first != nil {
+			return first
+		}
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return &ValidationError{field, "must be one of " + formatEnum(schema.Enum)}
+	}
+
+	switch value := value.(type) {
+	case string:
+		return s.validateString(schema, value, field)
+	case json.Number:
+		return s.validateNumber(schema, value, field)
+	case bool:
+		if schema.Type != "" && schema.Type != "boolean" {
+			return &ValidationError{field, "must be " + schema.Type}
+		}
+	case []any:
+		return s.validateArray(schema, value, field)
+	case map[string]any:
+		return s.validateObject(schema, value, field)
+	default:
+		return &ValidationError{field, fmt.Sprintf("has an unknown type %T", value)}
+	}
+
+	return nil
+}
+
+// validateString checks the type, the length, the pattern and the format of a string
+func (s *Spec) validateString(schema *Schema, value, field string) error {
+	if schema.Type != "" && schema.Type != "string" {
+		return &ValidationError{field, "must be " + schema.Type}
+	}
+
+	length := utf8.RuneCountInString(value)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		return &ValidationError{field, fmt.Sprintf("must have at least %d characters", *schema.MinLength)}
+	}
+
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return &ValidationError{field, fmt.Sprintf("must have up to %d characters", *schema.MaxLength)}
+	}
+
+	if schema.Pattern != "" {
+		pattern, err := compilePattern(schema.Pattern)
+		if err != nil {
+			return err
+		}
+
+		if !pattern.MatchString(value) {
+			return &ValidationError{field, "must match " + schema.Pattern}
+		}
+	}
+
+	if schema.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return &ValidationError{field, "must be an RFC3339 time"}
+		}
+	}
+
+	return nil
+}
+
+// validateNumber checks the type and the limits of a number, the integers can't have decimals
+func (s *Spec) validateNumber(schema *Schema, value json.Number, field string) error {
+	if schema.Type != "" && schema.Type != "number" && schema.Type != "integer" {
+		return &ValidationError{field, "must be " + schema.Type}
+	}
+
+	number, err := value.Float64()
+	if err != nil {
+		return &ValidationError{field, "must be a number"}
+	}
+
+	if schema.Type == "integer" {
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err != nil && number != math.Trunc(number) {
+			return &ValidationError{field, "must be an integer"}
+		}
+	}
+
+	if schema.Minimum != nil && number < *schema.Minimum {
+		return &ValidationError{field, "must be at least " + strconv.FormatFloat(*schema.Minimum, 'f', -1, 64)}
+	}
+
+	if schema.Maximum != nil && number > *schema.Maximum {
+		return &ValidationError{field, "must be at most " + strconv.FormatFloat(*schema.Maximum, 'f', -1, 64)}
+	}
+
+	return nil
+}
+
+// validateArray checks the type, the length and the items of an array
+func (s *Spec) validateArray(schema *Schema, value []any, field string) error {
+	if schema.Type != "" && schema.Type != "array" {
+		return &ValidationError{field, "must be " + schema.Type}
+	}
+
+	if schema.MinItems != nil && len(value) < *schema.MinItems {
+		return &ValidationError{field, fmt.Sprintf("must have at least %d items", *schema.MinItems)}
+	}
+
+	if schema.MaxItems != nil && len(value) > *schema.MaxItems {
+		return &ValidationError{field, fmt.Sprintf("must have up to %d items", *schema.MaxItems)}
+	}
+
+	if schema.Items == nil {
+		return nil
+	}
+
+	for i, item := range value {
+		if err := s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateObject checks the type, the required properties, the unknown properties and the properties of an object
+// the properties are checked in order of name to return always the same error
+func (s *Spec) validateObject(schema *Schema, value map[string]any, field string) error {
+	if schema.Type != "" && schema.Type != "object" {
+		return &ValidationError{field, "must be " + schema.Type}
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := value[name]; !ok {
+			return &ValidationError{joinField(field, name), "is required"}
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				return &ValidationError{joinField(field, name), "is not a known field"}
+			}
+			continue
+		}
+
+		if err := s.validate(property, value[name], joinField(field, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveSchema returns the component schema of a $ref
+func (s *Spec) resolveSchema(schema *Schema) (*Schema, error) {
+	for schema != nil && schema.Ref != "" {
+		resolved, ok := s.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok {
+			return nil, fmt.Errorf("schema %s not found", schema.Ref)
+		}
+		schema = resolved
+	}
+
+	if schema == nil {
+		return &Schema{}, nil
+	}
+
+	return schema, nil
+}
+
+// compilePattern returns the compiled pattern, they are compiled once
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := patterns.Load(pattern); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %s not valid: %w", pattern, err)
+	}
+	patterns.Store(pattern, compiled)
+
+	return compiled, nil
+}
+
+// inEnum checks the value is one of the values of the enum, the numbers are compared by value
+func inEnum(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if number, ok := value.(json.Number); ok {
+			if allowedNumber, ok := allowed.(float64); ok {
+				if parsed, err := number.Float64(); err == nil && parsed == allowedNumber {
+					return true
+				}
+			}
+			continue
+		}
+
+		if allowed == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatEnum returns the values of the enum separated by commas
+func formatEnum(enum []any) string {
+	values := make([]string, 0, len(enum))
+	for _, value := range enum {
+		values = append(values, fmt.Sprintf("%q", fmt.Sprint(value)))
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// joinField returns the path of a property of the field
+func joinField(field, name string) string {
+	if field == "" {
+		return name
+	}
+
+	return field + "." + name
+}
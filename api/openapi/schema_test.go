@@ -0,0 +1,119 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateJSON(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := &Schema{Ref: "#/components/schemas/QuerySearch"}
+
+	ttc := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"must accept the search of the app", `{"query":"libya","type":"AND","page":1,"limit":20,"orderBy":"desc","dateSearch":{"date":null,"operator":">="}}`, ""},
+		{"must accept an empty body", `{}`, ""},
+		{"must accept the empty and null dates", `{"date":null,"dateFrom":"","dateTo":"2012-09-11T00:00:00.000Z","tags":null}`, ""},
+		{"must ignore the unknown fields", `{"query":"libya","limits":20,"dateSearch":{"days":2}}`, ""},
+		{"must accept a limit too large, it is clamped", `{"limit":500,"page":0}`, ""},
+		{"must accept an unknown type, order and operator, they fall back to AND, desc and <=", `{"type":"XOR","orderBy":"up","dateSearch":{"operator":"!="}}`, ""},
+		{"must reject a decimal page", `{"page":1.5}`, "page must be an integer"},
+		{"must reject a wrong type", `{"query":3}`, "query must be string"},
+		{"must reject a date not valid", `{"dateSearch":{"date":"yesterday"}}`, "dateSearch.date must be an RFC3339 time"},
+		{"must reject an item of a wrong type", `{"tags":["follow-up",1]}`, "tags[1] must be string"},
+		{"must reject a body that is not JSON", `{"query":`, "is not valid JSON"},
+		{"must reject the data after the body", `{} {}`, "has data after the JSON value"},
+		{"must reject a null object", `null`, "must not be null"},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := spec.ValidateJSON(query, []byte(tt.body))
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateConstraints(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maximum := 200.0
+	closed := false
+	ttc := []struct {
+		name     string
+		schema   *Schema
+		body     string
+		expected string
+	}{
+		{"must reject the unknown fields of a closed object", &Schema{Ref: "#/components/schemas/FolderRequest"}, `{"name":"Libya","names":["Libya"]}`, "names is not a known field"},
+		{"must reject the unknown fields of an inline closed object", &Schema{Type: "object", AdditionalProperties: &closed}, `{"limits":20}`, "limits is not a known field"},
+		{"must reject a number too large", &Schema{Type: "integer", Maximum: &maximum}, `500`, "must be at most 200"},
+		{"must reject a value not in the enum", &Schema{Type: "string", Enum: []any{"AND", "OR"}}, `"XOR"`, `must be one of "AND", "OR"`},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := spec.ValidateJSON(tt.schema, []byte(tt.body))
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateRequired(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = spec.ValidateJSON(&Schema{Ref: "#/components/schemas/AnnotationRequest"}, []byte(`{"author":"analyst"}`))
+	if err == nil || err.Error() != "note is required" {
+		t.Errorf("expected the note to be required, got %v", err)
+	}
+
+	if err := spec.ValidateJSON(&Schema{Ref: "#/components/schemas/Missing"}, []byte(`{}`)); err == nil {
+		t.Error("expected an error for a missing schema")
+	}
+}
+
+// TestSchemaExamples validates the examples of the document against their schemas
+func TestSchemaExamples(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, schema := range spec.Components.Schemas {
+		if schema.Example == nil {
+			continue
+		}
+
+		example, err := json.Marshal(schema.Example)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := spec.ValidateJSON(schema, example); err != nil {
+			t.Errorf("the example of %s doesn't match its schema: %v", name, err)
+		}
+	}
+}
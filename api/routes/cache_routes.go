@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"api/cache"
+	"api/controllers"
+	"api/middleware"
+	"api/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupCacheRoutes configures the routes of the cache of the searches, they need the admin scope
+func SetupCacheRoutes(router chi.Router) {
+
+	cacheController := controllers.NewCacheController(cache.Search())
+
+	// Setup cache routes
+	router.Route("/cache", func(r chi.Router) {
+		r.Use(middleware.RequireScope(models.ScopeAdmin))
+		r.Get("/stats", cacheController.GetStats)
+	})
+}
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"api/cache"
+	"api/config"
+	"api/controllers"
+	"api/middleware"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// SetupCollectionRoutes configures the collection routes, the searches and the exports use the limits
+func SetupCollectionRoutes(router chi.Router, db *gorm.DB, limits *middleware.RouteLimits) {
+
+	collectionService := services.NewCollectionService(db)
+	collectionController := controllers.NewCollectionController(collectionService)
+	mailController := controllers.NewMailController(services.NewEmailServiceByDriver(config.GetConfig().Driver, db), collectionService)
+	entityController := controllers.NewEntityController(services.NewEntityService(db), collectionService)
+	tagController := controllers.NewTagController(services.NewTagService(db), collectionService)
+	savedSearchController := controllers.NewSavedSearchController(services.NewSavedSearchService(db), collectionService)
+	annotationController := controllers.NewAnnotationController(services.NewAnnotationService(db), collectionService)
+	folderController := controllers.NewFolderController(services.NewFolderService(db, services.NewEmailServiceByDriver(config.GetConfig().Driver, db)), collectionService)
+
+	// the endpoints that change the data need the write scope, the searches have their own limits
+	// the changes of the tags and the annotations invalidate the cached searches of the collection
+	write := middleware.RequireScope(models.ScopeWrite)
+	invalidate := middleware.InvalidateCache(cache.Search())
+
+	// Setup collection routes
+	router.Route("/collections", func(r chi.Router) {
+		r.Get("/", collectionController.ListCollections)
+		r.Route("/{name}/mails", func(r chi.Router) {
+			r.Use(middleware.Pagination)
+			r.With(limits.Search).Get("/", mailController.ListMails)
+			r.With(limits.Search).Post("/search", mailController.SearchMails)
+			r.With(write, invalidate).Post("/{id}/tags", tagController.AddTags)
+			r.With(write, invalidate).Delete("/{id}/tags", tagController.RemoveTags)
+			r.Get("/{id}/annotations", annotationController.ListAnnotations)
+			r.With(write, invalidate).Post("/{id}/annotations", annotationController.CreateAnnotation)
+			r.With(write, invalidate).Put("/{id}/annotations/{annotationId}", annotationController.UpdateAnnotation)
+			r.With(write, invalidate).Delete("/{id}/annotations/{annotationId}", annotationController.DeleteAnnotation)
+		})
+		r.With(middleware.Pagination).Get("/{name}/entities", entityController.ListEntities)
+		r.With(middleware.Pagination).Get("/{name}/tags", tagController.ListTags)
+		r.Route("/{name}/saved-searches", func(r chi.Router) {
+			r.Use(middleware.Pagination)
+			r.With(write).Post("/", savedSearchController.CreateSavedSearch)
+			r.Get("/", savedSearchController.ListSavedSearches)
+			r.With(write).Post("/{id}/new", savedSearchController.NewMatches)
+		})
+		r.Route("/{name}/folders", func(r chi.Router) {
+			setupFolderRoutes(r, folderController, limits)
+		})
+	})
+}
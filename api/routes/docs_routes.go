@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"api/controllers"
+	"api/openapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupDocsRoutes configures the routes of the OpenAPI document and its docs page, they don't need authentication
+func SetupDocsRoutes(router chi.Router, spec *openapi.Spec) {
+
+	docsController := controllers.NewDocsController(spec)
+
+	// Setup docs routes
+	router.Get("/openapi.json", docsController.GetOpenAPI)
+	router.Get("/docs", docsController.GetDocs)
+}
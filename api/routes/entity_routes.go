@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"api/controllers"
+	"api/middleware"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// SetupEntityRoutes configures the entity routes of the default collection
+func SetupEntityRoutes(router chi.Router, db *gorm.DB) {
+
+	entityController := controllers.NewEntityController(services.NewEntityService(db), services.NewCollectionService(db))
+
+	// Setup entity routes
+	router.Route("/entities", func(r chi.Router) {
+		r.Use(middleware.Pagination)
+		r.Get("/", entityController.ListEntities)
+	})
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"api/cache"
+	"api/config"
+	"api/controllers"
+	"api/middleware"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// SetupFolderRoutes configures the folder routes of the default collection, the searches and the exports use the limits
+func SetupFolderRoutes(router chi.Router, db *gorm.DB, limits *middleware.RouteLimits) {
+
+	folderService := services.NewFolderService(db, services.NewEmailServiceByDriver(config.GetConfig().Driver, db))
+	folderController := controllers.NewFolderController(folderService, services.NewCollectionService(db))
+
+	// Setup folder routes
+	router.Route("/folders", func(r chi.Router) {
+		setupFolderRoutes(r, folderController, limits)
+	})
+}
+
+// setupFolderRoutes adds the folder endpoints to the router, used by the default collection and the collections
+func setupFolderRoutes(r chi.Router, folderController *controllers.FolderController, limits *middleware.RouteLimits) {
+	r.Use(middleware.Pagination)
+	write := middleware.RequireScope(models.ScopeWrite)
+	// the searches return the folders of the emails, the changes of the items invalidate them
+	invalidate := middleware.InvalidateCache(cache.Search())
+	r.With(write).Post("/", folderController.CreateFolder)
+	r.Get("/", folderController.ListFolders)
+	r.Get("/{id}", folderController.GetFolder)
+	r.With(write, invalidate).Delete("/{id}", folderController.DeleteFolder)
+	r.With(write, limits.Search, invalidate).Post("/{id}/items", folderController.AddItems)
+	r.With(write, limits.Search, invalidate).Delete("/{id}/items", folderController.RemoveItems)
+	r.With(write).Put("/{id}/items/order", folderController.ReorderItems)
+	r.With(limits.Export).Get("/{id}/export", folderController.ExportFolder)
+}
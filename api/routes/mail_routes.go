@@ -1,25 +1,43 @@
 package routes
 
 import (
+	"api/cache"
+	"api/config"
 	"api/controllers"
 	"api/middleware"
+	"api/models"
 	"api/services"
 
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 )
 
-// SetupMailRoutes configures the mail routes
-func SetupMailRoutes(router *chi.Mux, db *gorm.DB) {
+// SetupMailRoutes configures the mail routes of the default collection, the searches use the limits
+func SetupMailRoutes(router chi.Router, db *gorm.DB, limits *middleware.RouteLimits) {
 
-	mailService := services.NewEmailService(db)
-	mailController := controllers.NewMailController(mailService)
+	mailService := services.NewEmailServiceByDriver(config.GetConfig().Driver, db)
+	collectionService := services.NewCollectionService(db)
+	mailController := controllers.NewMailController(mailService, collectionService)
+	tagController := controllers.NewTagController(services.NewTagService(db), collectionService)
+	annotationController := controllers.NewAnnotationController(services.NewAnnotationService(db), collectionService)
+
+	// the endpoints that change the data need the write scope, the searches have their own limits
+	// the changes of the tags and the annotations invalidate the cached searches of the collection
+	write := middleware.RequireScope(models.ScopeWrite)
+	invalidate := middleware.InvalidateCache(cache.Search())
 
 	// Setup mail routes
-	router.Route("/api", func(r chi.Router) {
-		r.Route("/mails", func(r chi.Router) {
-			r.Use(middleware.Pagination)
-			r.Post("/search", mailController.SearchMails)
-		})
+	router.Route("/mails", func(r chi.Router) {
+		r.Use(middleware.Pagination)
+		r.With(limits.Search).Get("/", mailController.ListMails)
+		r.With(limits.Search).Post("/search", mailController.SearchMails)
+		r.With(write, invalidate).Post("/{id}/tags", tagController.AddTags)
+		r.With(write, invalidate).Delete("/{id}/tags", tagController.RemoveTags)
+		r.Get("/{id}/annotations", annotationController.ListAnnotations)
+		r.With(write, invalidate).Post("/{id}/annotations", annotationController.CreateAnnotation)
+		r.With(write, invalidate).Put("/{id}/annotations/{annotationId}", annotationController.UpdateAnnotation)
+		r.With(write, invalidate).Delete("/{id}/annotations/{annotationId}", annotationController.DeleteAnnotation)
 	})
+
+	router.With(middleware.Pagination).Get("/tags", tagController.ListTags)
 }
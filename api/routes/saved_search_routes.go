@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"api/controllers"
+	"api/middleware"
+	"api/models"
+	"api/services"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// SetupSavedSearchRoutes configures the saved search routes of the default collection
+func SetupSavedSearchRoutes(router chi.Router, db *gorm.DB) {
+
+	savedSearchController := controllers.NewSavedSearchController(services.NewSavedSearchService(db), services.NewCollectionService(db))
+
+	// the endpoints that change the data need the write scope
+	write := middleware.RequireScope(models.ScopeWrite)
+
+	// Setup saved search routes
+	router.Route("/saved-searches", func(r chi.Router) {
+		r.Use(middleware.Pagination)
+		r.With(write).Post("/", savedSearchController.CreateSavedSearch)
+		r.Get("/", savedSearchController.ListSavedSearches)
+		r.With(write).Post("/{id}/new", savedSearchController.NewMatches)
+	})
+}
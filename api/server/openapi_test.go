@@ -0,0 +1,315 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"api/config"
+	"api/middleware"
+	"api/openapi"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// the API keys of the server of the contract tests
+const (
+	readerKey = "reader-key"
+	writerKey = "writer-key"
+	adminKey  = "admin-key"
+)
+
+// pathParam matches the {param} segments of the routes and the paths of the document
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// setupOpenAPIServer returns a server with the routes of the API on a SQLite database with the tables of the indexer
+// the default collection is also registered to be used by the collection routes
+func setupOpenAPIServer(t *testing.T) *Server {
+	t.Helper()
+
+	cfg := config.GetConfig()
+	driver := cfg.Driver
+	cfg.Driver = config.DriverSQLite
+	t.Cleanup(func() { cfg.Driver = driver })
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection := cfg.SchemaName
+	applyIndexerMigrations(t, db, collection)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	statements := []string{
+		// the registry of collections is created by the indexer outside of the migrations
+		`CREATE TABLE collections (name TEXT PRIMARY KEY, description TEXT NOT NULL DEFAULT '', created_at TIMESTAMP NOT NULL)`,
+		`INSERT INTO collections VALUES ('` + collection + `', 'Hillary Clinton emails', '` + now + `')`,
+		`INSERT INTO "` + collection + `_emails" VALUES (1, '2011-03-14 09:30:00', 'Libya update', 'Jake Sullivan', 'H', 'the embassy is running'), (2, '2012-09-11 22:00:00', 'Benghazi', 'Cheryl Mills', 'H', 'call me about libya')`,
+		`INSERT INTO "` + collection + `_emails_search" (rowid, subject, "from", "to", content) VALUES (1, 'Libya update', 'Jake Sullivan', 'H', 'the embassy is running'), (2, 'Benghazi', 'Cheryl Mills', 'H', 'call me about libya')`,
+		`INSERT INTO "` + collection + `_email_entities" (email_id, entity, type, field, start_offset, end_offset) VALUES (1, 'Libya', 'place', 'subject', 0, 5), (2, 'Cheryl Mills', 'person', 'content', 0, 12)`,
+		`INSERT INTO "` + collection + `_saved_search_matches" (saved_search_id, email_id, run_id, matched_at) VALUES (1, 2, 1, '` + now + `')`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auth, err := middleware.NewAuthenticator(config.AuthConfig{
+		Enabled: true,
+		APIKeys: []config.APIKey{
+			{Name: "reader", Key: readerKey, Scopes: []string{"read"}},
+			{Name: "writer", Key: writerKey, Scopes: []string{"write"}},
+			{Name: "admin", Key: adminKey, Scopes: []string{"admin"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(db, 0)
+	s.auth = auth
+	s.spec = spec
+	// every server has its own limits, the clients of the previous tests don't consume them
+	s.limits = middleware.NewRouteLimits(cfg.RateLimit)
+	s.setupRoutes()
+
+	return s
+}
+
+// applyIndexerMigrations creates the tables of the collection with the SQLite migrations of the indexer
+// the contract tests fail when the queries of the API don't match the schema of the indexer
+func applyIndexerMigrations(t *testing.T, db *gorm.DB, collection string) {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "indexer", "database", "migrations", "sqlite", "*.up.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no migrations of the indexer found")
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tmpl, err := template.New(filepath.Base(file)).Parse(string(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var migration strings.Builder
+		if err := tmpl.Execute(&migration, struct{ Schema string }{Schema: collection}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Exec(migration.String()).Error; err != nil {
+			t.Fatalf("error applying %s: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+// normalizeRoute returns the method and the path of a route without the names of its params and the trailing slash
+func normalizeRoute(method, path string) string {
+	path = pathParam.ReplaceAllString(path, "{}")
+	if path != "/" {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return method + " " + path
+}
+
+// TestOpenAPIRoutes fails when a route of the router is not in the document or a path of the document has no route
+func TestOpenAPIRoutes(t *testing.T) {
+	s := setupOpenAPIServer(t)
+
+	documented := make(map[string]bool)
+	for _, operation := range s.spec.Operations() {
+		documented[normalizeRoute(operation.Method, s.spec.BasePath()+operation.Path)] = true
+	}
+
+	routed := make(map[string]bool)
+	err := chi.Walk(s.Router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/") {
+			routed[normalizeRoute(method, route)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for route := range routed {
+		if !documented[route] {
+			t.Errorf("the route %s is not in the OpenAPI document", route)
+		}
+	}
+
+	for route := range documented {
+		if !routed[route] {
+			t.Errorf("the operation %s of the OpenAPI document has no route", route)
+		}
+	}
+}
+
+// TestOpenAPIContract calls every operation of the document and fails when a handler returns a status
+// that is not documented or a JSON body that doesn't match the schema of the response
+func TestOpenAPIContract(t *testing.T) {
+	s := setupOpenAPIServer(t)
+	collection := "/api/collections/" + config.GetConfig().SchemaName
+
+	// the cases run in order, the folders, annotations and saved searches are created before they are read
+	// the ids are shared by the default collection and the collection routes, they use the same tables
+	// the exports have a burst of 2 requests by client, the last one is done with another key
+	ttc := []struct {
+		method string
+		path   string
+		body   string
+		key    string
+		status int
+	}{
+		{http.MethodGet, "/api/openapi.json", "", "", http.StatusOK},
+		{http.MethodGet, "/api/docs", "", "", http.StatusOK},
+		{http.MethodGet, "/api/collections", "", readerKey, http.StatusOK},
+		{http.MethodGet, "/api/collections", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/cache/stats", "", adminKey, http.StatusOK},
+		{http.MethodGet, "/api/cache/stats", "", readerKey, http.StatusForbidden},
+
+		{http.MethodGet, "/api/mails?q=libya&from=cheryl&dateFrom=2012-09-01", "", readerKey, http.StatusOK},
+		{http.MethodGet, "/api/mails?type=XOR", "", readerKey, http.StatusBadRequest},
+		{http.MethodPost, "/api/mails/search", `{"query":"libya","type":"AND","page":1,"limit":10,"orderBy":"desc","dateSearch":{"date":null,"operator":">="}}`, readerKey, http.StatusOK},
+		{http.MethodPost, "/api/mails/search", `{"query":"libya","limit":500,"type":"XOR","unknown":true}`, readerKey, http.StatusOK},
+		{http.MethodPost, "/api/mails/search", `{"query":3}`, readerKey, http.StatusBadRequest},
+		{http.MethodPost, "/api/mails/1/tags", `{"tags":["follow-up"]}`, writerKey, http.StatusOK},
+		{http.MethodPost, "/api/mails/1/tags", `{"tags":["follow-up"]}`, readerKey, http.StatusForbidden},
+		{http.MethodPost, "/api/mails/99/tags", `{"tags":["follow-up"]}`, writerKey, http.StatusNotFound},
+		{http.MethodDelete, "/api/mails/1/tags", `{"tags":["follow-up"]}`, writerKey, http.StatusOK},
+		{http.MethodPost, "/api/mails/1/annotations", `{"note":"Which embassy?","start":4,"end":11}`, writerKey, http.StatusCreated},
+		{http.MethodPost, "/api/mails/1/annotations", `{"author":"analyst"}`, writerKey, http.StatusBadRequest},
+		{http.MethodGet, "/api/mails/1/annotations", "", readerKey, http.StatusOK},
+		{http.MethodGet, "/api/mails/one/annotations", "", readerKey, http.StatusBadRequest},
+		{http.MethodPut, "/api/mails/1/annotations/1", `{"author":"analyst","note":"The embassy of Tripoli"}`, writerKey, http.StatusOK},
+		{http.MethodDelete, "/api/mails/1/annotations/1", "", writerKey, http.StatusOK},
+		{http.MethodDelete, "/api/mails/1/annotations/1", "", writerKey, http.StatusNotFound},
+		{http.MethodGet, "/api/tags", "", readerKey, http.StatusOK},
+		{http.MethodGet, "/api/entities?type=place", "", readerKey, http.StatusOK},
+		{http.MethodPost, "/api/saved-searches", `{"name":"libya","query":{"query":"libya"}}`, writerKey, http.StatusCreated},
+		{http.MethodPost, "/api/saved-searches", `{"owner":"analyst","name":"libya","query":{"query":"libya"}}`, writerKey, http.StatusConflict},
+		{http.MethodGet, "/api/saved-searches", "", readerKey, http.StatusOK},
+		{http.MethodPost, "/api/saved-searches/1/new", "", readerKey, http.StatusForbidden},
+		{http.MethodPost, "/api/saved-searches/1/new", "", writerKey, http.StatusOK},
+		{http.MethodPost, "/api/saved-searches/99/new", "", writerKey, http.StatusNotFound},
+		{http.MethodPost, "/api/folders", `{"name":"Libya briefing"}`, writerKey, http.StatusCreated},
+		{http.MethodPost, "/api/folders", `{"name":"Libya briefing"}`, writerKey, http.StatusConflict},
+		{http.MethodGet, "/api/folders", "", readerKey, http.StatusOK},
+		{http.MethodPost, "/api/folders/1/items", `{"ids":[2,1]}`, writerKey, http.StatusOK},
+		{http.MethodPut, "/api/folders/1/items/order", `{"ids":[1,2]}`, writerKey, http.StatusOK},
+		{http.MethodGet, "/api/folders/1", "", readerKey, http.StatusOK},
+		{http.MethodGet, "/api/folders/99", "", readerKey, http.StatusNotFound},
+		{http.MethodGet, "/api/folders/1/export?format=csv", "", readerKey, http.StatusOK},
+		{http.MethodGet, "/api/folders/1/export?format=pdf", "", readerKey, http.StatusBadRequest},
+		{http.MethodDelete, "/api/folders/1/items", `{"ids":[2]}`, writerKey, http.StatusOK},
+		{http.MethodDelete, "/api/folders/1", "", writerKey, http.StatusOK},
+
+		{http.MethodGet, collection + "/mails?q=libya", "", readerKey, http.StatusOK},
+		{http.MethodGet, "/api/collections/podesta_emails/mails", "", readerKey, http.StatusNotFound},
+		{http.MethodPost, collection + "/mails/search", `{"query":"libya","tags":null,"dateFrom":"","dateTo":"2012-12-31T00:00:00Z"}`, readerKey, http.StatusOK},
+		{http.MethodPost, collection + "/mails/2/tags", `{"tags":["benghazi-timeline"]}`, writerKey, http.StatusOK},
+		{http.MethodDelete, collection + "/mails/2/tags", `{"tags":["benghazi-timeline"]}`, writerKey, http.StatusOK},
+		{http.MethodPost, collection + "/mails/2/annotations", `{"author":"reviewer","note":"Call back"}`, writerKey, http.StatusCreated},
+		{http.MethodGet, collection + "/mails/2/annotations", "", readerKey, http.StatusOK},
+		{http.MethodPut, collection + "/mails/2/annotations/2", `{"author":"reviewer","note":"Called back"}`, writerKey, http.StatusOK},
+		{http.MethodDelete, collection + "/mails/2/annotations/2", "", writerKey, http.StatusOK},
+		{http.MethodGet, collection + "/tags", "", readerKey, http.StatusOK},
+		{http.MethodGet, collection + "/entities", "", readerKey, http.StatusOK},
+		{http.MethodPost, collection + "/saved-searches", `{"owner":"analyst","name":"benghazi","query":{"query":"benghazi","type":"OR"}}`, writerKey, http.StatusCreated},
+		{http.MethodGet, collection + "/saved-searches", "", readerKey, http.StatusOK},
+		{http.MethodPost, collection + "/saved-searches/2/new", "", writerKey, http.StatusOK},
+		{http.MethodPost, collection + "/folders", `{"name":"Benghazi timeline"}`, writerKey, http.StatusCreated},
+		{http.MethodGet, collection + "/folders", "", readerKey, http.StatusOK},
+		{http.MethodPost, collection + "/folders/2/items", `{"query":{"query":"libya"}}`, writerKey, http.StatusOK},
+		{http.MethodPut, collection + "/folders/2/items/order", `{"ids":[1,2]}`, writerKey, http.StatusOK},
+		{http.MethodGet, collection + "/folders/2", "", readerKey, http.StatusOK},
+		{http.MethodGet, collection + "/folders/2/export?format=html", "", adminKey, http.StatusOK},
+		{http.MethodDelete, collection + "/folders/2/items", `{"ids":[1]}`, writerKey, http.StatusOK},
+		{http.MethodDelete, collection + "/folders/2", "", writerKey, http.StatusOK},
+	}
+
+	called := make(map[*openapi.Operation]bool)
+	for _, tt := range ttc {
+		name := tt.method + " " + tt.path + " " + strconv.Itoa(tt.status)
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			if tt.key != "" {
+				r.Header.Set(middleware.APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			s.Router.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+
+			operation, ok := s.spec.FindOperation(tt.method, r.URL.Path)
+			if !ok {
+				t.Fatalf("the operation is not in the OpenAPI document")
+			}
+			called[operation] = true
+
+			response, ok := operation.Responses[strconv.Itoa(w.Code)]
+			if !ok {
+				t.Fatalf("the status %d of %s is not documented", w.Code, operation.OperationID)
+			}
+
+			mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("the Content-Type %q is not valid", w.Header().Get("Content-Type"))
+			}
+			content, ok := response.Content[mediaType]
+			if !ok {
+				t.Fatalf("the Content-Type %s of the status %d of %s is not documented", mediaType, w.Code, operation.OperationID)
+			}
+
+			if mediaType == "application/json" {
+				if err := s.spec.ValidateJSON(content.Schema, w.Body.Bytes()); err != nil {
+					t.Errorf("the body doesn't match the document: %v\n%s", err, w.Body.String())
+				}
+			}
+		})
+	}
+
+	missing := make([]string, 0)
+	for _, operation := range s.spec.Operations() {
+		if !called[operation] {
+			missing = append(missing, operation.OperationID)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("the operations %v have no contract test", missing)
+	}
+}
@@ -1,12 +1,21 @@
 package server
 
 import (
+	"api/cache"
 	"api/config"
+	"api/logger"
 	"api/middleware"
+	"api/models"
+	"api/openapi"
 	"api/routes"
+	"api/services"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -15,31 +24,110 @@ import (
 )
 
 type Server struct {
-	Router *chi.Mux
-	DB     *gorm.DB
-	Port   int
+	Router     *chi.Mux
+	DB         *gorm.DB
+	Port       int
+	httpServer *http.Server
+	auth       *middleware.Authenticator
+	spec       *openapi.Spec           // the OpenAPI document, the request bodies are validated against it
+	limits     *middleware.RouteLimits // the rate and concurrency limits of the routes, shared by every collection
+	stop       chan struct{}           // closed by Shutdown to stop the background tasks
+	stopOnce   sync.Once
 }
 
 func NewServer(db *gorm.DB, port int) *Server {
-	return &Server{
+	s := &Server{
 		Router: chi.NewRouter(),
 		DB:     db,
 		Port:   port,
+		stop:   make(chan struct{}),
 	}
+	s.httpServer = s.newHTTPServer(config.GetConfig().HTTP)
+
+	return s
 }
 
+// Start listens until Shutdown is called, it returns nil after a shutdown
+// returns an error if the authentication config or the OpenAPI document are not valid
 func (s *Server) Start() error {
+	auth, err := middleware.NewAuthenticator(config.GetConfig().Auth)
+	if err != nil {
+		return err
+	}
+	s.auth = auth
+
+	spec, err := openapi.Load()
+	if err != nil {
+		return err
+	}
+	s.spec = spec
+	s.limits = middleware.NewRouteLimits(config.GetConfig().RateLimit)
 	s.setupRoutes()
+	s.watchDataVersion(config.GetConfig().SearchCache.CheckInterval)
 
 	log.Println("Server started on port:", s.Port)
-	return http.ListenAndServe(":"+strconv.Itoa(s.Port), s.Router)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for the requests in flight until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	return s.httpServer.Shutdown(ctx)
+}
+
+// watchDataVersion checks the data version of the collections every interval to invalidate the cache of the searches until Shutdown
+// the first check is done before the server listens, the searches are only cached after it
+func (s *Server) watchDataVersion(interval time.Duration) {
+	searchCache := cache.Search()
+	if searchCache == nil || s.DB == nil {
+		return
+	}
+
+	watcher := services.NewDataVersionWatcher(s.DB, searchCache)
+	check := func() {
+		if err := watcher.Check(context.Background()); err != nil {
+			logger.Logger().Error().Err(err).Msg("Cannot check the data version to invalidate the search cache")
+		}
+	}
+	check()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				check()
+			}
+		}
+	}()
+}
+
+// newHTTPServer returns the server of the router with the timeouts and the header limit of the config
+func (s *Server) newHTTPServer(cfg config.HTTPConfig) *http.Server {
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(s.Port),
+		Handler:           s.Router,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
 }
 
 func (s *Server) setupRoutes() *Server {
 
 	s.configCORS()
 	s.setupMiddleware(s.Router)
-	s.setupMailRoutes()
+	s.setupApiRoutes()
 	return s
 }
 
@@ -47,11 +135,33 @@ func (s *Server) setupMiddleware(router *chi.Mux) *Server {
 	router.Use(chiMiddleware.Recoverer)
 	router.Use(chiMiddleware.Logger)
 	router.Use(middleware.LogError)
+	router.Use(middleware.BodyLimit(config.GetConfig().HTTP.MaxBodyBytes))
 	return s
 }
 
-func (s *Server) setupMailRoutes() *Server {
-	routes.SetupMailRoutes(s.Router, s.DB)
+func (s *Server) setupApiRoutes() *Server {
+	s.Router.Route("/api", func(router chi.Router) {
+		// the OpenAPI document and its docs page are public
+		routes.SetupDocsRoutes(router, s.spec)
+
+		router.Group(func(r chi.Router) {
+			// every endpoint needs the read scope, the endpoints that change the data need the write scope
+			// the rate limit is by client, it is applied after the authentication to know the API key
+			// the requests rejected by the authentication are limited by IP
+			// the bodies are validated against the OpenAPI document after the authentication, before the handlers
+			r.Use(middleware.Authenticate(s.auth, s.limits.Unauthorized))
+			r.Use(s.limits.Default)
+			r.Use(middleware.RequireScope(models.ScopeRead))
+			r.Use(middleware.CacheBypass)
+			r.Use(middleware.ValidateRequest(s.spec))
+			routes.SetupMailRoutes(r, s.DB, s.limits)
+			routes.SetupCollectionRoutes(r, s.DB, s.limits)
+			routes.SetupEntityRoutes(r, s.DB)
+			routes.SetupSavedSearchRoutes(r, s.DB)
+			routes.SetupFolderRoutes(r, s.DB, s.limits)
+			routes.SetupCacheRoutes(r)
+		})
+	})
 	return s
 }
 
@@ -59,9 +169,9 @@ func (s *Server) configCORS() *Server {
 
 	s.Router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{config.GetConfig().ClientHost},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Cache-Control", middleware.APIKeyHeader},
+		ExposedHeaders:   []string{"Link", "Retry-After"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api/middleware"
+)
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	s := NewServer(nil, 0)
+	s.Router.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.httpServer.Serve(listener)
+	}()
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := <-status; code != http.StatusOK {
+		t.Errorf("the request in flight was cut off, status %d", code)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		t.Errorf("unexpected serve error %v", err)
+	}
+}
+
+// TestOwnersAreThePrincipals checks the owners of the saved searches and the authors of the annotations
+// are the subjects of the credentials and not the values of the body
+func TestOwnersAreThePrincipals(t *testing.T) {
+	s := setupOpenAPIServer(t)
+
+	ttc := []struct {
+		method   string
+		path     string
+		body     string
+		key      string
+		status   int
+		contains string
+	}{
+		{http.MethodPost, "/api/saved-searches", `{"owner":"reader","name":"libya","query":{"query":"libya"}}`, writerKey, http.StatusCreated, `"owner":"writer"`},
+		{http.MethodGet, "/api/saved-searches?owner=writer", "", readerKey, http.StatusOK, `"total":0`},
+		{http.MethodGet, "/api/saved-searches", "", writerKey, http.StatusOK, `"total":1`},
+		{http.MethodPost, "/api/saved-searches/1/new", "", adminKey, http.StatusNotFound, ""},
+		{http.MethodPost, "/api/saved-searches/1/new", "", writerKey, http.StatusOK, `"owner":"writer"`},
+		{http.MethodPost, "/api/mails/1/annotations", `{"author":"reader","note":"Which embassy?"}`, writerKey, http.StatusCreated, `"author":"writer"`},
+	}
+
+	for _, tt := range ttc {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		r.Header.Set(middleware.APIKeyHeader, tt.key)
+		w := httptest.NewRecorder()
+
+		s.Router.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", tt.method, tt.path, tt.status, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tt.contains) {
+			t.Errorf("%s %s: expected %s in %s", tt.method, tt.path, tt.contains, w.Body.String())
+		}
+	}
+}
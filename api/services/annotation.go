@@ -0,0 +1,202 @@
+package services
+
+import (
+	"api/config"
+	"api/models"
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ErrAnnotationNotFound is returned when the annotation does not exist in the email
+var ErrAnnotationNotFound = errors.New("annotation not found")
+
+// ErrAnnotationRange is returned when the range of the annotation is outside the content of the email
+var ErrAnnotationRange = errors.New("annotation range outside the content")
+
+// AnnotationService defines the interface for the annotations of the emails
+type AnnotationService interface {
+	// ListAnnotations retrieves the annotations of the email ordered by creation
+	ListAnnotations(ctx context.Context, collection string, emailID uint32) (*models.AnnotationResponse, error)
+	// CreateAnnotation adds an annotation to the email
+	CreateAnnotation(ctx context.Context, collection string, emailID uint32, request models.AnnotationRequest) (*models.Annotation, error)
+	// UpdateAnnotation replaces the author, the note and the range of an annotation of the email
+	UpdateAnnotation(ctx context.Context, collection string, emailID uint32, id int64, request models.AnnotationRequest) (*models.Annotation, error)
+	// DeleteAnnotation removes an annotation of the email
+	DeleteAnnotation(ctx context.Context, collection string, emailID uint32, id int64) error
+}
+
+type annotationService struct {
+	db *gorm.DB
+}
+
+// NewAnnotationService creates a new instance of AnnotationService
+func NewAnnotationService(db *gorm.DB) AnnotationService {
+	return &annotationService{
+		db: db,
+	}
+}
+
+// ListAnnotations implements AnnotationService interface
+func (s *annotationService) ListAnnotations(ctx context.Context, collection string, emailID uint32) (*models.AnnotationResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, err := s.emailContent(s.db.WithContext(ctx), collection, emailID); err != nil {
+		return nil, err
+	}
+
+	annotations := make([]models.Annotation, 0)
+	err := s.db.WithContext(ctx).
+		Table(s.table(collection)).
+		Where("email_id = ?", emailID).
+		Order("id").
+		Scan(&annotations).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve annotations", err)
+	}
+
+	return &models.AnnotationResponse{Annotations: annotations, Total: int64(len(annotations))}, nil
+}
+
+// CreateAnnotation implements AnnotationService interface
+// the range is in characters of the content of the email
+func (s *annotationService) CreateAnnotation(ctx context.Context, collection string, emailID uint32, request models.AnnotationRequest) (*models.Annotation, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	now := time.Now().UTC()
+	annotation := models.Annotation{
+		EmailID:   emailID,
+		Author:    request.Author,
+		Note:      request.Note,
+		Start:     request.Start,
+		End:       request.End,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkRange(tx, collection, emailID, request); err != nil {
+			return err
+		}
+
+		if err := tx.Table(s.table(collection)).Create(&annotation).Error; err != nil {
+			return models.NewApiError("cannot create annotation", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &annotation, nil
+}
+
+// UpdateAnnotation implements AnnotationService interface
+func (s *annotationService) UpdateAnnotation(ctx context.Context, collection string, emailID uint32, id int64, request models.AnnotationRequest) (*models.Annotation, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	annotation := models.Annotation{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkRange(tx, collection, emailID, request); err != nil {
+			return err
+		}
+
+		result := tx.Table(s.table(collection)).
+			Where("id = ? AND email_id = ?", id, emailID).
+			Updates(map[string]any{
+				"author":       request.Author,
+				"note":         request.Note,
+				"start_offset": request.Start,
+				"end_offset":   request.End,
+				"updated_at":   time.Now().UTC(),
+			})
+		if result.Error != nil {
+			return models.NewApiError("cannot update annotation", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrAnnotationNotFound
+		}
+
+		if err := tx.Table(s.table(collection)).Where("id = ?", id).Take(&annotation).Error; err != nil {
+			return models.NewApiError("cannot retrieve annotation", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &annotation, nil
+}
+
+// DeleteAnnotation implements AnnotationService interface
+func (s *annotationService) DeleteAnnotation(ctx context.Context, collection string, emailID uint32, id int64) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, err := s.emailContent(s.db.WithContext(ctx), collection, emailID); err != nil {
+		return err
+	}
+
+	result := s.db.WithContext(ctx).
+		Exec("DELETE FROM "+s.table(collection)+" WHERE id = ? AND email_id = ?", id, emailID)
+	if result.Error != nil {
+		return models.NewApiError("cannot delete annotation", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrAnnotationNotFound
+	}
+
+	return nil
+}
+
+// checkRange returns ErrEmailNotFound if the email is not in the collection
+// and ErrAnnotationRange if the end of the range is after the last character of the content
+func (s *annotationService) checkRange(tx *gorm.DB, collection string, emailID uint32, request models.AnnotationRequest) error {
+	content, err := s.emailContent(tx, collection, emailID)
+	if err != nil {
+		return err
+	}
+
+	if request.End != nil && *request.End > utf8.RuneCountInString(content) {
+		return ErrAnnotationRange
+	}
+
+	return nil
+}
+
+// emailContent returns the content of the email or ErrEmailNotFound if it is not in the collection
+func (s *annotationService) emailContent(tx *gorm.DB, collection string, emailID uint32) (string, error) {
+	contents := make([]string, 0, 1)
+	err := tx.Table(config.GetConfig().CollectionTable(collection, config.GetConfig().MailsTable)).
+		Where("id = ?", emailID).
+		Pluck("COALESCE(content, '')", &contents).Error
+	if err != nil {
+		return "", models.NewApiError("cannot retrieve email", err)
+	}
+
+	if len(contents) == 0 {
+		return "", ErrEmailNotFound
+	}
+
+	return contents[0], nil
+}
+
+// table returns the annotations table of the collection
+func (s *annotationService) table(collection string) string {
+	return config.GetConfig().CollectionTable(collection, config.GetConfig().AnnotationsTable)
+}
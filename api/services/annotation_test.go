@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"api/models"
+)
+
+func TestAnnotationService(t *testing.T) {
+	db := setupSQLite(t)
+	service := NewAnnotationService(db)
+	ctx := context.Background()
+	start, end := 4, 11
+
+	annotation, err := service.CreateAnnotation(ctx, "emails_hillary", 1, models.AnnotationRequest{Author: "analyst", Note: "Which embassy?", Start: &start, End: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if annotation.ID == 0 || annotation.EmailID != 1 || *annotation.Start != 4 || annotation.CreatedAt.IsZero() {
+		t.Errorf("unexpected annotation %+v", annotation)
+	}
+
+	if _, err := service.CreateAnnotation(ctx, "emails_hillary", 2, models.AnnotationRequest{Author: "reviewer", Note: "Call back"}); err != nil {
+		t.Fatal(err)
+	}
+
+	outside := 1000
+	if _, err := service.CreateAnnotation(ctx, "emails_hillary", 1, models.AnnotationRequest{Author: "analyst", Note: "too long", Start: &start, End: &outside}); !errors.Is(err, ErrAnnotationRange) {
+		t.Errorf("expected ErrAnnotationRange, got %v", err)
+	}
+
+	if _, err := service.CreateAnnotation(ctx, "emails_hillary", 99, models.AnnotationRequest{Author: "analyst", Note: "missing"}); !errors.Is(err, ErrEmailNotFound) {
+		t.Errorf("expected ErrEmailNotFound, got %v", err)
+	}
+
+	updated, err := service.UpdateAnnotation(ctx, "emails_hillary", 1, annotation.ID, models.AnnotationRequest{Author: "analyst", Note: "The embassy of Tripoli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Note != "The embassy of Tripoli" || updated.Start != nil || !updated.CreatedAt.Equal(annotation.CreatedAt) {
+		t.Errorf("unexpected updated annotation %+v", updated)
+	}
+
+	if _, err := service.UpdateAnnotation(ctx, "emails_hillary", 2, annotation.ID, models.AnnotationRequest{Author: "analyst", Note: "other email"}); !errors.Is(err, ErrAnnotationNotFound) {
+		t.Errorf("expected ErrAnnotationNotFound, got %v", err)
+	}
+
+	annotations, err := service.ListAnnotations(ctx, "emails_hillary", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if annotations.Total != 1 || annotations.Annotations[0].Author != "analyst" {
+		t.Errorf("unexpected annotations %+v", annotations)
+	}
+
+	search := NewSQLiteEmailService(db)
+	response, err := search.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Annotation: "TRIPOLI", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 1 || response.Emails[0].ID != 1 || !response.Emails[0].Annotated {
+		t.Errorf("unexpected search by annotation %+v", response.Emails)
+	}
+
+	response, err = search.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Limit: 10, OrderBy: models.OrderByAsc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Emails[0].Annotated || !response.Emails[1].Annotated || response.Emails[2].Annotated {
+		t.Errorf("unexpected annotated flags %+v", response.Emails)
+	}
+
+	if err := service.DeleteAnnotation(ctx, "emails_hillary", 1, annotation.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeleteAnnotation(ctx, "emails_hillary", 1, annotation.ID); !errors.Is(err, ErrAnnotationNotFound) {
+		t.Errorf("expected ErrAnnotationNotFound, got %v", err)
+	}
+}
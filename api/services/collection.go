@@ -0,0 +1,70 @@
+package services
+
+import (
+	"api/config"
+	"api/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// CollectionService defines the interface for the collections created by the indexer
+type CollectionService interface {
+	// ListCollections retrieves the collections ordered by name
+	ListCollections(ctx context.Context) ([]models.Collection, error)
+	// CollectionExists checks if the collection is registered
+	CollectionExists(ctx context.Context, name string) (bool, error)
+}
+
+type collectionService struct {
+	db *gorm.DB
+}
+
+// NewCollectionService creates a new instance of CollectionService
+func NewCollectionService(db *gorm.DB) CollectionService {
+	return &collectionService{
+		db: db,
+	}
+}
+
+// ListCollections implements CollectionService interface
+func (s *collectionService) ListCollections(ctx context.Context) ([]models.Collection, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	collections := make([]models.Collection, 0)
+	err := s.db.WithContext(ctx).
+		Table(config.GetConfig().CollectionsTable()).
+		Select("name, description, created_at").
+		Order("name").
+		Scan(&collections).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve collections", err)
+	}
+
+	return collections, nil
+}
+
+// CollectionExists implements CollectionService interface
+// the names that are not valid schema names never exist
+func (s *collectionService) CollectionExists(ctx context.Context, name string) (bool, error) {
+	if !models.IsValidCollectionName(name) {
+		return false, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var total int64
+	err := s.db.WithContext(ctx).
+		Table(config.GetConfig().CollectionsTable()).
+		Where("name = ?", name).
+		Count(&total).Error
+	if err != nil {
+		return false, models.NewApiError("cannot retrieve collection", err)
+	}
+
+	return total > 0, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCollectionService(t *testing.T) {
+	db := setupSQLite(t)
+	statements := []string{
+		`CREATE TABLE collections (name TEXT PRIMARY KEY, description TEXT NOT NULL DEFAULT '', created_at TIMESTAMP NOT NULL)`,
+		`INSERT INTO collections VALUES ('emails_hillary', 'Hillary Clinton emails', '` + time.Now().UTC().Format(time.RFC3339) + `')`,
+		`INSERT INTO collections VALUES ('dnc_emails', '', '` + time.Now().UTC().Format(time.RFC3339) + `')`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := NewCollectionService(db)
+
+	collections, err := service.ListCollections(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections) != 2 || collections[0].Name != "dnc_emails" || collections[1].Description != "Hillary Clinton emails" {
+		t.Errorf("unexpected collections %+v", collections)
+	}
+
+	ttc := []struct {
+		name     string
+		expected bool
+	}{
+		{"emails_hillary", true},
+		{"podesta_emails", false},
+		{"emails_hillary; DROP TABLE collections", false},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, err := service.CollectionExists(context.Background(), tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exists != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, exists)
+			}
+		})
+	}
+}
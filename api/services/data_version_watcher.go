@@ -0,0 +1,74 @@
+package services
+
+import (
+	"api/cache"
+	"api/config"
+	"api/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// DataVersionWatcher invalidates the cache of a collection when the indexer changes the version of its data
+// the indexer increments the version after the runs, the imports, the replays of the dead letters,
+// the extraction of the entities and the reindex of the search vectors
+type DataVersionWatcher struct {
+	db          *gorm.DB
+	cache       cache.Cache
+	collections CollectionService
+	versions    map[string]int64
+}
+
+// NewDataVersionWatcher creates the DataVersionWatcher of the cache
+func NewDataVersionWatcher(db *gorm.DB, c cache.Cache) *DataVersionWatcher {
+	return &DataVersionWatcher{
+		db:          db,
+		cache:       c,
+		collections: NewCollectionService(db),
+		versions:    make(map[string]int64),
+	}
+}
+
+// Check reads the data version of the collections and invalidates the cache of the collections with a new version
+// the first check of a collection only records its version, the server checks them before it serves the searches
+// the errors of a collection don't stop the check of the others, it must not be called concurrently
+func (w *DataVersionWatcher) Check(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	names := []string{config.GetConfig().SchemaName}
+	collections, err := w.collections.ListCollections(ctx)
+	if err != nil {
+		return err
+	}
+	for _, collection := range collections {
+		if collection.Name != config.GetConfig().SchemaName {
+			names = append(names, collection.Name)
+		}
+	}
+
+	var errs []error
+	for _, name := range names {
+		var version int64
+		err := w.db.WithContext(ctx).
+			Table(config.GetConfig().CollectionTable(name, config.GetConfig().DataVersionTable)).
+			Select("version").
+			Where("id = 1").
+			Scan(&version).Error
+		if err != nil {
+			errs = append(errs, models.NewApiError(fmt.Sprintf("cannot retrieve the data version of %s", name), err))
+			continue
+		}
+
+		last, ok := w.versions[name]
+		w.versions[name] = version
+		if ok && last != version {
+			w.cache.Invalidate(ctx, name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
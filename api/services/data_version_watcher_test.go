@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"api/cache"
+)
+
+func TestDataVersionWatcher(t *testing.T) {
+	ctx := context.Background()
+	db := setupSQLite(t)
+	statements := []string{
+		`CREATE TABLE collections (name TEXT PRIMARY KEY, description TEXT NOT NULL DEFAULT '', created_at TIMESTAMP NOT NULL)`,
+		`CREATE TABLE "emails_hillary_data_version" (id INTEGER PRIMARY KEY CHECK (id = 1), version INTEGER NOT NULL DEFAULT 0, updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO "emails_hillary_data_version" (id, version) VALUES (1, 3)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lru := cache.NewLRU(10, 0)
+	watcher := NewDataVersionWatcher(db, lru)
+	lru.Set(ctx, "emails_hillary", "libya", 0, []byte("{}"))
+
+	if err := watcher.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lru.Get(ctx, "emails_hillary", "libya"); !ok {
+		t.Fatal("the first check must not invalidate the cache")
+	}
+
+	if err := watcher.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lru.Get(ctx, "emails_hillary", "libya"); !ok {
+		t.Fatal("the same version must not invalidate the cache")
+	}
+
+	// an import, a replay or a reindex of the indexer increments the version
+	if err := db.Exec(`UPDATE "emails_hillary_data_version" SET version = version + 1 WHERE id = 1`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lru.Get(ctx, "emails_hillary", "libya"); ok {
+		t.Error("the new version must invalidate the cache")
+	}
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"api/cache"
+	"api/config"
 	"api/models"
 	"api/sanatizer"
 	"context"
@@ -19,8 +21,8 @@ type GetEmailsResponse struct {
 
 // EmailService defines the interface for email-related operations
 type EmailService interface {
-	// GetEmails retrieves a paginated list of emails
-	SearchEmails(ctx context.Context, query models.QuerySearch) (*GetEmailsResponse, error)
+	// SearchEmails retrieves a paginated list of emails of a collection
+	SearchEmails(ctx context.Context, collection string, query models.QuerySearch) (*GetEmailsResponse, error)
 }
 
 type emailService struct {
@@ -34,10 +36,20 @@ func NewEmailService(db *gorm.DB) EmailService {
 	}
 }
 
+// NewEmailServiceByDriver creates the EmailService of the database driver
+// the searches are cached in cache.Search if the cache is enabled
+func NewEmailServiceByDriver(driver string, db *gorm.DB) EmailService {
+	if driver == config.DriverSQLite {
+		return NewCachedEmailService(NewSQLiteEmailService(db), cache.Search())
+	}
+
+	return NewCachedEmailService(NewEmailService(db), cache.Search())
+}
+
 // SearchEmails implements EmailService interface
 // SearchEmails retrieves a paginated list of emails based on a search query using inverted index
 // can filter and order by Date
-func (s *emailService) SearchEmails(ctx context.Context, query models.QuerySearch) (*GetEmailsResponse, error) {
+func (s *emailService) SearchEmails(ctx context.Context, collection string, query models.QuerySearch) (*GetEmailsResponse, error) {
 
 	query = *query.Normalize()
 
@@ -49,22 +61,9 @@ func (s *emailService) SearchEmails(ctx context.Context, query models.QuerySearc
 	var emailsRank []models.EmailRank
 	var total int64
 
-	query.Query = sanatizer.SanitizeForSQL(query.Query, 80)
-
-	if len(query.Query) > 80 {
-		return nil, models.NewApiError("query too long", nil)
-	}
-
-	queriesSplit := strings.Split(query.Query, " ")
-	sanitizedQueries := make([]string, 0)
-	for _, query := range queriesSplit {
-		query = strings.TrimSpace(query)
-		query = regexp.MustCompile(`[^a-zA-Z0-9]`).ReplaceAllString(query, "")
-		if query == "" {
-			continue
-		}
-
-		sanitizedQueries = append(sanitizedQueries, query)
+	sanitizedQueries, err := sanitizeSearchTerms(&query)
+	if err != nil {
+		return nil, err
 	}
 
 	var querySearch string
@@ -80,18 +79,24 @@ func (s *emailService) SearchEmails(ctx context.Context, query models.QuerySearc
 
 	// create query to find mails
 	if len(querySearch) < 2 {
-		tx = s.createAllMailsSearchQuery(ctx, query)
+		tx = s.createAllMailsSearchQuery(ctx, collection, query)
 	} else {
-		tx = s.createQuerySearch(ctx, query, querySearch)
+		tx = s.createQuerySearch(ctx, collection, query, querySearch)
 	}
 
+	tx = filterByEntities(tx, collection, query.Entities)
+	tx = filterByTags(tx, collection, query.Tags)
+	tx = filterByAnnotation(tx, collection, query.Annotation)
+	tx = filterByParticipants(tx, query.From, query.To)
+	tx = filterByDateRange(tx, "e.date::date", query.DateFrom, query.DateTo)
+
 	// count total
 	tx.Count(&total)
 
 	// limit and offset
 	tx = tx.Limit(query.Limit).Offset(offset)
 
-	err := tx.Scan(&emailsRank).Error
+	err = tx.Scan(&emailsRank).Error
 	if err != nil {
 		return nil, models.NewApiError("cannot retrieve emails", err)
 	}
@@ -107,20 +112,179 @@ func (s *emailService) SearchEmails(ctx context.Context, query models.QuerySearc
 		})
 	}
 
+	if err := markAnnotated(s.db.WithContext(ctx), collection, emails); err != nil {
+		return nil, err
+	}
+
+	if err := markFolders(s.db.WithContext(ctx), collection, emails); err != nil {
+		return nil, err
+	}
+
 	return &GetEmailsResponse{Emails: emails, Total: total}, nil
 }
 
-func (s *emailService) createAllMailsSearchQuery(ctx context.Context, query models.QuerySearch) *gorm.DB {
+// filterByEntities keeps the emails that mention all the entities, the names are compared without case
+func filterByEntities(tx *gorm.DB, collection string, entities []string) *gorm.DB {
+	cfg := config.GetConfig()
+	for _, entity := range entities {
+		tx = tx.Where("e.id IN (SELECT email_id FROM "+cfg.CollectionTable(collection, cfg.EntitiesTable)+" WHERE lower(entity) = lower(?))", entity)
+	}
+
+	return tx
+}
+
+// filterByTags keeps the emails that have all the tags
+func filterByTags(tx *gorm.DB, collection string, tags []string) *gorm.DB {
+	cfg := config.GetConfig()
+	for _, tag := range tags {
+		tx = tx.Where("e.id IN (SELECT email_id FROM "+cfg.CollectionTable(collection, cfg.TagsTable)+" WHERE tag = ?)", tag)
+	}
+
+	return tx
+}
+
+// filterByAnnotation keeps the emails with an annotation that contains the text, without case
+func filterByAnnotation(tx *gorm.DB, collection, text string) *gorm.DB {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return tx
+	}
+
+	cfg := config.GetConfig()
+	return tx.Where("e.id IN (SELECT email_id FROM "+cfg.CollectionTable(collection, cfg.AnnotationsTable)+` WHERE lower(note) LIKE ? ESCAPE '\')`, "%"+strings.ToLower(escapeLike(text))+"%")
+}
+
+// filterByParticipants keeps the emails whose sender contains from and whose recipients contain to, without case
+// the empty values don't filter
+func filterByParticipants(tx *gorm.DB, from, to string) *gorm.DB {
+	if from = strings.TrimSpace(from); from != "" {
+		tx = tx.Where(`lower(e."from") LIKE ? ESCAPE '\'`, "%"+strings.ToLower(escapeLike(from))+"%")
+	}
+
+	if to = strings.TrimSpace(to); to != "" {
+		tx = tx.Where(`lower(e."to") LIKE ? ESCAPE '\'`, "%"+strings.ToLower(escapeLike(to))+"%")
+	}
+
+	return tx
+}
+
+// filterByDateRange keeps the emails from the day of from to the day of to, both included
+// dateColumn is the day of the email in the SQL of the driver, the nil dates don't filter
+func filterByDateRange(tx *gorm.DB, dateColumn string, from, to *models.DateParam) *gorm.DB {
+	if from != nil && from.Valid {
+		tx = tx.Where(dateColumn+" >= ?", from.Day())
+	}
+
+	if to != nil && to.Valid {
+		tx = tx.Where(dateColumn+" <= ?", to.Day())
+	}
+
+	return tx
+}
+
+// markAnnotated sets Annotated in the emails that have annotations
+func markAnnotated(db *gorm.DB, collection string, emails []models.Email) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	ids := make([]uint32, 0, len(emails))
+	for _, email := range emails {
+		ids = append(ids, email.ID)
+	}
+
+	annotated := make([]uint32, 0)
+	err := db.Table(config.GetConfig().CollectionTable(collection, config.GetConfig().AnnotationsTable)).
+		Distinct("email_id").
+		Where("email_id IN ?", ids).
+		Pluck("email_id", &annotated).Error
+	if err != nil {
+		return models.NewApiError("cannot retrieve annotations", err)
+	}
+
+	set := make(map[uint32]bool, len(annotated))
+	for _, id := range annotated {
+		set[id] = true
+	}
+
+	for i := range emails {
+		emails[i].Annotated = set[emails[i].ID]
+	}
+
+	return nil
+}
+
+// markFolders sets Folders in the emails with the ids of their folders
+func markFolders(db *gorm.DB, collection string, emails []models.Email) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	ids := make([]uint32, 0, len(emails))
+	for _, email := range emails {
+		ids = append(ids, email.ID)
+	}
+
+	items := make([]struct {
+		FolderID int64
+		EmailID  uint32
+	}, 0)
+	err := db.Table(config.GetConfig().CollectionTable(collection, config.GetConfig().FolderItemsTable)).
+		Select("folder_id, email_id").
+		Where("email_id IN ?", ids).
+		Order("folder_id").
+		Scan(&items).Error
+	if err != nil {
+		return models.NewApiError("cannot retrieve folders", err)
+	}
+
+	folders := make(map[uint32][]int64, len(items))
+	for _, item := range items {
+		folders[item.EmailID] = append(folders[item.EmailID], item.FolderID)
+	}
+
+	for i := range emails {
+		emails[i].Folders = folders[emails[i].ID]
+	}
+
+	return nil
+}
+
+// sanitizeSearchTerms cleans the query and returns the words to search
+// only letters and numbers are kept in each word
+func sanitizeSearchTerms(query *models.QuerySearch) ([]string, error) {
+	query.Query = sanatizer.SanitizeForSQL(query.Query, 80)
+
+	if len(query.Query) > 80 {
+		return nil, models.NewApiError("query too long", nil)
+	}
+
+	queriesSplit := strings.Split(query.Query, " ")
+	sanitizedQueries := make([]string, 0)
+	for _, query := range queriesSplit {
+		query = strings.TrimSpace(query)
+		query = regexp.MustCompile(`[^a-zA-Z0-9]`).ReplaceAllString(query, "")
+		if query == "" {
+			continue
+		}
+
+		sanitizedQueries = append(sanitizedQueries, query)
+	}
+
+	return sanitizedQueries, nil
+}
+
+func (s *emailService) createAllMailsSearchQuery(ctx context.Context, collection string, query models.QuerySearch) *gorm.DB {
 	whereComparison := fmt.Sprintf("e.date::date %s ?", string(query.DateSearch.Operator))
 	dateOrderBy := clause.OrderByColumn{Column: clause.Column{Name: "e.date"}, Desc: query.OrderBy == models.OrderByDesc}
 	orderBy := clause.OrderByColumn{Column: clause.Column{Name: "e.id"}, Desc: query.OrderBy == models.OrderByDesc}
 
 	tx := s.db.WithContext(ctx).
-		Table("emails_hillary.emails e").
+		Table(config.GetConfig().CollectionTable(collection, config.GetConfig().MailsTable) + " e").
 		Select(`e.id, e.subject, e."from", e."to", e.content, e.date`)
 
 	if query.DateSearch.Date != nil && query.DateSearch.Date.Valid {
-		tx = tx.Where(whereComparison, query.DateSearch.Date.Format("2006-01-02"))
+		tx = tx.Where(whereComparison, query.DateSearch.Date.Day())
 	}
 
 	if query.DateSearch.Date != nil && query.DateSearch.Date.Valid {
@@ -132,9 +296,12 @@ func (s *emailService) createAllMailsSearchQuery(ctx context.Context, query mode
 	return tx
 }
 
-func (s *emailService) createQuerySearch(ctx context.Context, query models.QuerySearch, querySearch string) *gorm.DB {
-	tx := s.db.WithContext(ctx).Table("emails_hillary.emails_search es").
-		Joins("JOIN emails_hillary.emails e ON e.id = es.id").
+func (s *emailService) createQuerySearch(ctx context.Context, collection string, query models.QuerySearch, querySearch string) *gorm.DB {
+	cfg := config.GetConfig()
+	// the query uses the text search config of the vectors, it is changed by reindex-search of the indexer
+	searchConfig := "(SELECT config FROM " + cfg.CollectionTable(collection, cfg.SearchConfigTable) + " WHERE id = 1)::REGCONFIG"
+	tx := s.db.WithContext(ctx).Table(cfg.CollectionTable(collection, cfg.MailSearchTable)+" es").
+		Joins("JOIN "+cfg.CollectionTable(collection, cfg.MailsTable)+" e ON e.id = es.id").
 		Select(`
 			e.id, 
 			e.subject, 
@@ -142,19 +309,19 @@ func (s *emailService) createQuerySearch(ctx context.Context, query models.Query
 			e."to", 
 			e.content, 
 			e.date,
-			ts_rank(es.search_vector, to_tsquery('english', ?)) AS rank`,
+			ts_rank(es.search_vector, to_tsquery(`+searchConfig+`, ?)) AS rank`,
 			querySearch)
 
 	// if date exist order by date first before order by rank
 	if query.DateSearch.Date != nil && query.DateSearch.Date.Valid {
 		whereComparison := fmt.Sprintf("e.date::date %s ?", string(query.DateSearch.Operator))
 		orderBy := clause.OrderByColumn{Column: clause.Column{Name: "e.date"}, Desc: query.OrderBy == models.OrderByDesc}
-		tx = tx.Where(whereComparison, query.DateSearch.Date.Format("2006-01-02")).
+		tx = tx.Where(whereComparison, query.DateSearch.Date.Day()).
 			Order(orderBy)
 	}
 
 	tx = tx.Order("rank DESC")
-	tx = tx.Where("es.search_vector @@ to_tsquery('english', ?)", querySearch)
+	tx = tx.Where("es.search_vector @@ to_tsquery("+searchConfig+", ?)", querySearch)
 
 	return tx
 }
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"api/cache"
+	"api/models"
+	"context"
+	"encoding/json"
+)
+
+// cachedEmailService stores the results of the searches of an EmailService in a cache
+// the results are keyed by the collection and the normalized query
+type cachedEmailService struct {
+	EmailService
+	cache cache.Cache
+}
+
+// NewCachedEmailService creates an EmailService that caches the searches of service, service itself if c is nil
+func NewCachedEmailService(service EmailService, c cache.Cache) EmailService {
+	if c == nil {
+		return service
+	}
+
+	return &cachedEmailService{
+		EmailService: service,
+		cache:        c,
+	}
+}
+
+// SearchEmails implements EmailService interface
+// the results are read from the cache unless the context bypasses it, the results of the database are stored
+// unless the collection was invalidated during the search, they could be older than the invalidation
+func (s *cachedEmailService) SearchEmails(ctx context.Context, collection string, query models.QuerySearch) (*GetEmailsResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	query.Normalize()
+
+	key, err := json.Marshal(query)
+	if err != nil {
+		return s.EmailService.SearchEmails(ctx, collection, query)
+	}
+
+	if cache.IsBypassed(ctx) {
+		s.cache.RecordBypass()
+	} else if value, ok := s.cache.Get(ctx, collection, string(key)); ok {
+		var response GetEmailsResponse
+		if err := json.Unmarshal(value, &response); err == nil {
+			return &response, nil
+		}
+	}
+
+	generation := s.cache.Generation(ctx, collection)
+	response, err := s.EmailService.SearchEmails(ctx, collection, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, err := json.Marshal(response); err == nil {
+		s.cache.Set(ctx, collection, string(key), generation, value)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"api/cache"
+	"api/models"
+)
+
+// countingEmailService counts the searches that reach the database
+type countingEmailService struct {
+	EmailService
+	searches int
+}
+
+func (s *countingEmailService) SearchEmails(ctx context.Context, collection string, query models.QuerySearch) (*GetEmailsResponse, error) {
+	s.searches++
+	return s.EmailService.SearchEmails(ctx, collection, query)
+}
+
+func TestCachedEmailService(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmailService{EmailService: NewSQLiteEmailService(setupSQLite(t))}
+	lru := cache.NewLRU(10, time.Minute)
+	service := NewCachedEmailService(inner, lru)
+
+	response, err := service.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Query: "libya", Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same query once normalized
+	cached, err := service.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Query: "libya", TypeSearch: models.TypeSearchAND, OrderBy: models.OrderByDesc, Page: 0, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.searches != 1 {
+		t.Fatalf("expected 1 search in the database, got %d", inner.searches)
+	}
+	if cached.Total != response.Total || len(cached.Emails) != len(response.Emails) || cached.Emails[0].ID != response.Emails[0].ID || !cached.Emails[0].Date.Equal(response.Emails[0].Date) {
+		t.Errorf("expected the cached %+v, got %+v", response, cached)
+	}
+
+	if _, err := service.SearchEmails(cache.WithBypass(ctx), "emails_hillary", models.QuerySearch{Query: "libya", Page: 1, Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.searches != 2 {
+		t.Errorf("the bypass must search in the database, got %d searches", inner.searches)
+	}
+	if stats := lru.Stats(); stats.Bypasses != 1 || stats.Hits != 1 {
+		t.Errorf("expected 1 bypass and 1 hit, got %+v", stats)
+	}
+
+	lru.Invalidate(ctx, "emails_hillary")
+	if _, err := service.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Query: "libya", Page: 1, Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.searches != 3 {
+		t.Errorf("the invalidation must search in the database, got %d searches", inner.searches)
+	}
+
+	if NewCachedEmailService(inner, nil) != inner {
+		t.Error("without cache the service must not be wrapped")
+	}
+}
+
+// invalidatingEmailService invalidates the cache during the searches, as an index run that ends while a search runs
+type invalidatingEmailService struct {
+	EmailService
+	cache cache.Cache
+}
+
+func (s *invalidatingEmailService) SearchEmails(ctx context.Context, collection string, query models.QuerySearch) (*GetEmailsResponse, error) {
+	response, err := s.EmailService.SearchEmails(ctx, collection, query)
+	s.cache.Invalidate(ctx, collection)
+	return response, err
+}
+
+func TestCachedEmailServiceSkipsStaleResults(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(10, time.Minute)
+	service := NewCachedEmailService(&invalidatingEmailService{EmailService: NewSQLiteEmailService(setupSQLite(t)), cache: lru}, lru)
+
+	if _, err := service.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Query: "libya", Page: 1, Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := lru.Stats(); stats.Entries != 0 {
+		t.Errorf("the result of a search invalidated while it ran must not be stored, got %+v", stats)
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"api/config"
+	"api/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type sqliteEmailService struct {
+	db *gorm.DB
+}
+
+// NewSQLiteEmailService creates a new instance of EmailService for a SQLite database
+// the search uses the FTS5 table created by the indexer
+func NewSQLiteEmailService(db *gorm.DB) EmailService {
+	return &sqliteEmailService{
+		db: db,
+	}
+}
+
+// SearchEmails implements EmailService interface
+// SearchEmails retrieves a paginated list of emails based on a search query using FTS5
+// can filter and order by Date
+func (s *sqliteEmailService) SearchEmails(ctx context.Context, collection string, query models.QuerySearch) (*GetEmailsResponse, error) {
+
+	query = *query.Normalize()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var emails []models.Email
+	var emailsRank []models.EmailRank
+	var total int64
+
+	sanitizedQueries, err := sanitizeSearchTerms(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	var querySearch string
+
+	if query.TypeSearch == models.TypeSearchAND {
+		querySearch = strings.Join(sanitizedQueries, " AND ")
+	} else {
+		querySearch = strings.Join(sanitizedQueries, " OR ")
+	}
+
+	offset := (query.Page - 1) * query.Limit
+	var tx *gorm.DB
+
+	// create query to find mails
+	if len(querySearch) < 2 {
+		tx = s.createAllMailsSearchQuery(ctx, collection, query)
+	} else {
+		tx = s.createQuerySearch(ctx, collection, query, matchExpression(sanitizedQueries, query.TypeSearch))
+	}
+
+	tx = filterByEntities(tx, collection, query.Entities)
+	tx = filterByTags(tx, collection, query.Tags)
+	tx = filterByAnnotation(tx, collection, query.Annotation)
+	tx = filterByParticipants(tx, query.From, query.To)
+	tx = filterByDateRange(tx, "date(e.date)", query.DateFrom, query.DateTo)
+
+	// count total
+	tx.Count(&total)
+
+	// limit and offset
+	tx = tx.Limit(query.Limit).Offset(offset)
+
+	err = tx.Scan(&emailsRank).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve emails", err)
+	}
+
+	for _, email := range emailsRank {
+		emails = append(emails, models.Email{
+			ID:      email.ID,
+			Date:    email.Date,
+			Subject: email.Subject,
+			From:    email.From,
+			To:      email.To,
+			Content: email.Content,
+		})
+	}
+
+	if err := markAnnotated(s.db.WithContext(ctx), collection, emails); err != nil {
+		return nil, err
+	}
+
+	if err := markFolders(s.db.WithContext(ctx), collection, emails); err != nil {
+		return nil, err
+	}
+
+	return &GetEmailsResponse{Emails: emails, Total: total}, nil
+}
+
+func (s *sqliteEmailService) createAllMailsSearchQuery(ctx context.Context, collection string, query models.QuerySearch) *gorm.DB {
+	whereComparison := fmt.Sprintf("date(e.date) %s ?", string(query.DateSearch.Operator))
+	dateOrderBy := clause.OrderByColumn{Column: clause.Column{Name: "e.date"}, Desc: query.OrderBy == models.OrderByDesc}
+	orderBy := clause.OrderByColumn{Column: clause.Column{Name: "e.id"}, Desc: query.OrderBy == models.OrderByDesc}
+
+	tx := s.db.WithContext(ctx).
+		Table(s.table(collection, config.GetConfig().MailsTable) + " e").
+		Select(`e.id, e.subject, e."from", e."to", e.content, e.date`)
+
+	if query.DateSearch.Date != nil && query.DateSearch.Date.Valid {
+		tx = tx.Where(whereComparison, query.DateSearch.Date.Day())
+		orderBy = dateOrderBy
+	}
+
+	tx = tx.Order(orderBy)
+
+	return tx
+}
+
+func (s *sqliteEmailService) createQuerySearch(ctx context.Context, collection string, query models.QuerySearch, querySearch string) *gorm.DB {
+	searchTable := s.table(collection, config.GetConfig().MailSearchTable)
+
+	// the FTS5 table is not aliased, MATCH and bm25 need the name of the table
+	// bm25 returns lower values for better matches, it is negated to order by rank DESC as cockroach
+	tx := s.db.WithContext(ctx).Table(searchTable).
+		Joins("JOIN " + s.table(collection, config.GetConfig().MailsTable) + " e ON e.id = " + searchTable + ".rowid").
+		Select(`
+			e.id,
+			e.subject,
+			e."from",
+			e."to",
+			e.content,
+			e.date,
+			-bm25(` + searchTable + `) AS rank`)
+
+	// if date exist order by date first before order by rank
+	if query.DateSearch.Date != nil && query.DateSearch.Date.Valid {
+		whereComparison := fmt.Sprintf("date(e.date) %s ?", string(query.DateSearch.Operator))
+		orderBy := clause.OrderByColumn{Column: clause.Column{Name: "e.date"}, Desc: query.OrderBy == models.OrderByDesc}
+		tx = tx.Where(whereComparison, query.DateSearch.Date.Day()).
+			Order(orderBy)
+	}
+
+	tx = tx.Order("rank DESC")
+	tx = tx.Where(searchTable+" MATCH ?", querySearch)
+
+	return tx
+}
+
+// matchExpression joins the terms with the AND or OR operator of FTS5
+// the terms are quoted, AND, OR, NOT and NEAR are searched as words and not read as operators
+func matchExpression(terms []string, typeSearch models.TypeSearch) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+term+`"`)
+	}
+
+	if typeSearch == models.TypeSearchAND {
+		return strings.Join(quoted, " AND ")
+	}
+
+	return strings.Join(quoted, " OR ")
+}
+
+// table returns the name of a table of the collection
+func (s *sqliteEmailService) table(collection, name string) string {
+	return config.GetConfig().CollectionTable(collection, name)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api/config"
+	"api/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupSQLite creates the tables as the indexer does and inserts the test emails
+// the driver of the config is sqlite until the end of the test
+func setupSQLite(t *testing.T) *gorm.DB {
+	cfg := config.GetConfig()
+	driver := cfg.Driver
+	cfg.Driver = config.DriverSQLite
+	t.Cleanup(func() { cfg.Driver = driver })
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements := []string{
+		`CREATE TABLE "emails_hillary_emails" (id INTEGER PRIMARY KEY, date TIMESTAMP NOT NULL, subject TEXT DEFAULT '', "from" TEXT DEFAULT '', "to" TEXT DEFAULT '', content TEXT DEFAULT '')`,
+		`CREATE VIRTUAL TABLE "emails_hillary_emails_search" USING fts5(subject, "from", "to", content, tokenize = 'porter unicode61')`,
+		`CREATE TABLE "emails_hillary_email_annotations" (id INTEGER PRIMARY KEY AUTOINCREMENT, email_id INTEGER NOT NULL, author TEXT NOT NULL, note TEXT NOT NULL, start_offset INTEGER, end_offset INTEGER, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL)`,
+		`CREATE TABLE "emails_hillary_folders" (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, UNIQUE (name))`,
+		`CREATE TABLE "emails_hillary_folder_items" (folder_id INTEGER NOT NULL, email_id INTEGER NOT NULL, position INTEGER NOT NULL, added_at TIMESTAMP NOT NULL, PRIMARY KEY (folder_id, email_id))`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	emails := []models.Email{
+		{ID: 1, Date: time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC), Subject: "Libya update", From: "Jake Sullivan", To: "H", Content: "the embassy is running"},
+		{ID: 2, Date: time.Date(2012, 9, 11, 22, 0, 0, 0, time.UTC), Subject: "Benghazi", From: "Cheryl Mills", To: "H", Content: "call me about libya"},
+		{ID: 3, Date: time.Date(2012, 9, 12, 8, 0, 0, 0, time.UTC), Subject: "Schedule", From: "Huma Abedin", To: "H", Content: "meeting at noon"},
+	}
+	for _, e := range emails {
+		if err := db.Exec(`INSERT INTO "emails_hillary_emails" VALUES (?, ?, ?, ?, ?, ?)`, e.ID, e.Date, e.Subject, e.From, e.To, e.Content).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec(`INSERT INTO "emails_hillary_emails_search" (rowid, subject, "from", "to", content) VALUES (?, ?, ?, ?, ?)`, e.ID, e.Subject, e.From, e.To, e.Content).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+func TestSQLiteSearchEmails(t *testing.T) {
+	service := NewSQLiteEmailService(setupSQLite(t))
+	date := models.DateParam{Time: time.Date(2012, 9, 11, 0, 0, 0, 0, time.UTC), Valid: true}
+	lastDate := models.DateParam{Time: time.Date(2012, 9, 12, 0, 0, 0, 0, time.UTC), Valid: true}
+	// 2012-09-12 in UTC
+	offsetDate := models.DateParam{Time: time.Date(2012, 9, 11, 22, 0, 0, 0, time.FixedZone("EDT", -4*60*60)), Valid: true}
+
+	ttc := []struct {
+		name     string
+		query    models.QuerySearch
+		expected []uint32
+	}{
+		{"must return all the mails ordered by id", models.QuerySearch{Limit: 10, OrderBy: models.OrderByAsc}, []uint32{1, 2, 3}},
+		{"must find the stemmed word", models.QuerySearch{Query: "run", Limit: 10}, []uint32{1}},
+		{"must find any word with OR", models.QuerySearch{Query: "libya noon", TypeSearch: models.TypeSearchOR, Limit: 10}, []uint32{1, 2, 3}},
+		{"must find all the words with AND", models.QuerySearch{Query: "libya call", TypeSearch: models.TypeSearchAND, Limit: 10}, []uint32{2}},
+		{"must filter by date", models.QuerySearch{Query: "libya", Limit: 10, DateSearch: models.DateSearch{Date: &date, Operator: models.OperatorEqual}}, []uint32{2}},
+		{"must paginate", models.QuerySearch{Limit: 1, Page: 2, OrderBy: models.OrderByAsc}, []uint32{2}},
+		{"must filter by the sender without case", models.QuerySearch{From: "cheryl", Limit: 10}, []uint32{2}},
+		{"must filter by the recipients", models.QuerySearch{Query: "libya", To: "h", Limit: 10, OrderBy: models.OrderByAsc}, []uint32{1, 2}},
+		{"must filter by the range of days included", models.QuerySearch{DateFrom: &date, DateTo: &lastDate, Limit: 10, OrderBy: models.OrderByAsc}, []uint32{2, 3}},
+		{"must filter from a day", models.QuerySearch{Query: "libya", DateFrom: &lastDate, Limit: 10}, []uint32{}},
+		{"must filter by the day in UTC of a time with an offset", models.QuerySearch{DateTo: &offsetDate, Limit: 10, OrderBy: models.OrderByAsc}, []uint32{1, 2, 3}},
+		{"must search the operators as words", models.QuerySearch{Query: "libya NOT", Limit: 10}, []uint32{}},
+		{"must search the operators as words with OR", models.QuerySearch{Query: "NEAR noon AND", TypeSearch: models.TypeSearchOR, Limit: 10}, []uint32{3}},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.SearchEmails(context.Background(), "emails_hillary", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := make([]uint32, 0, len(response.Emails))
+			for _, email := range response.Emails {
+				ids = append(ids, email.ID)
+			}
+
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("SearchEmails returned %v, expected %v", ids, tt.expected)
+			}
+
+			if tt.query.Query == "" || len(ids) == 1 {
+				for i := range ids {
+					if ids[i] != tt.expected[i] {
+						t.Errorf("SearchEmails returned %v, expected %v", ids, tt.expected)
+					}
+				}
+			}
+		})
+	}
+}
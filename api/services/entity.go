@@ -0,0 +1,79 @@
+package services
+
+import (
+	"api/config"
+	"api/models"
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// EntityService defines the interface for the entities extracted by the indexer
+type EntityService interface {
+	// ListEntities retrieves the entities of a collection ordered by the number of emails that mention them
+	ListEntities(ctx context.Context, collection string, filter models.EntityFilter) (*models.EntityResponse, error)
+}
+
+type entityService struct {
+	db *gorm.DB
+}
+
+// NewEntityService creates a new instance of EntityService
+func NewEntityService(db *gorm.DB) EntityService {
+	return &entityService{
+		db: db,
+	}
+}
+
+// ListEntities implements EntityService interface
+// the entities are grouped by name and type, the query filters the names that start with it
+func (s *entityService) ListEntities(ctx context.Context, collection string, filter models.EntityFilter) (*models.EntityResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	if filter.Limit < 1 {
+		filter.Limit = 1
+	}
+
+	cfg := config.GetConfig()
+	tx := s.db.WithContext(ctx).
+		Table(cfg.CollectionTable(collection, cfg.EntitiesTable)).
+		Select("entity, type, COUNT(DISTINCT email_id) AS emails").
+		Group("entity, type")
+
+	if filter.Type != "" {
+		tx = tx.Where("type = ?", filter.Type)
+	}
+
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		tx = tx.Where(`lower(entity) LIKE ? ESCAPE '\'`, strings.ToLower(escapeLike(query))+"%")
+	}
+
+	var total int64
+	err := s.db.WithContext(ctx).Table("(?) AS entities", tx).Count(&total).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot count entities", err)
+	}
+
+	entities := make([]models.Entity, 0)
+	err = tx.Order("emails DESC, entity").
+		Limit(filter.Limit).
+		Offset((filter.Page - 1) * filter.Limit).
+		Scan(&entities).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve entities", err)
+	}
+
+	return &models.EntityResponse{Entities: entities, Total: total}, nil
+}
+
+// escapeLike escapes the wildcards of LIKE with a backslash
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"api/models"
+
+	"gorm.io/gorm"
+)
+
+// setupEntities creates the entities table of the indexer with the entities of the test emails
+func setupEntities(t *testing.T) *gorm.DB {
+	db := setupSQLite(t)
+	statements := []string{
+		`CREATE TABLE "emails_hillary_email_entities" (id INTEGER PRIMARY KEY AUTOINCREMENT, email_id INTEGER NOT NULL, entity TEXT NOT NULL, type TEXT NOT NULL, field TEXT NOT NULL, start_offset INTEGER NOT NULL, end_offset INTEGER NOT NULL)`,
+		`INSERT INTO "emails_hillary_email_entities" (email_id, entity, type, field, start_offset, end_offset) VALUES
+			(1, 'Libya', 'place', 'subject', 0, 5),
+			(1, 'Jake Sullivan', 'person', 'content', 0, 13),
+			(2, 'Libya', 'place', 'content', 14, 19),
+			(2, 'Libya', 'place', 'content', 30, 35),
+			(2, 'Benghazi', 'place', 'subject', 0, 8),
+			(2, 'Cheryl Mills', 'person', 'content', 0, 12)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+func TestListEntities(t *testing.T) {
+	service := NewEntityService(setupEntities(t))
+
+	ttc := []struct {
+		name          string
+		filter        models.EntityFilter
+		expected      []string
+		expectedTotal int64
+	}{
+		{"must order by the number of emails", models.EntityFilter{Limit: 10}, []string{"Libya", "Benghazi", "Cheryl Mills", "Jake Sullivan"}, 4},
+		{"must filter by type", models.EntityFilter{Type: models.EntityTypePerson, Limit: 10}, []string{"Cheryl Mills", "Jake Sullivan"}, 2},
+		{"must filter by the start of the name", models.EntityFilter{Query: "ch", Limit: 10}, []string{"Cheryl Mills"}, 1},
+		{"must escape the wildcards", models.EntityFilter{Query: "%", Limit: 10}, []string{}, 0},
+		{"must paginate", models.EntityFilter{Limit: 2, Page: 2}, []string{"Cheryl Mills", "Jake Sullivan"}, 4},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.ListEntities(context.Background(), "emails_hillary", tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Total != tt.expectedTotal || len(response.Entities) != len(tt.expected) {
+				t.Fatalf("ListEntities returned %+v, expected %v", response, tt.expected)
+			}
+
+			for i, entity := range response.Entities {
+				if entity.Entity != tt.expected[i] {
+					t.Errorf("ListEntities returned %+v, expected %v", response.Entities, tt.expected)
+				}
+			}
+		})
+	}
+
+	response, err := service.ListEntities(context.Background(), "emails_hillary", models.EntityFilter{Query: "libya", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Entities[0].Emails != 2 {
+		t.Errorf("expected the emails that mention Libya to be counted once, got %+v", response.Entities[0])
+	}
+}
+
+func TestSearchEmailsByEntity(t *testing.T) {
+	service := NewSQLiteEmailService(setupEntities(t))
+
+	ttc := []struct {
+		name     string
+		query    models.QuerySearch
+		expected []uint32
+	}{
+		{"must filter by the entity of the query", models.QuerySearch{Query: "entity:libya", Limit: 10, OrderBy: models.OrderByAsc}, []uint32{1, 2}},
+		{"must filter by all the entities", models.QuerySearch{Query: `entity:Libya entity:"Cheryl Mills"`, Limit: 10}, []uint32{2}},
+		{"must filter by the entities of the body", models.QuerySearch{Query: "embassy", Entities: []string{"Jake Sullivan"}, Limit: 10}, []uint32{1}},
+		{"must combine the text search and the filter", models.QuerySearch{Query: "call entity:Jake_Sullivan", Limit: 10}, []uint32{}},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.SearchEmails(context.Background(), "emails_hillary", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Total != int64(len(tt.expected)) || len(response.Emails) != len(tt.expected) {
+				t.Fatalf("SearchEmails returned %+v, expected %v", response.Emails, tt.expected)
+			}
+
+			for i, email := range response.Emails {
+				if email.ID != tt.expected[i] {
+					t.Errorf("SearchEmails returned %+v, expected %v", response.Emails, tt.expected)
+				}
+			}
+		})
+	}
+}
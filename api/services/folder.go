@@ -0,0 +1,503 @@
+package services
+
+import (
+	"api/cache"
+	"api/config"
+	"api/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrFolderNotFound is returned when the folder does not exist in the collection
+var ErrFolderNotFound = errors.New("folder not found")
+
+// ErrFolderExists is returned when there is already a folder with the name
+var ErrFolderExists = errors.New("folder already exists")
+
+// ErrFolderFull is returned when the folder would have more than models.MaxFolderItems emails
+var ErrFolderFull = errors.New("folder full")
+
+// ErrFolderQueryTooLarge is returned when the query of a request matches more than models.MaxFolderItems emails
+var ErrFolderQueryTooLarge = errors.New("query matches too many emails")
+
+// ErrFolderItemNotFound is returned when an email to reorder is not in the folder
+var ErrFolderItemNotFound = errors.New("email not in folder")
+
+// FolderService defines the interface for the folders of emails curated by the users
+type FolderService interface {
+	// CreateFolder creates an empty folder with the name
+	CreateFolder(ctx context.Context, collection string, request models.FolderRequest) (*models.Folder, error)
+	// ListFolders retrieves the folders ordered by name
+	ListFolders(ctx context.Context, collection string, pagination models.Pagination) (*models.FolderResponse, error)
+	// GetFolder retrieves a folder with its number of emails
+	GetFolder(ctx context.Context, collection string, id int64) (*models.Folder, error)
+	// ListFolderMails retrieves the folder with a page of its emails in their order
+	ListFolderMails(ctx context.Context, collection string, id int64, pagination models.Pagination) (*models.FolderMailsResponse, error)
+	// StreamFolderMails calls fn with every email of the folder in their order
+	StreamFolderMails(ctx context.Context, collection string, id int64, fn func(email models.Email) error) error
+	// DeleteFolder removes the folder, the emails are not changed
+	DeleteFolder(ctx context.Context, collection string, id int64) error
+	// AddItems adds the emails of the request at the end of the folder
+	AddItems(ctx context.Context, collection string, id int64, request models.FolderItemsRequest) (*models.Folder, error)
+	// RemoveItems removes the emails of the request from the folder
+	RemoveItems(ctx context.Context, collection string, id int64, request models.FolderItemsRequest) (*models.Folder, error)
+	// ReorderItems moves the emails to the start of the folder in the order of ids
+	ReorderItems(ctx context.Context, collection string, id int64, ids []uint32) (*models.Folder, error)
+}
+
+type folderService struct {
+	db           *gorm.DB
+	emailService EmailService
+}
+
+// NewFolderService creates a new instance of FolderService
+// emailService resolves the emails of the requests with a query
+func NewFolderService(db *gorm.DB, emailService EmailService) FolderService {
+	return &folderService{
+		db:           db,
+		emailService: emailService,
+	}
+}
+
+// CreateFolder implements FolderService interface
+func (s *folderService) CreateFolder(ctx context.Context, collection string, request models.FolderRequest) (*models.Folder, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	table := s.table(collection, config.GetConfig().FoldersTable)
+	var folder *models.Folder
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var total int64
+		if err := tx.Table(table).Where("name = ?", request.Name).Count(&total).Error; err != nil {
+			return models.NewApiError("cannot retrieve folder", err)
+		}
+
+		if total > 0 {
+			return ErrFolderExists
+		}
+
+		now := time.Now().UTC()
+		err := tx.Exec("INSERT INTO "+table+" (name, created_at, updated_at) VALUES (?, ?, ?)", request.Name, now, now).Error
+		if err != nil {
+			return models.NewApiError("cannot create folder", err)
+		}
+
+		var id int64
+		if err := tx.Table(table).Where("name = ?", request.Name).Pluck("id", &id).Error; err != nil {
+			return models.NewApiError("cannot retrieve folder", err)
+		}
+
+		folder, err = s.folder(tx, collection, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// ListFolders implements FolderService interface
+func (s *folderService) ListFolders(ctx context.Context, collection string, pagination models.Pagination) (*models.FolderResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+
+	if pagination.Limit < 1 {
+		pagination.Limit = 1
+	}
+
+	var total int64
+	err := s.db.WithContext(ctx).Table(s.table(collection, config.GetConfig().FoldersTable)).Count(&total).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot count folders", err)
+	}
+
+	folders := make([]models.Folder, 0)
+	err = s.folders(s.db.WithContext(ctx), collection).
+		Order("f.name").
+		Limit(pagination.Limit).
+		Offset((pagination.Page - 1) * pagination.Limit).
+		Scan(&folders).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve folders", err)
+	}
+
+	return &models.FolderResponse{Folders: folders, Total: total}, nil
+}
+
+// GetFolder implements FolderService interface
+func (s *folderService) GetFolder(ctx context.Context, collection string, id int64) (*models.Folder, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return s.folder(s.db.WithContext(ctx), collection, id)
+}
+
+// ListFolderMails implements FolderService interface
+// the emails are marked with their annotations and folders as in the search results
+func (s *folderService) ListFolderMails(ctx context.Context, collection string, id int64, pagination models.Pagination) (*models.FolderMailsResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+
+	if pagination.Limit < 1 {
+		pagination.Limit = 1
+	}
+
+	folder, err := s.folder(s.db.WithContext(ctx), collection, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mails := make([]models.Email, 0)
+	err = s.folderMails(s.db.WithContext(ctx), collection, id).
+		Limit(pagination.Limit).
+		Offset((pagination.Page - 1) * pagination.Limit).
+		Scan(&mails).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve emails", err)
+	}
+
+	if err := markAnnotated(s.db.WithContext(ctx), collection, mails); err != nil {
+		return nil, err
+	}
+
+	if err := markFolders(s.db.WithContext(ctx), collection, mails); err != nil {
+		return nil, err
+	}
+
+	return &models.FolderMailsResponse{Folder: *folder, Mails: mails, Total: folder.Items}, nil
+}
+
+// StreamFolderMails implements FolderService interface
+// the emails are read with a cursor, the folder is not loaded in memory
+func (s *folderService) StreamFolderMails(ctx context.Context, collection string, id int64, fn func(email models.Email) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rows, err := s.folderMails(s.db.WithContext(ctx), collection, id).Rows()
+	if err != nil {
+		return models.NewApiError("cannot retrieve emails", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email models.Email
+		if err := s.db.ScanRows(rows, &email); err != nil {
+			return models.NewApiError("cannot read email", err)
+		}
+
+		if err := fn(email); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.NewApiError("cannot retrieve emails", err)
+	}
+
+	return nil
+}
+
+// DeleteFolder implements FolderService interface
+func (s *folderService) DeleteFolder(ctx context.Context, collection string, id int64) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cfg := config.GetConfig()
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.folder(tx, collection, id); err != nil {
+			return err
+		}
+
+		// the items are deleted first, the foreign keys of SQLite are not always enforced
+		if err := tx.Exec("DELETE FROM "+s.table(collection, cfg.FolderItemsTable)+" WHERE folder_id = ?", id).Error; err != nil {
+			return models.NewApiError("cannot delete folder", err)
+		}
+
+		if err := tx.Exec("DELETE FROM "+s.table(collection, cfg.FoldersTable)+" WHERE id = ?", id).Error; err != nil {
+			return models.NewApiError("cannot delete folder", err)
+		}
+
+		return nil
+	})
+}
+
+// AddItems implements FolderService interface
+// the emails already in the folder keep their position, the new ones are added in the order of the request
+func (s *folderService) AddItems(ctx context.Context, collection string, id int64, request models.FolderItemsRequest) (*models.Folder, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ids, err := s.requestIDs(ctx, collection, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.GetConfig()
+	itemsTable := s.table(collection, cfg.FolderItemsTable)
+	var folder *models.Folder
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := s.folder(tx, collection, id)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			folder = current
+			return nil
+		}
+
+		var emails int64
+		err = tx.Table(s.table(collection, cfg.MailsTable)).Where("id IN ?", ids).Count(&emails).Error
+		if err != nil {
+			return models.NewApiError("cannot retrieve emails", err)
+		}
+
+		if emails != int64(len(ids)) {
+			return ErrEmailNotFound
+		}
+
+		present := make([]uint32, 0)
+		err = tx.Table(itemsTable).Where("folder_id = ? AND email_id IN ?", id, ids).Pluck("email_id", &present).Error
+		if err != nil {
+			return models.NewApiError("cannot retrieve folder items", err)
+		}
+
+		added := excludeIDs(ids, present)
+		if current.Items+int64(len(added)) > models.MaxFolderItems {
+			return ErrFolderFull
+		}
+
+		var position int64
+		err = tx.Table(itemsTable).Where("folder_id = ?", id).Select("COALESCE(MAX(position), 0)").Scan(&position).Error
+		if err != nil {
+			return models.NewApiError("cannot retrieve folder items", err)
+		}
+
+		now := time.Now().UTC()
+		for _, emailID := range added {
+			position++
+			err := tx.Exec("INSERT INTO "+itemsTable+" (folder_id, email_id, position, added_at) VALUES (?, ?, ?, ?)", id, emailID, position, now).Error
+			if err != nil {
+				return models.NewApiError("cannot add emails", err)
+			}
+		}
+
+		folder, err = s.touch(tx, collection, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// RemoveItems implements FolderService interface
+// the emails that are not in the folder are ignored
+func (s *folderService) RemoveItems(ctx context.Context, collection string, id int64, request models.FolderItemsRequest) (*models.Folder, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ids, err := s.requestIDs(ctx, collection, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var folder *models.Folder
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := s.folder(tx, collection, id)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			folder = current
+			return nil
+		}
+
+		err = tx.Exec("DELETE FROM "+s.table(collection, config.GetConfig().FolderItemsTable)+" WHERE folder_id = ? AND email_id IN ?", id, ids).Error
+		if err != nil {
+			return models.NewApiError("cannot remove emails", err)
+		}
+
+		folder, err = s.touch(tx, collection, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// ReorderItems implements FolderService interface
+// the emails get positions before the first one of the folder, the others keep their order after them
+func (s *folderService) ReorderItems(ctx context.Context, collection string, id int64, ids []uint32) (*models.Folder, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ids = excludeIDs(ids, nil)
+	itemsTable := s.table(collection, config.GetConfig().FolderItemsTable)
+	var folder *models.Folder
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := s.folder(tx, collection, id); err != nil {
+			return err
+		}
+
+		var present int64
+		err := tx.Table(itemsTable).Where("folder_id = ? AND email_id IN ?", id, ids).Count(&present).Error
+		if err != nil {
+			return models.NewApiError("cannot retrieve folder items", err)
+		}
+
+		if present != int64(len(ids)) {
+			return ErrFolderItemNotFound
+		}
+
+		var first int64
+		err = tx.Table(itemsTable).Where("folder_id = ?", id).Select("COALESCE(MIN(position), 0)").Scan(&first).Error
+		if err != nil {
+			return models.NewApiError("cannot retrieve folder items", err)
+		}
+
+		position := first - int64(len(ids))
+		for _, emailID := range ids {
+			err := tx.Exec("UPDATE "+itemsTable+" SET position = ? WHERE folder_id = ? AND email_id = ?", position, id, emailID).Error
+			if err != nil {
+				return models.NewApiError("cannot reorder emails", err)
+			}
+			position++
+		}
+
+		folder, err = s.touch(tx, collection, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// requestIDs returns the ids of the request without duplicates, or the ids of the emails that match its query
+func (s *folderService) requestIDs(ctx context.Context, collection string, request models.FolderItemsRequest) ([]uint32, error) {
+	if request.Query == nil {
+		return excludeIDs(request.IDs, nil), nil
+	}
+
+	// the search is paginated with the max limit, the emails keep the order of the results
+	// the cache is bypassed to add the emails of the last run of the indexer
+	ctx = cache.WithBypass(ctx)
+	query := *request.Query
+	query.Page = 1
+	query.Limit = config.GetApiConfig().MaxLimitPagination
+
+	ids := make([]uint32, 0)
+	for {
+		response, err := s.emailService.SearchEmails(ctx, collection, query)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Total > models.MaxFolderItems {
+			return nil, ErrFolderQueryTooLarge
+		}
+
+		for _, email := range response.Emails {
+			ids = append(ids, email.ID)
+		}
+
+		if len(response.Emails) < query.Limit || int64(len(ids)) >= response.Total {
+			break
+		}
+		query.Page++
+	}
+
+	return excludeIDs(ids, nil), nil
+}
+
+// folder retrieves the folder with its number of emails, ErrFolderNotFound if it does not exist
+func (s *folderService) folder(tx *gorm.DB, collection string, id int64) (*models.Folder, error) {
+	folder := models.Folder{}
+	err := s.folders(tx, collection).Where("f.id = ?", id).Take(&folder).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve folder", err)
+	}
+
+	return &folder, nil
+}
+
+// folders returns the query of the folders with their number of emails
+func (s *folderService) folders(tx *gorm.DB, collection string) *gorm.DB {
+	cfg := config.GetConfig()
+	return tx.Table(s.table(collection, cfg.FoldersTable) + " f").
+		Select("f.id, f.name, f.created_at, f.updated_at, (SELECT COUNT(*) FROM " + s.table(collection, cfg.FolderItemsTable) + " i WHERE i.folder_id = f.id) AS items")
+}
+
+// folderMails returns the query of the emails of the folder in their order
+func (s *folderService) folderMails(tx *gorm.DB, collection string, id int64) *gorm.DB {
+	cfg := config.GetConfig()
+	return tx.Table(s.table(collection, cfg.FolderItemsTable)+" i").
+		Joins("JOIN "+s.table(collection, cfg.MailsTable)+" e ON e.id = i.email_id").
+		Select(`e.id, e.subject, e."from", e."to", e.content, e.date`).
+		Where("i.folder_id = ?", id).
+		Order("i.position, e.id")
+}
+
+// touch updates the date of the last change of the folder and returns it
+func (s *folderService) touch(tx *gorm.DB, collection string, id int64) (*models.Folder, error) {
+	err := tx.Exec("UPDATE "+s.table(collection, config.GetConfig().FoldersTable)+" SET updated_at = ? WHERE id = ?", time.Now().UTC(), id).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot update folder", err)
+	}
+
+	return s.folder(tx, collection, id)
+}
+
+// table returns the name of a table of the collection
+func (s *folderService) table(collection, name string) string {
+	return config.GetConfig().CollectionTable(collection, name)
+}
+
+// excludeIDs returns the ids without duplicates and without the excluded ones, in their order
+func excludeIDs(ids, excluded []uint32) []uint32 {
+	seen := make(map[uint32]bool, len(ids)+len(excluded))
+	for _, id := range excluded {
+		seen[id] = true
+	}
+
+	result := make([]uint32, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+
+	return result
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"api/models"
+)
+
+// folderMailIDs returns the ids of the emails of the folder in their order
+func folderMailIDs(t *testing.T, service FolderService, id int64) []uint32 {
+	t.Helper()
+
+	ids := make([]uint32, 0)
+	err := service.StreamFolderMails(context.Background(), "emails_hillary", id, func(email models.Email) error {
+		ids = append(ids, email.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ids
+}
+
+func equalIDs(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestFolderService(t *testing.T) {
+	db := setupSQLite(t)
+	emailService := NewSQLiteEmailService(db)
+	service := NewFolderService(db, emailService)
+	ctx := context.Background()
+
+	folder, err := service.CreateFolder(ctx, "emails_hillary", models.FolderRequest{Name: "Libya 2011 briefing pack"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder.ID == 0 || folder.Items != 0 {
+		t.Errorf("unexpected folder %+v", folder)
+	}
+
+	if _, err := service.CreateFolder(ctx, "emails_hillary", models.FolderRequest{Name: "Libya 2011 briefing pack"}); !errors.Is(err, ErrFolderExists) {
+		t.Errorf("expected ErrFolderExists, got %v", err)
+	}
+
+	folder, err = service.AddItems(ctx, "emails_hillary", folder.ID, models.FolderItemsRequest{IDs: []uint32{3, 1, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder.Items != 2 {
+		t.Errorf("expected 2 items, got %d", folder.Items)
+	}
+
+	if _, err := service.AddItems(ctx, "emails_hillary", folder.ID, models.FolderItemsRequest{IDs: []uint32{2, 99}}); !errors.Is(err, ErrEmailNotFound) {
+		t.Errorf("expected ErrEmailNotFound, got %v", err)
+	}
+
+	// the emails that match the query are added after the others, the ones already in the folder keep their position
+	folder, err = service.AddItems(ctx, "emails_hillary", folder.ID, models.FolderItemsRequest{Query: &models.QuerySearch{Query: "libya"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := folderMailIDs(t, service, folder.ID); !equalIDs(ids, []uint32{3, 1, 2}) {
+		t.Errorf("unexpected order after adding %v", ids)
+	}
+
+	if _, err := service.ReorderItems(ctx, "emails_hillary", folder.ID, []uint32{2, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := folderMailIDs(t, service, folder.ID); !equalIDs(ids, []uint32{2, 1, 3}) {
+		t.Errorf("unexpected order after reordering %v", ids)
+	}
+
+	other, err := service.CreateFolder(ctx, "emails_hillary", models.FolderRequest{Name: "Schedule"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.AddItems(ctx, "emails_hillary", other.ID, models.FolderItemsRequest{IDs: []uint32{3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.ReorderItems(ctx, "emails_hillary", other.ID, []uint32{1}); !errors.Is(err, ErrFolderItemNotFound) {
+		t.Errorf("expected ErrFolderItemNotFound, got %v", err)
+	}
+
+	search, err := emailService.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Limit: 10, OrderBy: models.OrderByAsc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(search.Emails[0].Folders) != 1 || len(search.Emails[2].Folders) != 2 || search.Emails[2].Folders[1] != other.ID {
+		t.Errorf("unexpected folders in the search %+v", search.Emails)
+	}
+
+	mails, err := service.ListFolderMails(ctx, "emails_hillary", folder.ID, models.Pagination{Page: 2, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mails.Total != 3 || len(mails.Mails) != 1 || mails.Mails[0].ID != 3 {
+		t.Errorf("unexpected page of the folder %+v", mails)
+	}
+
+	folder, err = service.RemoveItems(ctx, "emails_hillary", folder.ID, models.FolderItemsRequest{Query: &models.QuerySearch{Query: "benghazi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := folderMailIDs(t, service, folder.ID); !equalIDs(ids, []uint32{1, 3}) {
+		t.Errorf("unexpected emails after removing %v", ids)
+	}
+
+	folders, err := service.ListFolders(ctx, "emails_hillary", models.Pagination{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folders.Total != 2 || folders.Folders[0].Items != 2 || folders.Folders[1].Name != "Schedule" {
+		t.Errorf("unexpected folders %+v", folders)
+	}
+
+	if err := service.DeleteFolder(ctx, "emails_hillary", other.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetFolder(ctx, "emails_hillary", other.ID); !errors.Is(err, ErrFolderNotFound) {
+		t.Errorf("expected ErrFolderNotFound, got %v", err)
+	}
+}
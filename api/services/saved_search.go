@@ -0,0 +1,272 @@
+package services
+
+import (
+	"api/config"
+	"api/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSavedSearchNotFound is returned when the saved search does not exist in the collection
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// ErrSavedSearchExists is returned when the owner already has a saved search with the name
+var ErrSavedSearchExists = errors.New("saved search already exists")
+
+// SavedSearchService defines the interface for the searches saved by the users
+// the matches of the saved searches are recorded by the indexer after every run
+type SavedSearchService interface {
+	// CreateSavedSearch saves the query with the owner and the name
+	CreateSavedSearch(ctx context.Context, collection string, request models.SavedSearchRequest) (*models.SavedSearch, error)
+	// ListSavedSearches retrieves the saved searches ordered by owner and name, of an owner if it is not empty
+	ListSavedSearches(ctx context.Context, collection, owner string, pagination models.Pagination) (*models.SavedSearchResponse, error)
+	// NewMatches retrieves the emails matched since the last check and marks them as seen
+	// the searches of other owners are not found if the owner is not empty
+	NewMatches(ctx context.Context, collection string, id int64, owner string, limit int) (*models.NewMatchesResponse, error)
+}
+
+type savedSearchService struct {
+	db *gorm.DB
+}
+
+// savedSearchRow is a row of the saved searches table, the query is stored as JSON
+type savedSearchRow struct {
+	ID              int64
+	Owner           string
+	Name            string
+	Query           string
+	CreatedAt       time.Time
+	EvaluatedAt     *time.Time
+	LastSeenMatchID int64
+}
+
+// savedMatchRow is a new match with its email
+type savedMatchRow struct {
+	MatchID int64
+	models.Email
+}
+
+// NewSavedSearchService creates a new instance of SavedSearchService
+func NewSavedSearchService(db *gorm.DB) SavedSearchService {
+	return &savedSearchService{
+		db: db,
+	}
+}
+
+// CreateSavedSearch implements SavedSearchService interface
+// the query is stored normalized, with the sanitized words and the entity: filters in entities, as the indexer reads it
+// the indexer must find the emails of SearchEmails with the query, the shared cases of both are in testdata/saved_queries.json
+func (s *savedSearchService) CreateSavedSearch(ctx context.Context, collection string, request models.SavedSearchRequest) (*models.SavedSearch, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	query := request.Query
+	query.Normalize()
+	words, err := sanitizeSearchTerms(&query)
+	if err != nil {
+		return nil, err
+	}
+	query.Query = strings.Join(words, " ")
+
+	if query.DateSearch.Date != nil && !query.DateSearch.Date.Valid {
+		query.DateSearch.Date = nil
+	}
+
+	encoded, err := json.Marshal(query)
+	if err != nil {
+		return nil, models.NewApiError("cannot encode query", err)
+	}
+
+	table := s.table(collection, config.GetConfig().SavedSearchTable)
+	row := savedSearchRow{}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var total int64
+		if err := tx.Table(table).Where("owner = ? AND name = ?", request.Owner, request.Name).Count(&total).Error; err != nil {
+			return models.NewApiError("cannot retrieve saved search", err)
+		}
+
+		if total > 0 {
+			return ErrSavedSearchExists
+		}
+
+		err := tx.Exec("INSERT INTO "+table+" (owner, name, query, created_at) VALUES (?, ?, ?, ?)", request.Owner, request.Name, string(encoded), time.Now().UTC()).Error
+		if err != nil {
+			return models.NewApiError("cannot save search", err)
+		}
+
+		err = tx.Table(table).
+			Select("id, owner, name, query, created_at, evaluated_at, last_seen_match_id").
+			Where("owner = ? AND name = ?", request.Owner, request.Name).
+			Take(&row).Error
+		if err != nil {
+			return models.NewApiError("cannot retrieve saved search", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return row.toSavedSearch()
+}
+
+// ListSavedSearches implements SavedSearchService interface
+func (s *savedSearchService) ListSavedSearches(ctx context.Context, collection, owner string, pagination models.Pagination) (*models.SavedSearchResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+
+	if pagination.Limit < 1 {
+		pagination.Limit = 1
+	}
+
+	query := func() *gorm.DB {
+		tx := s.db.WithContext(ctx).Table(s.table(collection, config.GetConfig().SavedSearchTable))
+		if owner != "" {
+			tx = tx.Where("owner = ?", owner)
+		}
+
+		return tx
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, models.NewApiError("cannot count saved searches", err)
+	}
+
+	rows := make([]savedSearchRow, 0)
+	err := query().Select("id, owner, name, query, created_at, evaluated_at, last_seen_match_id").
+		Order("owner, name").
+		Limit(pagination.Limit).
+		Offset((pagination.Page - 1) * pagination.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve saved searches", err)
+	}
+
+	searches := make([]models.SavedSearch, 0, len(rows))
+	for _, row := range rows {
+		search, err := row.toSavedSearch()
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, *search)
+	}
+
+	return &models.SavedSearchResponse{SavedSearches: searches, Total: total}, nil
+}
+
+// NewMatches implements SavedSearchService interface
+// the matches are returned in the order they were recorded, the next check starts after the last one returned
+func (s *savedSearchService) NewMatches(ctx context.Context, collection string, id int64, owner string, limit int) (*models.NewMatchesResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if limit < 1 {
+		limit = 1
+	}
+
+	cfg := config.GetConfig()
+	searchTable := s.table(collection, cfg.SavedSearchTable)
+	matchesTable := s.table(collection, cfg.SavedMatchesTable)
+
+	var response *models.NewMatchesResponse
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row := savedSearchRow{}
+		searchQuery := tx.Table(searchTable).
+			Select("id, owner, name, query, created_at, evaluated_at, last_seen_match_id").
+			Where("id = ?", id)
+		if owner != "" {
+			searchQuery = searchQuery.Where("owner = ?", owner)
+		}
+
+		err := searchQuery.Take(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSavedSearchNotFound
+		}
+		if err != nil {
+			return models.NewApiError("cannot retrieve saved search", err)
+		}
+
+		search, err := row.toSavedSearch()
+		if err != nil {
+			return err
+		}
+
+		matches := make([]savedMatchRow, 0)
+		err = tx.Table(matchesTable+" m").
+			Joins("JOIN "+s.table(collection, cfg.MailsTable)+" e ON e.id = m.email_id").
+			Select(`m.id AS match_id, e.id, e.subject, e."from", e."to", e.content, e.date`).
+			Where("m.saved_search_id = ? AND m.id > ?", id, row.LastSeenMatchID).
+			Order("m.id").
+			Limit(limit).
+			Scan(&matches).Error
+		if err != nil {
+			return models.NewApiError("cannot retrieve matches", err)
+		}
+
+		mails := make([]models.Email, 0, len(matches))
+		lastSeen := row.LastSeenMatchID
+		for _, match := range matches {
+			mails = append(mails, match.Email)
+			lastSeen = match.MatchID
+		}
+
+		var remaining int64
+		err = tx.Table(matchesTable).
+			Where("saved_search_id = ? AND id > ?", id, lastSeen).
+			Count(&remaining).Error
+		if err != nil {
+			return models.NewApiError("cannot count matches", err)
+		}
+
+		if lastSeen != row.LastSeenMatchID {
+			err = tx.Exec("UPDATE "+searchTable+" SET last_seen_match_id = ? WHERE id = ?", lastSeen, id).Error
+			if err != nil {
+				return models.NewApiError("cannot update saved search", err)
+			}
+		}
+
+		response = &models.NewMatchesResponse{SavedSearch: *search, Mails: mails, Remaining: remaining}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// table returns the name of a table of the collection
+func (s *savedSearchService) table(collection, name string) string {
+	return config.GetConfig().CollectionTable(collection, name)
+}
+
+// toSavedSearch decodes the query of the row
+func (r savedSearchRow) toSavedSearch() (*models.SavedSearch, error) {
+	search := &models.SavedSearch{
+		ID:          r.ID,
+		Owner:       r.Owner,
+		Name:        r.Name,
+		CreatedAt:   r.CreatedAt,
+		EvaluatedAt: r.EvaluatedAt,
+	}
+
+	if err := json.Unmarshal([]byte(r.Query), &search.Query); err != nil {
+		return nil, models.NewApiError("cannot decode saved query", err)
+	}
+
+	return search, nil
+}
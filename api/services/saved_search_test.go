@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"api/models"
+
+	"gorm.io/gorm"
+)
+
+// setupSavedSearches creates the saved searches tables of the indexer
+func setupSavedSearches(t *testing.T) *gorm.DB {
+	db := setupSQLite(t)
+	statements := []string{
+		`CREATE TABLE "emails_hillary_saved_searches" (id INTEGER PRIMARY KEY AUTOINCREMENT, owner TEXT NOT NULL, name TEXT NOT NULL, query TEXT NOT NULL, created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, evaluated_at TIMESTAMP, last_seen_match_id INTEGER NOT NULL DEFAULT 0, UNIQUE (owner, name))`,
+		`CREATE TABLE "emails_hillary_saved_search_matches" (id INTEGER PRIMARY KEY AUTOINCREMENT, saved_search_id INTEGER NOT NULL, email_id INTEGER NOT NULL, run_id INTEGER, matched_at TIMESTAMP NOT NULL, UNIQUE (saved_search_id, email_id))`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+func TestSavedSearchService(t *testing.T) {
+	db := setupSavedSearches(t)
+	service := NewSavedSearchService(db)
+	ctx := context.Background()
+
+	request := models.SavedSearchRequest{Owner: "analyst", Name: "libya", Query: models.QuerySearch{Query: "Libya's embassy entity:Tripoli", Tags: []string{"Follow-Up"}}}
+	search, err := service.CreateSavedSearch(ctx, "emails_hillary", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.ID == 0 || search.EvaluatedAt != nil {
+		t.Errorf("unexpected saved search %+v", search)
+	}
+
+	// the query is stored as the indexer reads it
+	var stored string
+	if err := db.Raw(`SELECT query FROM "emails_hillary_saved_searches" WHERE id = ?`, search.ID).Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	var query map[string]any
+	if err := json.Unmarshal([]byte(stored), &query); err != nil {
+		t.Fatal(err)
+	}
+	if query["query"] != "Libyas embassy" || query["type"] != "AND" {
+		t.Errorf("unexpected stored query %s", stored)
+	}
+	if search.Query.Entities[0] != "Tripoli" || search.Query.Tags[0] != "follow-up" {
+		t.Errorf("unexpected query %+v", search.Query)
+	}
+
+	if _, err := service.CreateSavedSearch(ctx, "emails_hillary", request); !errors.Is(err, ErrSavedSearchExists) {
+		t.Errorf("expected ErrSavedSearchExists, got %v", err)
+	}
+
+	request.Owner = "reviewer"
+	if _, err := service.CreateSavedSearch(ctx, "emails_hillary", request); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := service.ListSavedSearches(ctx, "emails_hillary", "", models.Pagination{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 2 || list.SavedSearches[0].Owner != "analyst" {
+		t.Errorf("unexpected saved searches %+v", list)
+	}
+
+	list, err = service.ListSavedSearches(ctx, "emails_hillary", "reviewer", models.Pagination{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.SavedSearches[0].Owner != "reviewer" {
+		t.Errorf("unexpected saved searches of the owner %+v", list)
+	}
+
+	// matches recorded by the indexer
+	for _, emailID := range []int{2, 3, 1} {
+		if err := db.Exec(`INSERT INTO "emails_hillary_saved_search_matches" (saved_search_id, email_id, run_id, matched_at) VALUES (?, ?, 1, CURRENT_TIMESTAMP)`, search.ID, emailID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matches, err := service.NewMatches(ctx, "emails_hillary", search.ID, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches.Mails) != 2 || matches.Mails[0].ID != 2 || matches.Mails[1].ID != 3 || matches.Mails[0].Subject != "Benghazi" || matches.Remaining != 1 {
+		t.Errorf("unexpected new matches %+v", matches)
+	}
+
+	matches, err = service.NewMatches(ctx, "emails_hillary", search.ID, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches.Mails) != 1 || matches.Mails[0].ID != 1 || matches.Remaining != 0 {
+		t.Errorf("unexpected new matches %+v", matches)
+	}
+
+	matches, err = service.NewMatches(ctx, "emails_hillary", search.ID, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches.Mails) != 0 {
+		t.Errorf("expected no new matches, got %+v", matches)
+	}
+
+	if _, err := service.NewMatches(ctx, "emails_hillary", 99, "", 2); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Errorf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+}
+
+// savedQueryCase is a query of the shared cases of the API and the indexer
+// Stored is the query saved for the indexer, IDs are the emails found by both
+type savedQueryCase struct {
+	Name   string             `json:"name"`
+	Query  models.QuerySearch `json:"query"`
+	Stored json.RawMessage    `json:"stored"`
+	IDs    []uint32           `json:"ids"`
+}
+
+// TestSavedQueriesContract runs the shared cases of testdata/saved_queries.json, the indexer matches them with the stored queries
+func TestSavedQueriesContract(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "saved_queries.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var contract struct {
+		Emails []models.Email   `json:"emails"`
+		Cases  []savedQueryCase `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &contract); err != nil {
+		t.Fatal(err)
+	}
+
+	db := setupSavedSearches(t)
+	if err := db.Exec(`DELETE FROM "emails_hillary_emails"`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`DELETE FROM "emails_hillary_emails_search"`).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range contract.Emails {
+		if err := db.Exec(`INSERT INTO "emails_hillary_emails" VALUES (?, ?, ?, ?, ?, ?)`, e.ID, e.Date, e.Subject, e.From, e.To, e.Content).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec(`INSERT INTO "emails_hillary_emails_search" (rowid, subject, "from", "to", content) VALUES (?, ?, ?, ?, ?)`, e.ID, e.Subject, e.From, e.To, e.Content).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedSearches := NewSavedSearchService(db)
+	emails := NewSQLiteEmailService(db)
+	for _, tt := range contract.Cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			search, err := savedSearches.CreateSavedSearch(context.Background(), "emails_hillary", models.SavedSearchRequest{Owner: "contract", Name: tt.Name, Query: tt.Query})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var stored string
+			if err := db.Raw(`SELECT query FROM "emails_hillary_saved_searches" WHERE id = ?`, search.ID).Scan(&stored).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !equalJSON(t, []byte(stored), tt.Stored) {
+				t.Errorf("unexpected stored query %s", stored)
+			}
+
+			query := tt.Query
+			query.Limit = 100
+			query.OrderBy = models.OrderByAsc
+			result, err := emails.SearchEmails(context.Background(), "emails_hillary", query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := make([]uint32, 0, len(result.Emails))
+			for _, email := range result.Emails {
+				ids = append(ids, email.ID)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			if !reflect.DeepEqual(ids, tt.IDs) {
+				t.Errorf("expected %v, got %v", tt.IDs, ids)
+			}
+		})
+	}
+}
+
+// equalJSON compares two JSON documents by value
+func equalJSON(t *testing.T, a, b []byte) bool {
+	var decodedA, decodedB any
+	if err := json.Unmarshal(a, &decodedA); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &decodedB); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(decodedA, decodedB)
+}
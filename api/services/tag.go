@@ -0,0 +1,157 @@
+package services
+
+import (
+	"api/config"
+	"api/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrEmailNotFound is returned when the email does not exist in the collection
+var ErrEmailNotFound = errors.New("email not found")
+
+// TagService defines the interface for the tags added to the emails
+type TagService interface {
+	// AddTags adds the tags to the email and returns all the tags of the email
+	AddTags(ctx context.Context, collection string, emailID uint32, tags []string) ([]string, error)
+	// RemoveTags removes the tags of the email and returns the tags left
+	RemoveTags(ctx context.Context, collection string, emailID uint32, tags []string) ([]string, error)
+	// ListTags retrieves the tags of a collection ordered by the number of emails
+	ListTags(ctx context.Context, collection string, pagination models.Pagination) (*models.TagResponse, error)
+}
+
+type tagService struct {
+	db *gorm.DB
+}
+
+// NewTagService creates a new instance of TagService
+func NewTagService(db *gorm.DB) TagService {
+	return &tagService{
+		db: db,
+	}
+}
+
+// AddTags implements TagService interface
+// the tags already added are kept with their date
+func (s *tagService) AddTags(ctx context.Context, collection string, emailID uint32, tags []string) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cfg := config.GetConfig()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkEmail(tx, collection, emailID); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, tag := range tags {
+			err := tx.Exec("INSERT INTO "+cfg.CollectionTable(collection, cfg.TagsTable)+" (email_id, tag, created_at) VALUES (?, ?, ?) ON CONFLICT (email_id, tag) DO NOTHING", emailID, tag, now).Error
+			if err != nil {
+				return models.NewApiError("cannot add tags", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.emailTags(ctx, collection, emailID)
+}
+
+// RemoveTags implements TagService interface
+// the tags that the email doesn't have are ignored
+func (s *tagService) RemoveTags(ctx context.Context, collection string, emailID uint32, tags []string) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := s.checkEmail(s.db.WithContext(ctx), collection, emailID); err != nil {
+		return nil, err
+	}
+
+	cfg := config.GetConfig()
+	err := s.db.WithContext(ctx).
+		Exec("DELETE FROM "+cfg.CollectionTable(collection, cfg.TagsTable)+" WHERE email_id = ? AND tag IN ?", emailID, tags).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot remove tags", err)
+	}
+
+	return s.emailTags(ctx, collection, emailID)
+}
+
+// ListTags implements TagService interface
+func (s *tagService) ListTags(ctx context.Context, collection string, pagination models.Pagination) (*models.TagResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+
+	if pagination.Limit < 1 {
+		pagination.Limit = 1
+	}
+
+	cfg := config.GetConfig()
+	table := cfg.CollectionTable(collection, cfg.TagsTable)
+
+	var total int64
+	err := s.db.WithContext(ctx).Table(table).Distinct("tag").Count(&total).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot count tags", err)
+	}
+
+	tags := make([]models.Tag, 0)
+	err = s.db.WithContext(ctx).
+		Table(table).
+		Select("tag AS name, COUNT(*) AS emails").
+		Group("tag").
+		Order("emails DESC, tag").
+		Limit(pagination.Limit).
+		Offset((pagination.Page - 1) * pagination.Limit).
+		Scan(&tags).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve tags", err)
+	}
+
+	return &models.TagResponse{Tags: tags, Total: total}, nil
+}
+
+// checkEmail returns ErrEmailNotFound if the email is not in the collection
+func (s *tagService) checkEmail(tx *gorm.DB, collection string, emailID uint32) error {
+	var total int64
+	err := tx.Table(config.GetConfig().CollectionTable(collection, config.GetConfig().MailsTable)).
+		Where("id = ?", emailID).
+		Count(&total).Error
+	if err != nil {
+		return models.NewApiError("cannot retrieve email", err)
+	}
+
+	if total == 0 {
+		return ErrEmailNotFound
+	}
+
+	return nil
+}
+
+// emailTags returns the tags of the email ordered by name
+func (s *tagService) emailTags(ctx context.Context, collection string, emailID uint32) ([]string, error) {
+	tags := make([]string, 0)
+	err := s.db.WithContext(ctx).
+		Table(config.GetConfig().CollectionTable(collection, config.GetConfig().TagsTable)).
+		Where("email_id = ?", emailID).
+		Order("tag").
+		Pluck("tag", &tags).Error
+	if err != nil {
+		return nil, models.NewApiError("cannot retrieve tags", err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"api/models"
+
+	"gorm.io/gorm"
+)
+
+// setupTags creates the tags table of the indexer
+func setupTags(t *testing.T) *gorm.DB {
+	db := setupSQLite(t)
+	err := db.Exec(`CREATE TABLE "emails_hillary_email_tags" (email_id INTEGER NOT NULL, tag TEXT NOT NULL, created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (email_id, tag))`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestTagService(t *testing.T) {
+	db := setupTags(t)
+	service := NewTagService(db)
+	ctx := context.Background()
+
+	tags, err := service.AddTags(ctx, "emails_hillary", 2, []string{"follow-up", "benghazi-timeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"benghazi-timeline", "follow-up"}) {
+		t.Errorf("AddTags returned %v", tags)
+	}
+
+	// adding a tag again keeps it once
+	if _, err := service.AddTags(ctx, "emails_hillary", 2, []string{"follow-up"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.AddTags(ctx, "emails_hillary", 1, []string{"benghazi-timeline"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.AddTags(ctx, "emails_hillary", 99, []string{"follow-up"}); !errors.Is(err, ErrEmailNotFound) {
+		t.Errorf("expected ErrEmailNotFound, got %v", err)
+	}
+
+	response, err := service.ListTags(ctx, "emails_hillary", models.Pagination{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.Tag{{Name: "benghazi-timeline", Emails: 2}, {Name: "follow-up", Emails: 1}}
+	if response.Total != 2 || !reflect.DeepEqual(response.Tags, expected) {
+		t.Errorf("ListTags returned %+v, expected %+v", response, expected)
+	}
+
+	search := NewSQLiteEmailService(db)
+	emails, err := search.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Tags: []string{"Benghazi-Timeline"}, Limit: 10, OrderBy: models.OrderByAsc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if emails.Total != 2 || emails.Emails[0].ID != 1 || emails.Emails[1].ID != 2 {
+		t.Errorf("SearchEmails returned %+v", emails)
+	}
+
+	emails, err = search.SearchEmails(ctx, "emails_hillary", models.QuerySearch{Query: "libya", Tags: []string{"benghazi-timeline", "follow-up"}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if emails.Total != 1 || emails.Emails[0].ID != 2 {
+		t.Errorf("SearchEmails returned %+v", emails)
+	}
+
+	tags, err = service.RemoveTags(ctx, "emails_hillary", 2, []string{"follow-up", "not-added"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"benghazi-timeline"}) {
+		t.Errorf("RemoveTags returned %v", tags)
+	}
+
+	response, err = service.ListTags(ctx, "emails_hillary", models.Pagination{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 1 {
+		t.Errorf("ListTags returned %+v after removing follow-up", response)
+	}
+}
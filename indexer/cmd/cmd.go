@@ -2,49 +2,59 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"indexer/config"
 	"indexer/database"
 	"indexer/models"
+	"indexer/notifier"
 	"indexer/scraper"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // Cmd represents the command line interface for the indexer
 type Cmd struct {
 	lastPage       int                         // Last max page available to index
 	scrapper       *scraper.Scrapper           // Scraper instance
-	isScraping     bool                        // Whether the scraper is running
-	status         *models.SafeMap             // Safe map to store the status
+	db             database.IDatabase          // Database to read and write the emails
+	isIndexing     *atomic.Bool                // Whether an index run is in progress, from its setup to the evaluation of the saved searches
 	indexer        *database.Indexer           // Indexer instance
-	mu             *sync.Mutex                 // Mutex to synchronize access to the status
+	mu             *sync.Mutex                 // Mutex to synchronize access to the status file
 	intervalUpdate int                         // Interval in rows to update the status
 	paginationSize scraper.PaginationWikileaks // Pagination size for the scraper
 	batchSize      int                         // Batch size for the indexer
+	ctx            context.Context             // Context of the cli, it is cancelled on shutdown
+	runs           *sync.WaitGroup             // Index runs in progress
+	webhook        *notifier.Webhook           // Webhook of the new matches of the saved searches, nil to disable it
 }
 
-const statusDirectory = "data"           // Directory to store the status
-const statusFilename = "data200pag.json" // File to store the status
+const statusDirectory = "data"           // Directory of the status export
+const statusFilename = "data200pag.json" // File of the status export
 
 // NewCmd creates a new Cmd instance
-func NewCmd(db *database.Connection, parallelism, delayRequest int) *Cmd {
-	status := models.NewSafeMap()
+func NewCmd(db database.IDatabase, parallelism, delayRequest int) *Cmd {
 	var mu sync.Mutex
 
 	return &Cmd{
 		scrapper:       scraper.NewScrapper(parallelism, delayRequest),
-		isScraping:     false,
-		status:         status,
+		db:             db,
+		isIndexing:     &atomic.Bool{},
 		indexer:        database.NewIndexer(db),
 		mu:             &mu,
 		intervalUpdate: 50,
 		paginationSize: scraper.PaginationWikileaks200,
 		batchSize:      100,
+		ctx:            context.Background(),
+		runs:           &sync.WaitGroup{},
 	}
 }
 
@@ -67,6 +77,16 @@ func (c *Cmd) SetPaginationSize(size scraper.PaginationWikileaks) {
 	c.paginationSize = size
 }
 
+// SetWebhook sets the webhook that receives the new matches of the saved searches
+func (c *Cmd) SetWebhook(webhook *notifier.Webhook) {
+	c.webhook = webhook
+}
+
+// SetPipelineConfig sets the workers and the queues of the stages of the scrape pipeline
+func (c *Cmd) SetPipelineConfig(pipeline scraper.PipelineConfig) {
+	c.scrapper.SetPipelineConfig(pipeline)
+}
+
 // SetIntervalUpdate sets the interval in rows to update the status
 // The minimum value is 50
 func (c *Cmd) SetIntervalUpdate(interval int) {
@@ -78,16 +98,17 @@ func (c *Cmd) SetIntervalUpdate(interval int) {
 }
 
 // Execute starts the command line interface
-func (c *Cmd) Execute() {
-
-	statusLoaded, err := c.LoadPageResults(statusDirectory, statusFilename)
-	if err == nil {
-		c.status.SetMap(statusLoaded)
-	}
+// It returns on the exit command, at the end of the input or when ctx is cancelled
+// The running index is cancelled when it returns, call Shutdown to wait for it
+func (c *Cmd) Execute(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c.ctx = ctx
 
 	c.lastPage = -1
 	scanner := bufio.NewScanner(os.Stdin)
 
+	var err error
 	c.lastPage, err = c.scrapper.GetLastPage(c.paginationSize)
 	if err != nil {
 		c.lastPage = -1
@@ -96,13 +117,31 @@ func (c *Cmd) Execute() {
 	fmt.Println("initializing in log Level [", config.GetConfig().LogLevel, "]")
 	c.printHelp()
 
+	// the input is read in another goroutine to stop waiting for a command on shutdown
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
 	for {
 		fmt.Print("> write a command: ")
-		if !scanner.Scan() {
+		var line string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return
+		case line, ok = <-lines:
+		}
+
+		if !ok {
 			break
 		}
 
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(line)
 		if input == "" {
 			continue
 		}
@@ -112,20 +151,63 @@ func (c *Cmd) Execute() {
 		// try to execute a command
 		switch args[0] {
 		case "index":
-			if c.isScraping {
+			// the flag is set before the run starts and cleared when it is completely finished
+			if !c.isIndexing.CompareAndSwap(false, true) {
 				fmt.Println("Already indexing")
 				continue
 			}
 
-			// create map to save the status
+			// the run is tracked to wait for it on shutdown
+			c.runs.Add(1)
 			go func() {
+				defer c.runs.Done()
+				defer c.isIndexing.Store(false)
 				c.Indexer(args)
 			}()
 		case "status":
-			// load the status
-			// update the status every time we receive a page result
+			c.Status(args)
+		case "verify":
+			c.Verify(args)
+		case "history":
+			c.History(args)
+		case "report":
+			c.Report(args)
+		case "migrate":
+			if c.isIndexing.Load() {
+				fmt.Println("Already indexing")
+				continue
+			}
 
-			c.Status()
+			c.Migrate(args)
+		case "reindex-search":
+			if c.isIndexing.Load() {
+				fmt.Println("Already indexing")
+				continue
+			}
+
+			c.ReindexSearch(args)
+		case "extract-entities":
+			c.ExtractEntities(args)
+		case "saved-searches":
+			c.SavedSearches(args)
+		case "collections":
+			c.Collections(args)
+		case "deadletters":
+			if c.isIndexing.Load() && len(args) > 1 && args[1] == "replay" {
+				fmt.Println("Already indexing")
+				continue
+			}
+
+			c.DeadLetters(args)
+		case "export":
+			c.Export(args)
+		case "import":
+			if c.isIndexing.Load() {
+				fmt.Println("Already indexing")
+				continue
+			}
+
+			c.Import(args)
 		case "help":
 			c.printHelp()
 
@@ -140,14 +222,42 @@ func (c *Cmd) Execute() {
 	}
 }
 
+// Shutdown waits for the running index to drain its queues and save its status
+// returns an error if it does not finish before the timeout
+func (c *Cmd) Shutdown(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		c.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("the index did not stop in %s", timeout)
+	}
+}
+
 func (c *Cmd) printHelp() {
-	indexMessage := "index --from=N --to=M   Start indexing from page N to M (default 1)"
+	indexMessage := "index --from=N --to=M   Start indexing from page N to M (default 1) in --collection, --resume for the unfinished pages"
 	if c.lastPage > 0 {
 		indexMessage += " (last page: " + strconv.Itoa(c.lastPage) + ")"
 	}
 	fmt.Println("Available commands:")
 	fmt.Println(indexMessage)
-	fmt.Println("  status                  Show current status")
+	fmt.Println("  status                  Show current status of the pages (--collection, --export to write the JSON file)")
+	fmt.Println("  verify                  Find the email ids missing in the collection (--from-id, --to-id, --backfill)")
+	fmt.Println("  history                 Show the last runs of the indexer (--collection, --limit)")
+	fmt.Println("  report <run-id>         Show the details of a run")
+	fmt.Println("  collections             List the collections, create one with: collections create --name=N")
+	fmt.Println("  reindex-search          Rebuild the search vectors of --collection with --config, resumable (--batch)")
+	fmt.Println("  extract-entities        Find the people, organizations and places of the new emails (--collection, --rebuild, --batch)")
+	fmt.Println("  saved-searches          List the saved searches of the API, --evaluate records their new matches (--collection)")
+	fmt.Println("  migrate up|down|status  Apply, revert or show the schema migrations (--steps=N)")
+	fmt.Println("  import --in=PATH        Import emails from a jsonl dump, an mbox file or a directory of .eml files")
+	fmt.Println("  deadletters list|replay Show or replay the rejected emails (--stage, --ids, --all, --limit)")
+	fmt.Println("  export --format=F       Export emails to jsonl, csv, eml or mbox (--out, --ids, --from-id, --to-id, --date-from, --date-to, --annotations)")
 	fmt.Println("  exit                    Exit the CLI")
 	fmt.Println("  help                    Show this help message")
 }
@@ -170,6 +280,20 @@ func (c *Cmd) SavePageResults(directory, filename string, data map[string]models
 	return err
 }
 
+// loadStatus reads the status of the pages of the collection from the database and returns it
+// the status is replaced on every run, the pages of a collection can be indexed by another run or another process
+func (c *Cmd) loadStatus(collection string) *models.SafeMap {
+	status := models.NewSafeMap()
+	results, err := c.db.LoadPageResults(collection)
+	if err != nil {
+		log.Error("Error loading status of collection ", collection, ": ", err)
+	} else {
+		status.SetMap(results)
+	}
+
+	return status
+}
+
 // LoadPageResults loads the page results from a file
 func (c *Cmd) LoadPageResults(directory, filename string) (map[string]models.PageResult, error) {
 
@@ -1,10 +1,20 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"indexer/database"
 	"indexer/models"
+	"indexer/notifier"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -21,9 +31,10 @@ func Test_SaveAndLoadStatus(t *testing.T) {
 
 	newCmd := NewCmd(nil, 10, 2)
 
-	newCmd.status.Set("1", testCases[0].pageResult)
-	newCmd.status.Set("2", testCases[1].pageResult)
-	newCmd.SavePageResults("../data", "test.json", newCmd.status.GetCopy())
+	status := models.NewSafeMap()
+	status.Set("1", testCases[0].pageResult)
+	status.Set("2", testCases[1].pageResult)
+	newCmd.SavePageResults("../data", "test.json", status.GetCopy())
 
 	loadedStatus, err := newCmd.LoadPageResults("../data", "test.json")
 	if err != nil {
@@ -37,3 +48,151 @@ func Test_SaveAndLoadStatus(t *testing.T) {
 		})
 	}
 }
+
+func Test_LoadStatusByCollection(t *testing.T) {
+	db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	assert.NoError(t, db.CreateSchemaIfNotExist("emails_default"))
+	assert.NoError(t, db.CreateSchemaIfNotExist("emails_other"))
+
+	run := models.IndexRun{FromPage: 1, ToPage: 2, State: models.IndexRunStateRunning, StartedAt: time.Now().UTC()}
+	assert.NoError(t, db.CreateIndexRun("emails_other", &run))
+	assert.NoError(t, db.SavePageResults("emails_other", run.ID, []models.PageResult{testCases[0].pageResult, testCases[1].pageResult}))
+
+	newCmd := NewCmd(db, 10, 2)
+	other := newCmd.loadStatus("emails_other")
+	defaultStatus := newCmd.loadStatus("emails_default")
+
+	assert.Equal(t, 2, other.Len())
+	assert.Equal(t, 0, defaultStatus.Len())
+
+	// a run of the default collection doesn't change the status of the other collection
+	defaultStatus.Set("1", models.PageResult{Page: 1, State: models.PageResultStatePending})
+	result, ok := newCmd.loadStatus("emails_other").Get("1")
+	assert.True(t, ok)
+	assert.Equal(t, models.PageResultStateFinished, result.State)
+}
+
+func Test_DeadLettersDoesNotCreateCollection(t *testing.T) {
+	db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	newCmd := NewCmd(db, 10, 2)
+	newCmd.DeadLetters([]string{"deadletters", "list", "--collection=emails_mistyped"})
+
+	exists, err := db.IsSchemaCreated("emails_mistyped")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// unreachableConnection is a SQLite connection that fails to send the emails as if the database was not reachable
+type unreachableConnection struct {
+	*database.SQLiteConnection
+}
+
+func (c *unreachableConnection) SendMails(schemaName string, emails []models.Email) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (c *unreachableConnection) Ping() error {
+	return errors.New("connection refused")
+}
+
+func Test_ReplayKeepsDeadLettersPendingIfBatchFails(t *testing.T) {
+	db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	assert.NoError(t, db.CreateSchemaIfNotExist("emails_default"))
+
+	// a full batch is sent before the end of the replay
+	letters := make([]models.DeadLetter, 0, 100)
+	for id := uint32(1); id <= 100; id++ {
+		email := models.Email{ID: id, Date: time.Now().UTC(), Subject: "subject"}
+		letters = append(letters, models.NewEmailDeadLetter(models.StageInsert, email, errors.New("value too long")))
+	}
+	assert.NoError(t, db.SendDeadLetters("emails_default", letters))
+
+	newCmd := NewCmd(&unreachableConnection{SQLiteConnection: db}, 10, 2)
+	newCmd.DeadLetters([]string{"deadletters", "replay", "--collection=emails_default"})
+
+	pending, err := db.ListDeadLetters("emails_default", models.DeadLetterFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, pending, 100)
+}
+
+func Test_DeadLetterErrorIsCutAtCharacter(t *testing.T) {
+	// the width falls in the middle of the second byte of an é
+	message := strings.Repeat("a", deadLetterErrorWidth-1) + "é\nrest"
+	cut := deadLetterError(message)
+
+	assert.True(t, utf8.ValidString(cut))
+	assert.Equal(t, strings.Repeat("a", deadLetterErrorWidth-1)+"...", cut)
+	assert.Equal(t, "invalid id 12a", deadLetterError("invalid id\n12a"))
+}
+
+func Test_SavedSearchAlertIsSentAgainAfterFailure(t *testing.T) {
+	db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	assert.NoError(t, db.CreateSchemaIfNotExist("emails_default"))
+
+	_, err = db.DB.Exec(`INSERT INTO emails_default_saved_searches (owner, name, query) VALUES ($1, $2, $3)`, "analyst", "libya", `{"query":"libya","type":"AND"}`)
+	assert.NoError(t, err)
+
+	// the webhook fails the first alert and accepts the next one
+	statuses := []int{http.StatusInternalServerError, http.StatusNoContent}
+	alerts := make([]models.SavedSearchAlert, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert models.SavedSearchAlert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		alerts = append(alerts, alert)
+		w.WriteHeader(statuses[len(alerts)-1])
+	}))
+	defer server.Close()
+
+	webhook, err := notifier.NewWebhook(server.URL, time.Second)
+	assert.NoError(t, err)
+	newCmd := NewCmd(db, 10, 2)
+	newCmd.SetWebhook(webhook)
+
+	// the first evaluation only records the current matches
+	_, err = db.SendMails("emails_default", []models.Email{{ID: 1, Date: time.Now().UTC(), Subject: "Libya update"}})
+	assert.NoError(t, err)
+	newCmd.evaluateSavedSearches("emails_default", 0)
+	assert.Empty(t, alerts)
+
+	_, err = db.SendMails("emails_default", []models.Email{{ID: 2, Date: time.Now().UTC(), Subject: "Libya again"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, newCmd.evaluateSavedSearches("emails_default", 0))
+	assert.Len(t, alerts, 1)
+
+	// the failed alert is sent again without new matches
+	assert.Equal(t, 0, newCmd.evaluateSavedSearches("emails_default", 0))
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, []uint32{2}, alerts[1].EmailIDs)
+
+	newCmd.evaluateSavedSearches("emails_default", 0)
+	assert.Len(t, alerts, 2)
+}
+
+func Test_ReplayBumpsDataVersion(t *testing.T) {
+	db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	assert.NoError(t, db.CreateSchemaIfNotExist("emails_default"))
+
+	email := models.Email{ID: 7, Date: time.Now().UTC(), Subject: "subject"}
+	assert.NoError(t, db.SendDeadLetters("emails_default", []models.DeadLetter{
+		models.NewEmailDeadLetter(models.StageInsert, email, errors.New("connection reset")),
+	}))
+
+	// the API invalidates its cache when the version changes
+	newCmd := NewCmd(db, 10, 2)
+	newCmd.DeadLetters([]string{"deadletters", "replay", "--collection=emails_default"})
+
+	var version int64
+	assert.NoError(t, db.DB.QueryRow(`SELECT version FROM emails_default_data_version WHERE id = 1`).Scan(&version))
+	assert.Equal(t, int64(1), version)
+}
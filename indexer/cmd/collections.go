@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"indexer/database"
+)
+
+// Collections lists the collections of the registry or creates a new one
+// collections: lists the collections
+// collections create --name=N --description=D: creates the schema of the collection and registers it
+func (c *Cmd) Collections(args []string) {
+	if len(args) < 2 || args[1] == "list" {
+		collections, err := c.db.ListCollections()
+		if err != nil {
+			fmt.Println("Error reading collections:", err)
+			return
+		}
+
+		if len(collections) == 0 {
+			fmt.Println("No collections registered")
+			return
+		}
+
+		for _, collection := range collections {
+			fmt.Printf("%s\tcreated at %s\t%s\n", collection.Name, collection.CreatedAt.Format("2006-01-02 15:04:05"), collection.Description)
+		}
+		return
+	}
+
+	if args[1] != "create" {
+		fmt.Println("Usage: collections [list] | collections create --name=N [--description=D]")
+		return
+	}
+
+	var name, description string
+	fs := flag.NewFlagSet("collections", flag.ContinueOnError)
+	fs.StringVar(&name, "name", "", "name of the collection, only letters, numbers and _")
+	fs.StringVar(&description, "description", "", "description of the collection")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[2:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	if err := c.ensureCollection(name); err != nil {
+		fmt.Println("Error creating collection:", err)
+		return
+	}
+
+	if err := c.db.RegisterCollection(name, description); err != nil {
+		fmt.Println("Error registering collection:", err)
+		return
+	}
+
+	fmt.Println("Collection created:", name)
+}
+
+// ensureCollection validates the name and creates the schema of the collection if it doesn't exist
+func (c *Cmd) ensureCollection(name string) error {
+	if err := database.ValidateIsSafeString(name); err != nil {
+		return fmt.Errorf("invalid collection name: %s", name)
+	}
+
+	return c.db.CreateSchemaIfNotExist(name)
+}
+
+// requireCollection validates the name and returns an error if the schema of the collection doesn't exist
+func (c *Cmd) requireCollection(name string) error {
+	if err := database.ValidateIsSafeString(name); err != nil {
+		return fmt.Errorf("invalid collection name: %s", name)
+	}
+
+	exists, err := c.db.IsSchemaCreated(name)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("collection %s does not exist", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"indexer/database"
+	"indexer/importer"
+	"indexer/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const deadLetterErrorWidth = 80 // Maximum length of the error printed by deadletters list
+
+// DeadLetters lists or replays the emails rejected by the pipeline
+// deadletters list: shows the pending dead letters, --all includes the replayed
+// deadletters replay: sends the pending dead letters to the pipeline again, the emails rejected again are new dead letters
+// Both can be filtered by --stage and --ids (ids of the emails) of the collection specified with --collection
+func (c *Cmd) DeadLetters(args []string) {
+	if len(args) < 2 || (args[1] != "list" && args[1] != "replay") {
+		fmt.Println("Usage: deadletters list|replay [--collection=C] [--stage=S] [--ids=1,2] [--all] [--limit=N]")
+		return
+	}
+
+	var collection, stage, ids string
+	var all bool
+	var limit int
+	fs := flag.NewFlagSet("deadletters", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection of the dead letters")
+	fs.StringVar(&stage, "stage", "", "stage of the dead letters: scrape, import, insert or missing")
+	fs.StringVar(&ids, "ids", "", "ids of the emails separated by commas")
+	fs.BoolVar(&all, "all", false, "list the dead letters already replayed")
+	fs.IntVar(&limit, "limit", 0, "maximum number of dead letters")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[2:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	filter := models.DeadLetterFilter{Stage: stage, IncludeReplayed: all && args[1] == "list", Limit: limit}
+	var err error
+	if filter.EmailIDs, err = parseIDs(ids); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// the dead letters are only in the collections already created, a collection is never created to read them
+	if err := c.requireCollection(collection); err != nil {
+		fmt.Println("Error reading collection:", err)
+		return
+	}
+
+	letters, err := c.db.ListDeadLetters(collection, filter)
+	if err != nil {
+		fmt.Println("Error reading dead letters:", err)
+		return
+	}
+
+	if len(letters) == 0 {
+		fmt.Println("No dead letters")
+		return
+	}
+
+	if args[1] == "list" {
+		printDeadLetters(letters)
+		return
+	}
+
+	c.replayDeadLetters(collection, letters)
+}
+
+// replayDeadLetters sends the dead letters to the indexer and marks them as replayed
+// the dead letters stay pending if a batch is not sent, the emails of the batch are neither inserted nor stored again
+func (c *Cmd) replayDeadLetters(collection string, letters []models.DeadLetter) {
+	emailsCh := make(chan models.EmailResult)
+	go func() {
+		defer close(emailsCh)
+		for _, letter := range letters {
+			emailsCh <- c.replayDeadLetter(letter)
+		}
+	}()
+
+	fmt.Printf("Replaying %d dead letters in collection %s\n", len(letters), collection)
+	stats, err := c.indexer.WithCollection(collection).IndexEmail(emailsCh, c.batchSize)
+	if err != nil {
+		fmt.Println("Error replaying dead letters, they are still pending:", err)
+		log.Error("Error replaying dead letters:", err)
+		return
+	}
+
+	ids := make([]int64, 0, len(letters))
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+	}
+
+	if err := c.db.MarkDeadLettersReplayed(collection, ids); err != nil {
+		fmt.Println("Error marking dead letters as replayed:", err)
+		return
+	}
+
+	log.WithFields(log.Fields{"collection": collection, "replayed": len(letters), "inserted": stats.Inserted, "duplicated": stats.Duplicated, "errors": stats.Errors}).Info("Replay finished")
+	fmt.Printf("Replay finished, inserted: %d, duplicated: %d, errors: %d\n", stats.Inserted, stats.Duplicated, stats.Errors)
+
+	if stats.Inserted > 0 {
+		c.bumpDataVersion(collection)
+		c.evaluateSavedSearches(collection, 0)
+	}
+}
+
+// replayDeadLetter builds the email of the dead letter again from the input of its stage
+// the rows of the scraper are parsed and the content is fetched, the messages of the dumps are parsed,
+// the missing ids are fetched from their page and the emails rejected by the database are read from the payload
+func (c *Cmd) replayDeadLetter(letter models.DeadLetter) models.EmailResult {
+	var email *models.Email
+	var err error
+
+	switch {
+	case letter.Stage == models.StageScrape && letter.Raw != "":
+		email, err = c.scrapper.ScrapeRow(letter.Raw)
+	case letter.Stage == models.StageImport && letter.Raw != "":
+		email, err = importer.ParseRaw(letter.Raw)
+	case letter.Stage == models.StageMissing:
+		email, err = c.scrapper.ScrapeEmail(letter.EmailID)
+	default:
+		email, err = letter.Email()
+	}
+
+	if err == nil {
+		err = email.Validate()
+	}
+
+	if err != nil {
+		return models.EmailResult{Email: email, Error: fmt.Errorf("replay of dead letter %d: %w", letter.ID, err), Stage: letter.Stage, Raw: letter.Raw}
+	}
+
+	return models.EmailResult{Email: email}
+}
+
+// printDeadLetters prints a line per dead letter
+func printDeadLetters(letters []models.DeadLetter) {
+	for _, letter := range letters {
+		replayed := "pending"
+		if letter.ReplayedAt != nil {
+			replayed = "replayed at " + letter.ReplayedAt.Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Printf("%d\temail %d\t%s\t%s\t%s\t%s\n", letter.ID, letter.EmailID, letter.Stage, letter.CreatedAt.Format("2006-01-02 15:04:05"), replayed, deadLetterError(letter.Error))
+	}
+
+	fmt.Printf("Total: %d\n", len(letters))
+}
+
+// deadLetterError returns the error in a line cut to deadLetterErrorWidth bytes
+// the cut is done at the start of a character to print valid UTF-8
+func deadLetterError(message string) string {
+	message = strings.ReplaceAll(message, "\n", " ")
+	if len(message) > deadLetterErrorWidth {
+		cut := deadLetterErrorWidth
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut] + "..."
+	}
+
+	return message
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"indexer/database"
+	"indexer/entities"
+	"indexer/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const extractEntitiesBatchSize = 500 // Default number of emails by batch of extract-entities
+
+// ExtractEntities finds the people, organizations and places of the emails not processed yet
+// The entities are found with the gazetteers and the patterns of the entities package, without remote services
+// --rebuild deletes the entities of the collection to extract them again, ex: after changing the gazetteers
+func (c *Cmd) ExtractEntities(args []string) {
+	var collection string
+	var rebuild bool
+	var batch int
+	fs := flag.NewFlagSet("extract-entities", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection to enrich")
+	fs.BoolVar(&rebuild, "rebuild", false, "delete the entities and extract them from all the emails")
+	fs.IntVar(&batch, "batch", extractEntitiesBatchSize, "emails by batch")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	if batch < 1 {
+		fmt.Println("--batch must be greater than 0")
+		return
+	}
+
+	if err := c.ensureCollection(collection); err != nil {
+		fmt.Println("Error preparing collection:", err)
+		return
+	}
+
+	extractor, err := entities.NewExtractor()
+	if err != nil {
+		fmt.Println("Error loading gazetteers:", err)
+		return
+	}
+
+	if rebuild {
+		if err := c.db.ResetEntities(collection); err != nil {
+			fmt.Println("Error deleting entities:", err)
+			return
+		}
+	}
+
+	// the entity filter of the searches changes with the entities deleted or saved, the batches saved before an error included
+	processed, found := 0, 0
+	defer func() {
+		if rebuild || processed > 0 {
+			c.bumpDataVersion(collection)
+		}
+	}()
+	for c.ctx.Err() == nil {
+		emails, err := c.db.ListEmailsWithoutEntities(collection, batch)
+		if err != nil {
+			fmt.Println("Error reading emails:", err)
+			return
+		}
+
+		if len(emails) == 0 {
+			break
+		}
+
+		ids := make([]uint32, 0, len(emails))
+		extracted := make([]models.EmailEntity, 0)
+		for _, email := range emails {
+			ids = append(ids, email.ID)
+			extracted = append(extracted, extractor.ExtractEmail(email)...)
+		}
+
+		if err := c.db.SaveEmailEntities(collection, ids, extracted); err != nil {
+			fmt.Println("Error saving entities:", err)
+			log.Error("Error saving entities:", err)
+			return
+		}
+
+		processed += len(emails)
+		found += len(extracted)
+		fmt.Printf("Processed %d emails, %d entities\n", processed, found)
+	}
+
+	log.WithFields(log.Fields{"collection": collection, "emails": processed, "entities": found}).Info("Entity extraction finished")
+	fmt.Printf("Extraction finished, emails: %d, entities: %d\n", processed, found)
+}
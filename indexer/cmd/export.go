@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"indexer/database"
+	"indexer/exporter"
+	"indexer/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const exportDirectory = "data/export" // Default directory of the exports
+
+// Export exports the emails of the database to a file
+// The format is specified with the --format flag: jsonl, csv, eml or mbox
+// The emails can be filtered by --ids, --from-id, --to-id, --date-from and --date-to
+// The emails are read from the collection specified with --collection
+// --annotations also exports the annotations of the exported emails in a jsonl file next to them
+func (c *Cmd) Export(args []string) {
+	var format, out, ids, dateFrom, dateTo, collection string
+	var fromID, toID uint
+	var annotations bool
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection to export")
+	fs.StringVar(&format, "format", "jsonl", "output format: jsonl, csv, eml or mbox")
+	fs.StringVar(&out, "out", "", "output file, or directory for the eml format")
+	fs.StringVar(&ids, "ids", "", "ids to export separated by commas")
+	fs.UintVar(&fromID, "from-id", 0, "minimum id to export")
+	fs.UintVar(&toID, "to-id", 0, "maximum id to export")
+	fs.StringVar(&dateFrom, "date-from", "", "minimum date to export YYYY-MM-DD")
+	fs.StringVar(&dateTo, "date-to", "", "maximum date to export YYYY-MM-DD")
+	fs.BoolVar(&annotations, "annotations", false, "export the annotations of the emails")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	exportFormat, err := exporter.NewFormat(format)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	filter := models.EmailFilter{FromID: uint32(fromID), ToID: uint32(toID)}
+	if filter.IDs, err = parseIDs(ids); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if filter.DateFrom, err = parseDate(dateFrom, false); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if filter.DateTo, err = parseDate(dateTo, true); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if out == "" {
+		out = defaultExportPath(exportFormat)
+	}
+
+	writer, err := exporter.NewWriter(exportFormat, out)
+	if err != nil {
+		fmt.Println("Error creating export:", err)
+		return
+	}
+
+	total := 0
+	exported := make(map[uint32]bool)
+	err = c.db.StreamEmails(collection, filter, func(email models.Email) error {
+		if err := writer.Write(email); err != nil {
+			return err
+		}
+
+		if annotations {
+			exported[email.ID] = true
+		}
+
+		total++
+		if total%1000 == 0 {
+			fmt.Printf("Exported %d emails\n", total)
+		}
+		return nil
+	})
+
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		fmt.Println("Error exporting:", err)
+		log.Error("Error exporting:", err)
+		return
+	}
+
+	log.WithFields(log.Fields{"format": exportFormat, "out": out, "total": total}).Info("Export finished")
+	fmt.Printf("Exported %d emails to %s\n", total, out)
+
+	if annotations {
+		c.exportAnnotations(collection, exporter.AnnotationsPath(exportFormat, out), exported)
+	}
+}
+
+// exportAnnotations writes the annotations of the exported emails
+func (c *Cmd) exportAnnotations(collection, out string, exported map[uint32]bool) {
+	writer, err := exporter.NewAnnotationsWriter(out)
+	if err != nil {
+		fmt.Println("Error creating annotations export:", err)
+		return
+	}
+
+	total := 0
+	err = c.db.StreamAnnotations(collection, func(annotation models.Annotation) error {
+		if !exported[annotation.EmailID] {
+			return nil
+		}
+
+		total++
+		return writer.Write(annotation)
+	})
+
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		fmt.Println("Error exporting annotations:", err)
+		log.Error("Error exporting annotations:", err)
+		return
+	}
+
+	log.WithFields(log.Fields{"out": out, "total": total}).Info("Annotations export finished")
+	fmt.Printf("Exported %d annotations to %s\n", total, out)
+}
+
+// defaultExportPath returns the path used when --out is not specified
+func defaultExportPath(format exporter.Format) string {
+	if format == exporter.FormatEML {
+		return filepath.Join(exportDirectory, "eml")
+	}
+
+	return filepath.Join(exportDirectory, "emails."+string(format))
+}
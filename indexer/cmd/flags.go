@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateFlagLayout = "2006-01-02"
+
+// parseIDs parses a list of ids separated by commas, ex: 1,2,3
+func parseIDs(value string) ([]uint32, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	ids := make([]uint32, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid id: %s", part)
+		}
+		ids = append(ids, uint32(id))
+	}
+
+	return ids, nil
+}
+
+// parseDate parses a date with the format YYYY-MM-DD
+// endOfDay: if true returns the last instant of the day
+func parseDate(value string, endOfDay bool) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(dateFlagLayout, strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %s, the format must be %s", value, dateFlagLayout)
+	}
+
+	if endOfDay {
+		date = date.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return &date, nil
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"indexer/database"
+	"indexer/models"
+)
+
+const historyDateLayout = "2006-01-02 15:04:05" // Layout of the dates printed by history and report
+
+// History shows the last runs of the indexer in the collection specified with --collection
+// The number of runs is specified with --limit
+func (c *Cmd) History(args []string) {
+	var collection string
+	var limit int
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection of the runs")
+	fs.IntVar(&limit, "limit", 20, "maximum number of runs, 0 to show all")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	runs, err := c.db.ListIndexRuns(collection, limit)
+	if err != nil {
+		fmt.Println("Error reading runs:", err)
+		return
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No runs in collection", collection)
+		return
+	}
+
+	for _, run := range runs {
+		fmt.Printf("%d\t%s\t%s\t%s\tinserted: %d\terrors: %d\tduration: %s\n",
+			run.ID, run.StartedAt.Local().Format(historyDateLayout), run.State, pagesLabel(run),
+			run.Stats.Inserted, run.Stats.Errors, run.Duration().Round(time.Second))
+	}
+}
+
+// Report shows the details of a run of the indexer
+// report <run-id> [--collection=C]
+func (c *Cmd) Report(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: report <run-id> [--collection=C]")
+		return
+	}
+
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		fmt.Println("invalid run id:", args[1])
+		return
+	}
+
+	var collection string
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection of the run")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[2:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	run, err := c.db.GetIndexRun(collection, id)
+	if err != nil {
+		fmt.Println("Error reading run:", err)
+		return
+	}
+
+	printReport(collection, *run)
+}
+
+// printReport prints the parameters, the dates and the totals by stage of the run
+func printReport(collection string, run models.IndexRun) {
+	finishedAt := "-"
+	if run.FinishedAt != nil {
+		finishedAt = run.FinishedAt.Local().Format(historyDateLayout)
+	}
+
+	fmt.Printf("Run %d of collection %s\n", run.ID, collection)
+	fmt.Printf("  State:       %s\n", run.State)
+	if run.Error != "" {
+		fmt.Printf("  Error:       %s\n", run.Error)
+	}
+	fmt.Printf("  Started:     %s\n", run.StartedAt.Local().Format(historyDateLayout))
+	fmt.Printf("  Finished:    %s\n", finishedAt)
+	fmt.Printf("  Duration:    %s\n", run.Duration().Round(time.Second))
+	fmt.Printf("  Parameters:  %s, pagination %d, batch size %d, parallelism %d\n", pagesLabel(run), run.Pagination, run.BatchSize, run.Parallelism)
+
+	fmt.Println("Scrape")
+	fmt.Printf("  Pages finished:    %d of %d\n", run.PagesFinished, run.PageCount())
+	fmt.Printf("  Pages with errors: %d\n", run.PagesWithErrors)
+	fmt.Printf("  Emails received:   %d\n", run.Stats.Received)
+
+	fmt.Println("Insert")
+	fmt.Printf("  Inserted:          %d\n", run.Stats.Inserted)
+	fmt.Printf("  Duplicated:        %d\n", run.Stats.Duplicated)
+	fmt.Printf("  Errors:            %d\n", run.Stats.Errors)
+
+	if len(run.Stats.DeadLetters) > 0 {
+		fmt.Println("Dead letters")
+		for _, stage := range sortedKeys(run.Stats.DeadLetters) {
+			fmt.Printf("  %-18s %d\n", stage+":", run.Stats.DeadLetters[stage])
+		}
+	}
+
+	if len(run.Stats.ErrorSummary) > 0 {
+		fmt.Println("Errors")
+		messages := sortedKeys(run.Stats.ErrorSummary)
+		sort.SliceStable(messages, func(i, j int) bool {
+			return run.Stats.ErrorSummary[messages[i]] > run.Stats.ErrorSummary[messages[j]]
+		})
+		for _, message := range messages {
+			fmt.Printf("  %6d  %s\n", run.Stats.ErrorSummary[message], message)
+		}
+	}
+}
+
+// pagesLabel returns the pages of the run
+// the range is only printed alone when the run indexed all its pages, a resumed run indexes some pages of the range
+func pagesLabel(run models.IndexRun) string {
+	if run.PageCount() == run.ToPage-run.FromPage+1 {
+		return fmt.Sprintf("pages %d-%d", run.FromPage, run.ToPage)
+	}
+
+	return fmt.Sprintf("%d pages in %d-%d", run.PageCount(), run.FromPage, run.ToPage)
+}
+
+// sortedKeys returns the keys of the map sorted
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"indexer/database"
+	"indexer/importer"
+	"indexer/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const importProgressInterval = 1000 // Interval in emails to print the progress of an import
+
+// Import seeds the database from a dump
+// The dump is specified with the --in flag, it can be a jsonl file, an mbox file or a directory of .eml files
+// The format is detected from the path unless --format is specified
+func (c *Cmd) Import(args []string) {
+	var in, format, collection string
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection to store the emails")
+	fs.StringVar(&in, "in", "", "jsonl file, mbox file or directory of .eml files to import")
+	fs.StringVar(&format, "format", "", "format of the dump: jsonl, mbox or eml (default detected from --in)")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	if in == "" {
+		fmt.Println("the flag --in is required")
+		return
+	}
+
+	importFormat, err := importer.DetectFormat(format, in)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := c.ensureCollection(collection); err != nil {
+		fmt.Println("Error preparing collection:", err)
+		return
+	}
+
+	sourceCh := make(chan models.EmailResult)
+	emailsCh := make(chan models.EmailResult)
+
+	go func() {
+		if err := importer.Read(importFormat, in, sourceCh); err != nil {
+			fmt.Println("Error reading dump:", err)
+			log.Error("Error reading dump:", err)
+		}
+	}()
+
+	// report the progress and the invalid emails before sending them to the indexer
+	go func() {
+		defer close(emailsCh)
+		read := 0
+		for result := range sourceCh {
+			read++
+			if result.Error != nil {
+				log.WithFields(log.Fields{"error": result.Error}).Warn("Invalid email in dump")
+			}
+
+			if read%importProgressInterval == 0 {
+				fmt.Printf("Read %d emails\n", read)
+			}
+
+			emailsCh <- result
+		}
+	}()
+
+	fmt.Printf("Importing %s from %s in collection %s\n", importFormat, in, collection)
+	stats, err := c.indexer.WithCollection(collection).IndexEmail(emailsCh, c.batchSize)
+	if err != nil {
+		fmt.Println("Error importing:", err)
+		log.Error("Error importing:", err)
+	}
+
+	log.WithFields(log.Fields{"in": in, "format": importFormat, "collection": collection, "inserted": stats.Inserted, "duplicated": stats.Duplicated, "errors": stats.Errors}).Info("Import finished")
+	fmt.Printf("Import finished, inserted: %d, duplicated: %d, errors: %d\n", stats.Inserted, stats.Duplicated, stats.Errors)
+
+	if stats.Inserted > 0 {
+		c.bumpDataVersion(collection)
+		c.evaluateSavedSearches(collection, 0)
+	}
+}
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"sort"
 	"strconv"
+	"sync"
+	"time"
 
+	"indexer/database"
+	"indexer/metrics"
 	"indexer/models"
+	"indexer/scraper"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -13,11 +21,18 @@ import (
 // Indexer indexes emails from a range of pages
 // The pages are specified with the --from and --to flags
 // If --to is greater than the last page, it will use the last page
+// --resume indexes the pages not finished by the previous runs instead of the range
+// The emails are stored in the collection specified with --collection
+// It returns when the run is finished or interrupted by the context of the cmd
 func (c *Cmd) Indexer(args []string) {
 	var from, to int
+	var resume bool
+	var collection string
 	fs := flag.NewFlagSet("index", flag.ContinueOnError)
 	fs.IntVar(&from, "from", 1, "page number to start indexing from")
 	fs.IntVar(&to, "to", 1, "page number to end indexing at")
+	fs.BoolVar(&resume, "resume", false, "index the pending and interrupted pages of the collection")
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection to store the emails")
 
 	// Parse the flags from the input
 	err := fs.Parse(args[1:])
@@ -26,49 +41,219 @@ func (c *Cmd) Indexer(args []string) {
 		return
 	}
 
+	if err := c.ensureCollection(collection); err != nil {
+		fmt.Println("Error preparing collection:", err)
+		return
+	}
+
+	// the status of the pages is the status of the collection of this run
+	status := c.loadStatus(collection)
+
 	if c.lastPage > 0 && to > c.lastPage {
 		to = c.lastPage
 	}
 
-	for i := from; i <= to; i++ {
-		key := strconv.Itoa(i)
-		c.status.Set(key, models.PageResult{Page: i, State: models.PageResultStatePending, Total: 0, Error: ""})
+	var pages []int
+	if resume {
+		pages, err = c.resumablePages(collection)
+		if err != nil {
+			fmt.Println("Error reading status:", err)
+			return
+		}
+
+		if len(pages) == 0 {
+			fmt.Println("No pages to resume")
+			return
+		}
+
+		from, to = pages[0], pages[len(pages)-1]
+	} else {
+		for i := from; i <= to; i++ {
+			pages = append(pages, i)
+		}
+	}
+
+	run := models.IndexRun{
+		FromPage:    from,
+		ToPage:      to,
+		Pages:       len(pages),
+		Pagination:  int(c.paginationSize),
+		BatchSize:   c.batchSize,
+		Parallelism: c.scrapper.Parallelism(),
+		State:       models.IndexRunStateRunning,
+		StartedAt:   time.Now().UTC(),
 	}
-	err = c.SavePageResults(statusDirectory, statusFilename, c.status.GetCopy())
+	if err := c.db.CreateIndexRun(collection, &run); err != nil {
+		fmt.Println("Error creating run:", err)
+		return
+	}
+
+	pending := make([]models.PageResult, 0, len(pages))
+	for _, i := range pages {
+		result := models.PageResult{Page: i, State: models.PageResultStatePending, Total: 0, Error: ""}
+		status.Set(strconv.Itoa(i), result)
+		pending = append(pending, result)
+	}
+	err = c.db.SavePageResults(collection, run.ID, pending)
 	if err != nil {
 		log.Error("Error saving initial status:", err)
 	}
+	metrics.SetPagesByState(status.GetCopy())
 
+	pipeline := c.scrapper.PipelineConfig()
 	pageResultCh := make(chan models.PageResult)
-	emailsCh := make(chan models.EmailResult)
+	emailsCh := make(chan models.EmailResult, pipeline.QueueSize)
+
+	var wg sync.WaitGroup
+	var scrapeErr, indexErr error
+	var stats models.IndexStats
+	wg.Add(3)
 
 	go func() {
-		c.isScraping = true
-		err = c.scrapper.ScrapeEmails(from, to, c.paginationSize, emailsCh, pageResultCh, c.intervalUpdate)
-		if err != nil {
-			fmt.Println("Error indexing:", err)
-			log.Error("Error indexing:", err)
+		defer wg.Done()
+		scrapeErr = c.scrapper.ScrapePages(c.ctx, pages, c.paginationSize, emailsCh, pageResultCh, c.intervalUpdate)
+		if scrapeErr != nil {
+			fmt.Println("Error indexing:", scrapeErr)
+			log.Error("Error indexing:", scrapeErr)
 		}
 
 		fmt.Println("Scraping finished")
-
-		c.isScraping = false
 	}()
 
 	// Index emails in batches
 	go func() {
-		c.indexer.IndexEmail(emailsCh, c.batchSize)
+		defer wg.Done()
+		stats, indexErr = c.indexEmails(collection, emailsCh, pipeline.InsertWorkers)
 	}()
 
 	// Update status in real time
 	go func() {
+		defer wg.Done()
 		for result := range pageResultCh {
-			key := strconv.Itoa(result.Page)
 			log.WithFields(log.Fields{"page": result.Page, "total": result.Total, "state": result.State}).Info("Update data page")
-			c.status.Set(key, result)
-			c.SavePageResults(statusDirectory, statusFilename, c.status.GetCopy())
+			status.Set(strconv.Itoa(result.Page), result)
+			run.AddPageResult(result)
+			if err := c.db.SavePageResults(collection, run.ID, []models.PageResult{result}); err != nil {
+				log.Error("Error saving status:", err)
+			}
+			metrics.SetPagesByState(status.GetCopy())
 		}
 	}()
 
-	fmt.Printf("Indexing from page %d to %d\n", from, to)
+	fmt.Printf("Indexing %d pages from page %d to %d in collection %s, run %d\n", len(pages), from, to, collection, run.ID)
+
+	// Finish the run when the emails and the pages are processed
+	wg.Wait()
+	run.Stats = stats
+	if errors.Is(scrapeErr, context.Canceled) {
+		c.interruptPages(collection, run.ID, pages, status)
+	}
+	c.finishRun(collection, run, errors.Join(scrapeErr, indexErr))
+
+	if stats.Inserted > 0 {
+		c.evaluateSavedSearches(collection, run.ID)
+	}
+}
+
+// resumablePages returns the sorted pages of the collection that are not finished
+func (c *Cmd) resumablePages(collection string) ([]int, error) {
+	results, err := c.db.LoadPageResults(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]int, 0)
+	for _, result := range results {
+		if result.State != models.PageResultStateFinished {
+			pages = append(pages, result.Page)
+		}
+	}
+	sort.Ints(pages)
+
+	return pages, nil
+}
+
+// interruptPages marks the pages of the run that are not finished as interrupted to resume them later
+// status is the status of the pages of the collection
+func (c *Cmd) interruptPages(collection string, runID int64, pages []int, status *models.SafeMap) {
+	interrupted := make([]models.PageResult, 0)
+	for _, page := range pages {
+		result, ok := status.Get(strconv.Itoa(page))
+		if !ok || result.State == models.PageResultStateFinished {
+			continue
+		}
+
+		result.State = models.PageResultStateInterrupted
+		status.Set(strconv.Itoa(page), result)
+		interrupted = append(interrupted, result)
+	}
+
+	if err := c.db.SavePageResults(collection, runID, interrupted); err != nil {
+		log.Error("Error saving interrupted pages:", err)
+	}
+	metrics.SetPagesByState(status.GetCopy())
+
+	log.WithFields(log.Fields{"run": runID, "collection": collection, "pages": len(interrupted)}).Warn("Index run interrupted")
+	fmt.Printf("Run %d interrupted, %d pages to resume with: index --resume --collection=%s\n", runID, len(interrupted), collection)
+}
+
+// finishRun stores the end and the totals of the run
+// the run is interrupted if the scraper was cancelled and fails if the scraper or the indexer returned another error
+func (c *Cmd) finishRun(collection string, run models.IndexRun, err error) {
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+	run.State = models.IndexRunStateFinished
+	if errors.Is(err, context.Canceled) {
+		run.State = models.IndexRunStateInterrupted
+		run.Error = err.Error()
+	} else if err != nil {
+		run.State = models.IndexRunStateFailed
+		run.Error = err.Error()
+	}
+
+	if err := c.db.UpdateIndexRun(collection, run); err != nil {
+		log.Error("Error saving run:", err)
+	}
+
+	if run.Stats.Inserted > 0 {
+		c.bumpDataVersion(collection)
+	}
+
+	log.WithFields(log.Fields{"run": run.ID, "collection": collection, "state": run.State, "inserted": run.Stats.Inserted, "errors": run.Stats.Errors}).Info("Index run finished")
+}
+
+// bumpDataVersion records that the searchable data of the collection changed, the API invalidates its cache of searches
+// the error is logged, the cache expires after its TTL
+func (c *Cmd) bumpDataVersion(collection string) {
+	if err := c.db.BumpDataVersion(collection); err != nil {
+		log.WithFields(log.Fields{"collection": collection, "error": err}).Error("Error updating data version")
+	}
+}
+
+// indexEmails runs the workers of the insert stage and adds their totals
+func (c *Cmd) indexEmails(collection string, emailsCh <-chan models.EmailResult, workers int) (models.IndexStats, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	stats := models.IndexStats{}
+	indexer := c.indexer.WithCollection(collection)
+
+	metrics.PipelineWorkers.WithLabelValues(scraper.StageInsert).Set(float64(workers))
+	defer metrics.PipelineWorkers.WithLabelValues(scraper.StageInsert).Set(0)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workerStats, err := indexer.IndexEmail(emailsCh, c.batchSize)
+
+			mu.Lock()
+			defer mu.Unlock()
+			stats.Merge(workerStats)
+			errs = append(errs, err)
+		}()
+	}
+
+	wg.Wait()
+	return stats, errors.Join(errs...)
 }
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"indexer/database"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Migrate applies, reverts or shows the migrations of the schema of a collection
+// migrate up [--steps=N]: applies the pending migrations, all by default
+// migrate down [--steps=N]: reverts the last applied migrations, one by default
+// migrate status: shows the migrations and if they are applied
+func (c *Cmd) Migrate(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: migrate up|down|status [--steps=N] [--collection=C]")
+		return
+	}
+
+	action := args[1]
+	var steps int
+	var collection string
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection to migrate")
+	defaultSteps := 0
+	if action == "down" {
+		defaultSteps = 1
+	}
+	fs.IntVar(&steps, "steps", defaultSteps, "number of migrations to apply or revert, 0 for all")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[2:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	migrator, err := c.db.NewMigrator(collection)
+	if err != nil {
+		fmt.Println("Error loading migrations:", err)
+		return
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(steps)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+			log.WithFields(log.Fields{"version": migration.Version, "name": migration.Name}).Info("Migration applied")
+		}
+		if err != nil {
+			fmt.Println("Error applying migrations:", err)
+			log.Error("Error applying migrations:", err)
+			return
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		if err := c.db.RegisterCollection(collection, ""); err != nil {
+			fmt.Println("Error registering collection:", err)
+		}
+	case "down":
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+			log.WithFields(log.Fields{"version": migration.Version, "name": migration.Name}).Warn("Migration reverted")
+		}
+		if err != nil {
+			fmt.Println("Error reverting migrations:", err)
+			log.Error("Error reverting migrations:", err)
+			return
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to revert")
+		}
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			fmt.Println("Error reading migrations:", err)
+			return
+		}
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s: %s\n", s.Version, s.Name, appliedAt)
+		}
+	default:
+		fmt.Println("Unknown migrate action:", action)
+		fmt.Println("Usage: migrate up|down|status [--steps=N] [--collection=C]")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"indexer/database"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const reindexSearchBatchSize = 1000 // Default number of emails by batch of reindex-search
+
+// ReindexSearch computes the search vectors of the collection again with the text search config of --config
+// The vectors are written in a new table in batches of --batch emails, the API keeps searching in the current table
+// When all the emails are indexed the new table replaces the current one
+// An interrupted reindex is resumed by running the command again with the same config
+func (c *Cmd) ReindexSearch(args []string) {
+	var collection, searchConfig string
+	var batch int
+	fs := flag.NewFlagSet("reindex-search", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection to reindex")
+	fs.StringVar(&searchConfig, "config", "", "text search config in cockroach or FTS5 tokenizer in sqlite, the options separated by commas (default the current config)")
+	fs.IntVar(&batch, "batch", reindexSearchBatchSize, "emails by batch")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	if batch < 1 {
+		fmt.Println("--batch must be greater than 0")
+		return
+	}
+
+	if err := c.ensureCollection(collection); err != nil {
+		fmt.Println("Error preparing collection:", err)
+		return
+	}
+
+	current, err := c.db.GetSearchConfig(collection)
+	if err != nil {
+		fmt.Println("Error reading search config:", err)
+		return
+	}
+
+	// the cli splits the input by spaces, the options of the tokenizers are separated by commas
+	searchConfig = strings.ReplaceAll(searchConfig, ",", " ")
+	if searchConfig == "" {
+		searchConfig = current.Config
+	}
+
+	lastID, err := c.db.StartSearchReindex(collection, searchConfig)
+	if err != nil {
+		fmt.Println("Error starting reindex:", err)
+		return
+	}
+
+	if lastID > 0 {
+		fmt.Printf("Resuming reindex of collection %s with config '%s' after email %d\n", collection, searchConfig, lastID)
+	} else {
+		fmt.Printf("Reindexing collection %s with config '%s'\n", collection, searchConfig)
+	}
+
+	reindexed := 0
+	for {
+		if c.ctx.Err() != nil {
+			fmt.Printf("Reindex interrupted after email %d, run reindex-search --config=%s again to resume\n", lastID, strings.ReplaceAll(searchConfig, " ", ","))
+			return
+		}
+
+		var total int
+		lastID, total, err = c.db.ReindexSearchBatch(collection, searchConfig, lastID, batch)
+		if err != nil {
+			fmt.Println("Error reindexing:", err)
+			log.Error("Error reindexing:", err)
+			return
+		}
+
+		if total == 0 {
+			break
+		}
+
+		reindexed += total
+		fmt.Printf("Reindexed %d emails, last id %d\n", reindexed, lastID)
+	}
+
+	if err := c.db.SwapSearchIndex(collection); err != nil {
+		fmt.Println("Error swapping search table:", err)
+		log.Error("Error swapping search table:", err)
+		return
+	}
+	c.bumpDataVersion(collection)
+
+	log.WithFields(log.Fields{"collection": collection, "config": searchConfig, "reindexed": reindexed}).Info("Search reindex finished")
+	fmt.Printf("Reindex finished, the search of collection %s uses config '%s'\n", collection, searchConfig)
+}
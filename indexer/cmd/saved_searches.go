@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"indexer/database"
+	"indexer/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SavedSearches lists the searches saved in the API with the date of their last evaluation
+// --evaluate records the new matches of the searches now, without waiting for the next run
+func (c *Cmd) SavedSearches(args []string) {
+	var collection string
+	var evaluate bool
+	fs := flag.NewFlagSet("saved-searches", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection of the saved searches")
+	fs.BoolVar(&evaluate, "evaluate", false, "record the new matches of the saved searches")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	if evaluate {
+		total := c.evaluateSavedSearches(collection, 0)
+		fmt.Printf("Saved searches evaluated, new matches: %d\n", total)
+		return
+	}
+
+	searches, err := c.db.ListSavedSearches(collection)
+	if err != nil {
+		fmt.Println("Error reading saved searches:", err)
+		return
+	}
+
+	if len(searches) == 0 {
+		fmt.Println("No saved searches")
+		return
+	}
+
+	for _, search := range searches {
+		evaluated := "never"
+		if search.EvaluatedAt != nil {
+			evaluated = search.EvaluatedAt.Format(time.RFC3339)
+		}
+
+		fmt.Printf("%d  %s/%s  query=%q entities=%v tags=%v  evaluated: %s\n", search.ID, search.Owner, search.Name, search.Query.Query, search.Query.Entities, search.Query.Tags, evaluated)
+	}
+}
+
+// evaluateSavedSearches records the new matches of the saved searches of the collection after a run
+// the new matches are sent to the webhook, the errors are logged without failing the run
+// the matches of an alert not accepted by the webhook stay pending and are sent again after the next evaluation
+// returns the number of new matches
+func (c *Cmd) evaluateSavedSearches(collection string, runID int64) int {
+	searches, err := c.db.ListSavedSearches(collection)
+	if err != nil {
+		log.WithFields(log.Fields{"collection": collection, "error": err}).Error("Error reading saved searches")
+		return 0
+	}
+
+	total := 0
+	matchedAt := time.Now().UTC()
+	for _, search := range searches {
+		ids, err := c.db.MatchSavedSearch(collection, search.Query)
+		if err != nil {
+			log.WithFields(log.Fields{"collection": collection, "savedSearch": search.ID, "error": err}).Error("Error matching saved search")
+			continue
+		}
+
+		newIDs, err := c.db.SaveSavedSearchMatches(collection, search.ID, runID, ids, matchedAt, c.webhook != nil)
+		if err != nil {
+			log.WithFields(log.Fields{"collection": collection, "savedSearch": search.ID, "error": err}).Error("Error saving saved search matches")
+			continue
+		}
+
+		total += len(newIDs)
+		log.WithFields(log.Fields{"collection": collection, "savedSearch": search.ID, "run": runID, "matches": len(ids), "new": len(newIDs)}).Info("Saved search evaluated")
+
+		if c.webhook != nil {
+			c.sendSavedSearchAlert(collection, search, runID, matchedAt)
+		}
+	}
+
+	return total
+}
+
+// sendSavedSearchAlert sends the pending matches of the saved search to the webhook
+// the matches are marked as sent only when the webhook accepts the alert
+func (c *Cmd) sendSavedSearchAlert(collection string, search models.SavedSearch, runID int64, matchedAt time.Time) {
+	ids, err := c.db.ListPendingAlerts(collection, search.ID)
+	if err != nil {
+		log.WithFields(log.Fields{"collection": collection, "savedSearch": search.ID, "error": err}).Error("Error reading pending alerts")
+		return
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	alert := models.SavedSearchAlert{
+		SavedSearchID: search.ID,
+		Owner:         search.Owner,
+		Name:          search.Name,
+		Collection:    collection,
+		RunID:         runID,
+		EmailIDs:      ids,
+		MatchedAt:     matchedAt,
+	}
+
+	// the alerts are sent during the shutdown too, the client has its own timeout
+	if err := c.webhook.Send(context.Background(), alert); err != nil {
+		log.WithFields(log.Fields{"collection": collection, "savedSearch": search.ID, "pending": len(ids), "error": err}).Error("Error sending saved search alert, it will be sent again after the next evaluation")
+		return
+	}
+
+	if err := c.db.MarkAlertsSent(collection, search.ID, ids); err != nil {
+		log.WithFields(log.Fields{"collection": collection, "savedSearch": search.ID, "error": err}).Error("Error marking saved search alert as sent")
+	}
+}
@@ -1,30 +1,48 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
 
+	"indexer/database"
 	"indexer/models"
 )
 
-// Status shows the status of the indexing process
-func (c *Cmd) Status() {
-	if c.status.Len() == 0 {
-		loadedStatus, err := c.LoadPageResults(statusDirectory, statusFilename)
-		if err == nil {
-			c.status.SetMap(loadedStatus)
-		}
+// Status shows the status of the pages indexed in the collection specified with --collection
+// The status is read from the database, --export also writes it to the JSON file of the data directory
+func (c *Cmd) Status(args []string) {
+	var collection string
+	var export bool
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection of the pages")
+	fs.BoolVar(&export, "export", false, "write the status to "+statusDirectory+"/"+statusFilename)
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	results, err := c.db.LoadPageResults(collection)
+	if err != nil {
+		fmt.Println("Error reading status:", err)
+		return
 	}
 
-	if c.status.Len() == 0 {
+	if len(results) == 0 {
 		fmt.Println("No pages to show status")
 		return
 	}
 
+	status := models.NewSafeMap()
+	status.SetMap(results)
+
 	fmt.Println("Checking status...")
 	pendingPages := 0
 	totalFinished := 0
 	totalProcessing := 0
-	c.status.Range(func(key int, value models.PageResult) {
+	totalInterrupted := 0
+	status.Range(func(key int, value models.PageResult) {
 		fmt.Printf("Page: %d, State: %s, Total: %d, Error: %s\n", key, value.State, value.Total, value.Error)
 		switch value.State {
 		case models.PageResultStatePending:
@@ -33,7 +51,17 @@ func (c *Cmd) Status() {
 			totalFinished++
 		case models.PageResultStateProcessing:
 			totalProcessing++
+		case models.PageResultStateInterrupted:
+			totalInterrupted++
 		}
 	})
-	fmt.Printf("Total pages: %d, Pending pages: %d, Processing pages: %d, Finished pages: %d\n", c.status.Len(), pendingPages, totalProcessing, totalFinished)
+	fmt.Printf("Total pages: %d, Pending pages: %d, Processing pages: %d, Interrupted pages: %d, Finished pages: %d\n", status.Len(), pendingPages, totalProcessing, totalInterrupted, totalFinished)
+
+	if export {
+		if err := c.SavePageResults(statusDirectory, statusFilename, results); err != nil {
+			fmt.Println("Error exporting status:", err)
+			return
+		}
+		fmt.Println("Status exported to", statusDirectory+"/"+statusFilename)
+	}
 }
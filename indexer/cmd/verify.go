@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"indexer/database"
+	"indexer/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const verifyMaxGapsPrinted = 50 // Maximum number of gaps printed by verify
+
+// Verify compares the ids of the collection with the ids listed by the site
+// The range of ids is --from-id to --to-id, by default 1 to the number of emails listed by the site
+// The finished pages with less rows than the pagination are listed too
+// --backfill stores the missing ids as dead letters of the missing stage, deadletters replay fetches them
+func (c *Cmd) Verify(args []string) {
+	var collection string
+	var fromID, toID uint
+	var backfill bool
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.StringVar(&collection, "collection", database.DBSchemaName, "collection to verify")
+	fs.UintVar(&fromID, "from-id", 1, "first id of the range")
+	fs.UintVar(&toID, "to-id", 0, "last id of the range (default the number of emails listed by the site)")
+	fs.BoolVar(&backfill, "backfill", false, "queue the missing ids to fetch them with deadletters replay --stage=missing")
+
+	// Parse the flags from the input
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Println("Error parsing flags:", err)
+		return
+	}
+
+	if toID == 0 {
+		total, err := c.scrapper.CountEmails(c.paginationSize)
+		if err != nil {
+			fmt.Println("Error counting the emails of the site:", err)
+			return
+		}
+		toID = uint(total)
+	}
+
+	if fromID < 1 || fromID > toID {
+		fmt.Println("--from-id must be between 1 and --to-id")
+		return
+	}
+
+	report, err := c.verifyCollection(collection, uint32(fromID), uint32(toID))
+	if err != nil {
+		fmt.Println("Error verifying collection:", err)
+		return
+	}
+
+	printVerifyReport(report)
+
+	if backfill && len(report.Gaps) > 0 {
+		queued, err := c.queueMissing(collection, report.Gaps)
+		if err != nil {
+			fmt.Println("Error queueing missing ids:", err)
+			return
+		}
+
+		log.WithFields(log.Fields{"collection": collection, "queued": queued}).Info("Missing ids queued")
+		fmt.Printf("Queued %d missing ids, fetch them with: deadletters replay --stage=%s --collection=%s\n", queued, models.StageMissing, collection)
+	}
+}
+
+// verifyCollection finds the gaps of ids of the collection and the pages with less rows than the pagination
+func (c *Cmd) verifyCollection(collection string, fromID, toID uint32) (models.VerifyReport, error) {
+	report := models.VerifyReport{Expected: models.IDRange{From: fromID, To: toID}}
+
+	ids, err := c.db.ListEmailIDs(collection, fromID, toID)
+	if err != nil {
+		return report, err
+	}
+	report.Stored = len(ids)
+	report.Gaps = models.FindGaps(ids, fromID, toID)
+
+	results, err := c.db.LoadPageResults(collection)
+	if err != nil {
+		return report, err
+	}
+
+	for _, result := range results {
+		if result.State == models.PageResultStateFinished && result.Total < int(c.paginationSize) && (c.lastPage < 1 || result.Page < c.lastPage) {
+			report.ShortPages = append(report.ShortPages, result)
+		}
+	}
+	sort.Slice(report.ShortPages, func(i, j int) bool { return report.ShortPages[i].Page < report.ShortPages[j].Page })
+
+	return report, nil
+}
+
+// queueMissing stores the missing ids as dead letters, the ids already pending are skipped
+// returns the number of ids queued
+func (c *Cmd) queueMissing(collection string, gaps []models.IDRange) (int, error) {
+	pending, err := c.db.ListDeadLetters(collection, models.DeadLetterFilter{Stage: models.StageMissing})
+	if err != nil {
+		return 0, err
+	}
+
+	queued := make(map[uint32]bool, len(pending))
+	for _, letter := range pending {
+		queued[letter.EmailID] = true
+	}
+
+	letters := make([]models.DeadLetter, 0)
+	for _, gap := range gaps {
+		for _, id := range gap.IDs() {
+			if queued[id] {
+				continue
+			}
+
+			letters = append(letters, models.NewEmailDeadLetter(models.StageMissing, models.Email{ID: id}, fmt.Errorf("id %d missing in the collection", id)))
+		}
+	}
+
+	return len(letters), c.db.SendDeadLetters(collection, letters)
+}
+
+// printVerifyReport prints the totals, the gaps and the short pages
+func printVerifyReport(report models.VerifyReport) {
+	fmt.Printf("Expected ids: %d to %d (%d), stored: %d, missing: %d in %d gaps\n",
+		report.Expected.From, report.Expected.To, report.Expected.Len(), report.Stored, report.Missing(), len(report.Gaps))
+
+	for i, gap := range report.Gaps {
+		if i == verifyMaxGapsPrinted {
+			fmt.Printf("... and %d gaps more\n", len(report.Gaps)-verifyMaxGapsPrinted)
+			break
+		}
+
+		if gap.From == gap.To {
+			fmt.Println("  missing id", gap.From)
+			continue
+		}
+		fmt.Printf("  missing ids %d to %d (%d)\n", gap.From, gap.To, gap.Len())
+	}
+
+	for _, page := range report.ShortPages {
+		fmt.Printf("  page %d finished with %d rows\n", page.Page, page.Total)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -12,10 +13,15 @@ import (
 // Env: Environment
 // DBConfig: Database configuration
 // Scrapper: Scraper configuration
+// Metrics: Metrics listener configuration
+// SavedSearches: Webhook of the new matches of the saved searches
+// ShutdownTimeout: time to wait for the running index to stop on a signal
 // LogLevel: Log level
 type Config struct {
 	Env      string
 	DBConfig struct {
+		Driver   string // cockroach or sqlite
+		Path     string // file of the sqlite database
 		Host     string
 		Name     string
 		User     string
@@ -24,16 +30,31 @@ type Config struct {
 		SSL      bool
 	}
 	Scrapper struct {
-		Parallelism int
-		Delay       int
+		Parallelism    int
+		Delay          int
+		ListingWorkers int // parallel requests to the listing pages
+		ParseWorkers   int // workers parsing the rows
+		ContentWorkers int // parallel requests to the content of the emails
+		InsertWorkers  int // workers inserting the batches
+		QueueSize      int // buffer of the queue of every stage
 	}
-	LogLevel string
+	Metrics struct {
+		Address string // empty to disable the listener
+	}
+	SavedSearches struct {
+		WebhookURL     string        // local URL of the alerts, empty to disable them
+		WebhookTimeout time.Duration // timeout of every alert
+	}
+	ShutdownTimeout time.Duration
+	LogLevel        string
 }
 
 var config *Config
 
 func InitConfigEnviroment() {
 	config = &Config{Env: os.Getenv("ENVIRONMENT")}
+	config.DBConfig.Driver = strings.ToLower(os.Getenv("DB_DRIVER"))
+	config.DBConfig.Path = os.Getenv("DB_PATH")
 	config.DBConfig.Host = os.Getenv("DB_HOST")
 	config.DBConfig.Name = os.Getenv("DB_NAME")
 	config.DBConfig.User = os.Getenv("DB_USER")
@@ -41,6 +62,7 @@ func InitConfigEnviroment() {
 	config.DBConfig.Port = os.Getenv("DB_PORT")
 	config.DBConfig.SSL = os.Getenv("DB_SSL") == "true"
 	config.LogLevel = os.Getenv("LOG_LEVEL")
+	config.Metrics.Address = os.Getenv("METRICS_ADDRESS")
 
 	if config.LogLevel == "" {
 		config.LogLevel = "info"
@@ -59,6 +81,27 @@ func InitConfigEnviroment() {
 	if err != nil {
 		config.Scrapper.Delay = 1
 	}
+
+	config.Scrapper.ListingWorkers = getEnvInt("SCRAPPER_LISTING_WORKERS", config.Scrapper.Parallelism)
+	config.Scrapper.ParseWorkers = getEnvInt("SCRAPPER_PARSE_WORKERS", 4)
+	config.Scrapper.ContentWorkers = getEnvInt("SCRAPPER_CONTENT_WORKERS", config.Scrapper.Parallelism)
+	config.Scrapper.InsertWorkers = getEnvInt("INDEXER_INSERT_WORKERS", 1)
+	config.Scrapper.QueueSize = getEnvInt("PIPELINE_QUEUE_SIZE", 200)
+
+	config.ShutdownTimeout = time.Duration(getEnvInt("SHUTDOWN_TIMEOUT", 60)) * time.Second
+
+	config.SavedSearches.WebhookURL = os.Getenv("SAVED_SEARCH_WEBHOOK_URL")
+	config.SavedSearches.WebhookTimeout = time.Duration(getEnvInt("SAVED_SEARCH_WEBHOOK_TIMEOUT", 5)) * time.Second
+}
+
+// getEnvInt returns the environment variable as int or the default value if it is not a number
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
 }
 
 func GetConfig() *Config {
@@ -70,6 +113,18 @@ func GetConfig() *Config {
 		config.Env = "dev"
 	}
 
+	if config.DBConfig.Driver == "" {
+		config.DBConfig.Driver = "cockroach"
+	}
+
+	if config.DBConfig.Driver == "sqlite" {
+		if config.DBConfig.Path == "" {
+			config.DBConfig.Path = "data/hillary.db"
+		}
+
+		return config
+	}
+
 	if config.DBConfig.Host == "" {
 		panic("db_host not specified")
 	}
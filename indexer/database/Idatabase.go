@@ -2,13 +2,42 @@ package database
 
 import (
 	"database/sql"
+	"time"
 
 	"indexer/models"
 )
 
 // IDatabase represents the database interface
 // SendMails: Sends emails to the database
-// CreateSchemaIfNotExist: Creates the schema if it doesn't exist
+// SendDeadLetters: Stores the emails rejected by the pipeline
+// ListDeadLetters: Reads the dead letters that match the filter ordered by id
+// MarkDeadLettersReplayed: Sets the date of the replay of the dead letters
+// CreateIndexRun: Stores a new run of the indexer and sets its id
+// UpdateIndexRun: Updates the state of a run of the indexer
+// ListIndexRuns: Reads the last runs of the indexer
+// GetIndexRun: Reads a run of the indexer by id
+// SavePageResults: Stores the state of the pages indexed by a run
+// LoadPageResults: Reads the state of the pages
+// StreamEmails: Reads the emails that match the filter ordered by id
+// ListEmailIDs: Reads the sorted ids of the emails in a range of ids
+// GetSearchConfig: Reads the text search configuration of the search table
+// StartSearchReindex: Creates or resumes the new search table of a reindex and returns the last id indexed
+// ReindexSearchBatch: Computes the search vectors of a batch of emails in the new search table
+// SwapSearchIndex: Replaces the search table with the new one
+// ListEmailsWithoutEntities: Reads the emails not processed by the entity extraction
+// SaveEmailEntities: Replaces the entities of the emails and marks them as processed
+// ResetEntities: Deletes the entities to extract them again
+// BumpDataVersion: Increments the version of the searchable data read by the API
+// ListSavedSearches: Reads the searches saved in the API
+// MatchSavedSearch: Returns the ids of the emails that match a saved query
+// SaveSavedSearchMatches: Records the new matches of a saved search and returns them
+// ListPendingAlerts: Returns the matches of a saved search that wait for their alert
+// MarkAlertsSent: Removes the matches of a saved search from the pending alerts
+// StreamAnnotations: Reads the annotations of the emails added in the API
+// CreateSchemaIfNotExist: Creates the schema if it doesn't exist and applies the pending migrations
+// NewMigrator: Returns the migrator of the schema
+// RegisterCollection: Adds the schema to the registry of collections
+// ListCollections: Returns the collections of the registry
 // IsSchemaCreated: Checks if the schema exists
 // Open: Opens the database connection
 // OpenWithPool: Opens the database connection with a pool
@@ -16,7 +45,35 @@ import (
 // Close: Closes the database connection
 type IDatabase interface {
 	SendMails(schemaName string, emails []models.Email) (int64, error)
+	SendDeadLetters(schemaName string, letters []models.DeadLetter) error
+	ListDeadLetters(schemaName string, filter models.DeadLetterFilter) ([]models.DeadLetter, error)
+	MarkDeadLettersReplayed(schemaName string, ids []int64) error
+	CreateIndexRun(schemaName string, run *models.IndexRun) error
+	UpdateIndexRun(schemaName string, run models.IndexRun) error
+	ListIndexRuns(schemaName string, limit int) ([]models.IndexRun, error)
+	GetIndexRun(schemaName string, id int64) (*models.IndexRun, error)
+	SavePageResults(schemaName string, runID int64, results []models.PageResult) error
+	LoadPageResults(schemaName string) (map[string]models.PageResult, error)
+	StreamEmails(schemaName string, filter models.EmailFilter, fn func(models.Email) error) error
+	ListEmailIDs(schemaName string, fromID, toID uint32) ([]uint32, error)
+	GetSearchConfig(schemaName string) (models.SearchConfig, error)
+	StartSearchReindex(schemaName, config string) (uint32, error)
+	ReindexSearchBatch(schemaName, config string, afterID uint32, limit int) (uint32, int, error)
+	SwapSearchIndex(schemaName string) error
+	ListEmailsWithoutEntities(schemaName string, limit int) ([]models.Email, error)
+	SaveEmailEntities(schemaName string, emailIDs []uint32, entities []models.EmailEntity) error
+	ResetEntities(schemaName string) error
+	BumpDataVersion(schemaName string) error
+	ListSavedSearches(schemaName string) ([]models.SavedSearch, error)
+	MatchSavedSearch(schemaName string, query models.SavedQuery) ([]uint32, error)
+	SaveSavedSearchMatches(schemaName string, searchID, runID int64, emailIDs []uint32, matchedAt time.Time, alert bool) ([]uint32, error)
+	ListPendingAlerts(schemaName string, searchID int64) ([]uint32, error)
+	MarkAlertsSent(schemaName string, searchID int64, emailIDs []uint32) error
+	StreamAnnotations(schemaName string, fn func(models.Annotation) error) error
 	CreateSchemaIfNotExist(schemaName string) error
+	NewMigrator(schemaName string) (*Migrator, error)
+	RegisterCollection(name, description string) error
+	ListCollections() ([]models.Collection, error)
 	IsSchemaCreated(schemaName string) (bool, error)
 	Open() (*sql.DB, error)
 	OpenWithPool(maxOpenConns, maxIdleConns int) (*sql.DB, error)
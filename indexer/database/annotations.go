@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"indexer/models"
+)
+
+// streamAnnotations reads the annotations of the schema ordered by email and id and calls fn for each one
+// the reading stops at the first error returned by fn
+func streamAnnotations(db *sql.DB, driver, schemaName string, fn func(models.Annotation) error) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, email_id, author, note, start_offset, end_offset, created_at, updated_at
+		FROM %s
+		ORDER BY email_id, id;
+	`, schemaTable(driver, schemaName, "email_annotations")))
+	if err != nil {
+		return fmt.Errorf("failed to query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var annotation models.Annotation
+		var start, end sql.NullInt64
+		if err := rows.Scan(&annotation.ID, &annotation.EmailID, &annotation.Author, &annotation.Note, &start, &end, &annotation.CreatedAt, &annotation.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to scan annotation: %w", err)
+		}
+
+		if start.Valid && end.Valid {
+			startOffset, endOffset := int(start.Int64), int(end.Int64)
+			annotation.Start, annotation.End = &startOffset, &endOffset
+		}
+
+		if err := fn(annotation); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
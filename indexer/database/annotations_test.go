@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStreamAnnotations(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	emails := []models.Email{
+		{ID: 1, Date: time.Now().UTC(), Subject: "Libya", Content: "the embassy is running"},
+		{ID: 2, Date: time.Now().UTC(), Subject: "Benghazi", Content: "call me"},
+	}
+	_, err := conn.SendMails(DBSchemaNameTest, emails)
+	assert.NoError(t, err)
+
+	table := sqliteTable(DBSchemaNameTest, "email_annotations")
+	now := time.Now().UTC()
+	_, err = conn.DB.Exec(`INSERT INTO `+table+` (email_id, author, note, start_offset, end_offset, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $6)`, 2, "analyst", "who called?", 0, 4, now)
+	assert.NoError(t, err)
+	_, err = conn.DB.Exec(`INSERT INTO `+table+` (email_id, author, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $4)`, 1, "reviewer", "check the date", now)
+	assert.NoError(t, err)
+
+	annotations := make([]models.Annotation, 0)
+	err = conn.StreamAnnotations(DBSchemaNameTest, func(annotation models.Annotation) error {
+		annotations = append(annotations, annotation)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, annotations, 2)
+	assert.Equal(t, uint32(1), annotations[0].EmailID)
+	assert.Nil(t, annotations[0].Start)
+	assert.Equal(t, "who called?", annotations[1].Note)
+	assert.Equal(t, 0, *annotations[1].Start)
+	assert.Equal(t, 4, *annotations[1].End)
+}
@@ -4,10 +4,11 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"indexer/models"
 
-	_ "github.com/lib/pq" // PostgreSQL driver compatible with CockroachDB
+	"github.com/lib/pq" // PostgreSQL driver compatible with CockroachDB
 )
 
 // Connection holds the database configuration
@@ -166,6 +167,159 @@ func (c *Connection) SendMails(schemaName string, emails []models.Email) (int64,
 	return rowsInserted, nil
 }
 
+// SendDeadLetters stores the emails rejected by the pipeline in the dead_letters table
+func (c *Connection) SendDeadLetters(schemaName string, letters []models.DeadLetter) error {
+	return insertDeadLetters(c.DB, DriverCockroach, schemaName, letters)
+}
+
+// ListDeadLetters reads the dead letters that match the filter ordered by id
+func (c *Connection) ListDeadLetters(schemaName string, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	return listDeadLetters(c.DB, DriverCockroach, schemaName, filter)
+}
+
+// MarkDeadLettersReplayed sets the date of the replay of the dead letters
+func (c *Connection) MarkDeadLettersReplayed(schemaName string, ids []int64) error {
+	return markDeadLettersReplayed(c.DB, DriverCockroach, schemaName, ids)
+}
+
+// CreateIndexRun stores a new run of the indexer and sets its id
+func (c *Connection) CreateIndexRun(schemaName string, run *models.IndexRun) error {
+	return createIndexRun(c.DB, DriverCockroach, schemaName, run)
+}
+
+// UpdateIndexRun updates the state of a run of the indexer
+func (c *Connection) UpdateIndexRun(schemaName string, run models.IndexRun) error {
+	return updateIndexRun(c.DB, DriverCockroach, schemaName, run)
+}
+
+// ListIndexRuns reads the last runs of the indexer ordered from the newest
+func (c *Connection) ListIndexRuns(schemaName string, limit int) ([]models.IndexRun, error) {
+	return listIndexRuns(c.DB, DriverCockroach, schemaName, limit)
+}
+
+// GetIndexRun reads a run of the indexer by id
+func (c *Connection) GetIndexRun(schemaName string, id int64) (*models.IndexRun, error) {
+	return getIndexRun(c.DB, DriverCockroach, schemaName, id)
+}
+
+// SavePageResults stores the state of the pages indexed by a run
+func (c *Connection) SavePageResults(schemaName string, runID int64, results []models.PageResult) error {
+	return savePageResults(c.DB, DriverCockroach, schemaName, runID, results)
+}
+
+// LoadPageResults reads the state of the pages, the key is the page number
+func (c *Connection) LoadPageResults(schemaName string) (map[string]models.PageResult, error) {
+	return loadPageResults(c.DB, DriverCockroach, schemaName)
+}
+
+// StreamEmails reads the emails that match the filter ordered by id
+// fn is called once per email, if it returns an error the reading stops
+func (c *Connection) StreamEmails(schemaName string, filter models.EmailFilter, fn func(models.Email) error) error {
+	if err := ValidateDBConnection(c.DB); err != nil {
+		return err
+	}
+
+	query, valueArgs, err := c.createSelectQuery(schemaName, filter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.DB.Query(query, valueArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to query emails: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.Email
+		if err := rows.Scan(&e.ID, &e.Date, &e.Subject, &e.From, &e.To, &e.Content); err != nil {
+			return fmt.Errorf("failed to scan email: %w", err)
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ListEmailIDs reads the sorted ids of the emails between fromID and toID
+func (c *Connection) ListEmailIDs(schemaName string, fromID, toID uint32) ([]uint32, error) {
+	return listEmailIDs(c.DB, DriverCockroach, schemaName, fromID, toID)
+}
+
+// GetSearchConfig reads the text search configuration of the search table
+func (c *Connection) GetSearchConfig(schemaName string) (models.SearchConfig, error) {
+	return getSearchConfig(c.DB, DriverCockroach, schemaName)
+}
+
+// StartSearchReindex creates or resumes the new search table and returns the last id indexed
+func (c *Connection) StartSearchReindex(schemaName, config string) (uint32, error) {
+	return startSearchReindex(c.DB, DriverCockroach, schemaName, config)
+}
+
+// ReindexSearchBatch computes the search vectors of the emails after afterID in the new search table
+func (c *Connection) ReindexSearchBatch(schemaName, config string, afterID uint32, limit int) (uint32, int, error) {
+	return reindexSearchBatch(c.DB, DriverCockroach, schemaName, config, afterID, limit)
+}
+
+// SwapSearchIndex replaces the search table with the new one
+func (c *Connection) SwapSearchIndex(schemaName string) error {
+	return swapSearchIndex(c.DB, DriverCockroach, schemaName)
+}
+
+// ListEmailsWithoutEntities reads the first emails not processed by the entity extraction
+func (c *Connection) ListEmailsWithoutEntities(schemaName string, limit int) ([]models.Email, error) {
+	return listEmailsWithoutEntities(c.DB, DriverCockroach, schemaName, limit)
+}
+
+// SaveEmailEntities replaces the entities of the emails and marks them as processed
+func (c *Connection) SaveEmailEntities(schemaName string, emailIDs []uint32, entities []models.EmailEntity) error {
+	return saveEmailEntities(c.DB, DriverCockroach, schemaName, emailIDs, entities)
+}
+
+// ResetEntities deletes the entities and the extractions to extract them again
+func (c *Connection) ResetEntities(schemaName string) error {
+	return resetEntities(c.DB, DriverCockroach, schemaName)
+}
+
+// BumpDataVersion increments the version of the searchable data, the API invalidates its cache when it changes
+func (c *Connection) BumpDataVersion(schemaName string) error {
+	return bumpDataVersion(c.DB, DriverCockroach, schemaName)
+}
+
+// ListSavedSearches reads the searches saved in the API ordered by id
+func (c *Connection) ListSavedSearches(schemaName string) ([]models.SavedSearch, error) {
+	return listSavedSearches(c.DB, DriverCockroach, schemaName)
+}
+
+// MatchSavedSearch returns the ids of the emails that match the saved query
+func (c *Connection) MatchSavedSearch(schemaName string, query models.SavedQuery) ([]uint32, error) {
+	return matchSavedSearch(c.DB, DriverCockroach, schemaName, query)
+}
+
+// SaveSavedSearchMatches records the emails not matched before by the saved search and returns them
+// alert: the new matches are pending alerts until MarkAlertsSent
+func (c *Connection) SaveSavedSearchMatches(schemaName string, searchID, runID int64, emailIDs []uint32, matchedAt time.Time, alert bool) ([]uint32, error) {
+	return saveSavedSearchMatches(c.DB, DriverCockroach, schemaName, searchID, runID, emailIDs, matchedAt, alert)
+}
+
+// ListPendingAlerts returns the ids of the emails matched by the saved search that wait for their alert
+func (c *Connection) ListPendingAlerts(schemaName string, searchID int64) ([]uint32, error) {
+	return listPendingAlerts(c.DB, DriverCockroach, schemaName, searchID)
+}
+
+// MarkAlertsSent removes the matches of the saved search from the pending alerts
+func (c *Connection) MarkAlertsSent(schemaName string, searchID int64, emailIDs []uint32) error {
+	return markAlertsSent(c.DB, DriverCockroach, schemaName, searchID, emailIDs)
+}
+
+// StreamAnnotations reads the annotations ordered by email and id and calls fn for each one
+func (c *Connection) StreamAnnotations(schemaName string, fn func(models.Annotation) error) error {
+	return streamAnnotations(c.DB, DriverCockroach, schemaName, fn)
+}
+
 // Ping checks if the database is reachable
 func (c *Connection) Ping() error {
 	return Ping(c.DB)
@@ -194,50 +348,34 @@ func (c *Connection) BuildConnectionString() string {
 	)
 }
 
-// CreateSchemaIfNotExist creates the schema if it does not exist
-// validates tableName
+// CreateSchemaIfNotExist creates the schema if it does not exist,
+// applies the pending migrations and registers it as a collection
 func (c *Connection) CreateSchemaIfNotExist(schemaName string) error {
-
-	if err := ValidateIsSafeString(schemaName); err != nil {
-		return fmt.Errorf("invalid schema name: %s", schemaName)
+	migrator, err := c.NewMigrator(schemaName)
+	if err != nil {
+		return err
 	}
 
-	_, err := c.DB.Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s";`, schemaName))
-	if err != nil {
-		return fmt.Errorf("error creating schema: %w", err)
-	}
-
-	queries := []string{
-		fmt.Sprintf(`
-					CREATE TABLE IF NOT EXISTS "%s".emails (
-							id INT PRIMARY KEY,
-							date TIMESTAMP WITH TIME ZONE NOT NULL,
-							subject TEXT DEFAULT '',
-							"from" TEXT DEFAULT '',
-							"to" TEXT DEFAULT '',
-							content TEXT DEFAULT ''
-					);
-			`, schemaName),
-		fmt.Sprintf(`
-					CREATE TABLE IF NOT EXISTS "%s".emails_search (
-							id INT PRIMARY KEY,
-							search_vector TSVECTOR,
-							FOREIGN KEY (id) REFERENCES "%s".emails(id)
-					);
-			`, schemaName, schemaName),
-		fmt.Sprintf(`CREATE INVERTED INDEX IF NOT EXISTS idx_emails_search_vector
-									ON "%s".emails_search (search_vector);`, schemaName),
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_emails_date
-									ON "%s".emails (date);`, schemaName),
-	}
-
-	for _, q := range queries {
-		if _, err := c.DB.Exec(q); err != nil {
-			return fmt.Errorf("error executing query: %w", err)
-		}
+	if _, err := migrator.Up(0); err != nil {
+		return fmt.Errorf("error migrating schema: %w", err)
 	}
 
-	return nil
+	return c.RegisterCollection(schemaName, "")
+}
+
+// NewMigrator returns the migrator of the schema
+func (c *Connection) NewMigrator(schemaName string) (*Migrator, error) {
+	return NewMigrator(c.DB, DriverCockroach, schemaName)
+}
+
+// RegisterCollection adds the schema to the registry of collections
+func (c *Connection) RegisterCollection(name, description string) error {
+	return registerCollection(c.DB, DriverCockroach, name, description)
+}
+
+// ListCollections returns the collections of the registry
+func (c *Connection) ListCollections() ([]models.Collection, error) {
+	return listCollections(c.DB, DriverCockroach)
 }
 
 func (c *Connection) IsSchemaCreated(schemaName string) (bool, error) {
@@ -309,7 +447,8 @@ func (c *Connection) createSearchVectorQuery(schemaName string, emails []models.
 		to := n + 3
 		content := n + 4
 
-		searchVectorValue := fmt.Sprintf("($%d, to_tsvector('english', $%d || ' ' || $%d || ' ' || $%d || ' ' || $%d))", id, subject, from, to, content)
+		// the config is read from search_config, it is changed by reindex-search
+		searchVectorValue := fmt.Sprintf(`($%d, to_tsvector((SELECT config FROM "%s".search_config WHERE id = 1)::REGCONFIG, $%d || ' ' || $%d || ' ' || $%d || ' ' || $%d))`, id, schemaName, subject, from, to, content)
 
 		valuesFlags = append(valuesFlags, searchVectorValue)
 		valueArgs = append(valueArgs, e.ID, e.Subject, e.From, e.To, e.Content)
@@ -324,3 +463,55 @@ func (c *Connection) createSearchVectorQuery(schemaName string, emails []models.
 
 	return query, valueArgs, nil
 }
+
+// createSelectQuery creates the query to read the emails that match the filter
+func (c *Connection) createSelectQuery(schemaName string, filter models.EmailFilter) (query string, valueArgs []any, err error) {
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return "", nil, err
+	}
+
+	conditions := make([]string, 0, 5)
+	valueArgs = make([]any, 0, 5)
+	addCondition := func(condition string, value any) {
+		valueArgs = append(valueArgs, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(valueArgs)))
+	}
+
+	if len(filter.IDs) > 0 {
+		ids := make([]int64, 0, len(filter.IDs))
+		for _, id := range filter.IDs {
+			ids = append(ids, int64(id))
+		}
+		addCondition("id = ANY($%d)", pq.Array(ids))
+	}
+
+	if filter.FromID > 0 {
+		addCondition("id >= $%d", filter.FromID)
+	}
+
+	if filter.ToID > 0 {
+		addCondition("id <= $%d", filter.ToID)
+	}
+
+	if filter.DateFrom != nil {
+		addCondition("date >= $%d", *filter.DateFrom)
+	}
+
+	if filter.DateTo != nil {
+		addCondition("date <= $%d", *filter.DateTo)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query = fmt.Sprintf(`
+		SELECT id, date, COALESCE(subject, ''), COALESCE("from", ''), COALESCE("to", ''), COALESCE(content, '')
+		FROM "%s".emails
+		%s
+		ORDER BY id;
+	`, schemaName, where)
+
+	return query, valueArgs, nil
+}
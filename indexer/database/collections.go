@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"indexer/models"
+)
+
+// collectionsTable returns the name of the registry of collections
+// the registry is shared by all the schemas, in cockroach it is stored in the public schema
+func collectionsTable(driver string) string {
+	if driver == DriverSQLite {
+		return "collections"
+	}
+
+	return "public.collections"
+}
+
+// createCollectionsTable creates the registry of collections if it doesn't exist
+func createCollectionsTable(db *sql.DB, driver string) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		name TEXT PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL
+	);`, collectionsTable(driver))
+
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("error creating collections table: %w", err)
+	}
+
+	return nil
+}
+
+// registerCollection adds the collection to the registry
+// if it is registered the description is updated only when it is not empty
+func registerCollection(db *sql.DB, driver, name, description string) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if err := ValidateIsSafeString(name); err != nil {
+		return fmt.Errorf("invalid collection name: %s", name)
+	}
+
+	if err := createCollectionsTable(db, driver); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (name, description, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET description = excluded.description
+		WHERE excluded.description <> '';
+	`, collectionsTable(driver))
+
+	if _, err := db.Exec(query, name, description, time.Now().UTC()); err != nil {
+		return fmt.Errorf("error registering collection %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// listCollections returns the collections of the registry ordered by name
+func listCollections(db *sql.DB, driver string) ([]models.Collection, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := createCollectionsTable(db, driver); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT name, description, created_at FROM %s ORDER BY name;`, collectionsTable(driver)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := make([]models.Collection, 0)
+	for rows.Next() {
+		var collection models.Collection
+		if err := rows.Scan(&collection.Name, &collection.Description, &collection.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading collections: %w", err)
+		}
+		collections = append(collections, collection)
+	}
+
+	return collections, rows.Err()
+}
@@ -1,6 +1,12 @@
 package database
 
 const (
-	DBSchemaName     string = "emails_hillary"
+	DBSchemaName     string = "emails_hillary" // Schema of the default collection
 	DBSchemaNameTest string = "emails_hillary_test"
 )
+
+// Database drivers supported
+const (
+	DriverCockroach string = "cockroach"
+	DriverSQLite    string = "sqlite"
+)
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// bumpDataVersion increments the version of the searchable data of the schema
+// the API invalidates its cache of searches of the collection when the version changes
+func bumpDataVersion(db *sql.DB, driver, schemaName string) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET version = version + 1, updated_at = $1 WHERE id = 1;`, schemaTable(driver, schemaName, "data_version"))
+	if _, err := db.Exec(query, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to update data version: %w", err)
+	}
+
+	return nil
+}
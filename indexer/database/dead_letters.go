@@ -0,0 +1,157 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"indexer/models"
+)
+
+// deadLettersTable returns the name of the dead_letters table of the schema
+func deadLettersTable(driver, schemaName string) string {
+	return schemaTable(driver, schemaName, "dead_letters")
+}
+
+// insertDeadLetters stores the dead letters in a transaction
+func insertDeadLetters(db *sql.DB, driver, schemaName string, letters []models.DeadLetter) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if len(letters) == 0 {
+		return nil
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO %s (email_id, stage, error, payload, raw, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`, deadLettersTable(driver, schemaName)))
+	if err != nil {
+		return fmt.Errorf("failed to prepare dead letters insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, letter := range letters {
+		if _, err := stmt.Exec(letter.EmailID, letter.Stage, letter.Error, letter.Payload, letter.Raw, letter.CreatedAt); err != nil {
+			return fmt.Errorf("failed to insert dead letter of email %d: %w", letter.EmailID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// listDeadLetters reads the dead letters that match the filter ordered by id
+func listDeadLetters(db *sql.DB, driver, schemaName string, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	conditions := make([]string, 0, 3)
+	valueArgs := make([]any, 0, len(filter.EmailIDs)+1)
+
+	if !filter.IncludeReplayed {
+		conditions = append(conditions, "replayed_at IS NULL")
+	}
+
+	if filter.Stage != "" {
+		valueArgs = append(valueArgs, filter.Stage)
+		conditions = append(conditions, fmt.Sprintf("stage = $%d", len(valueArgs)))
+	}
+
+	if len(filter.EmailIDs) > 0 {
+		placeholders := make([]string, 0, len(filter.EmailIDs))
+		for _, id := range filter.EmailIDs {
+			valueArgs = append(valueArgs, id)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(valueArgs)))
+		}
+		conditions = append(conditions, fmt.Sprintf("email_id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := ""
+	if filter.Limit > 0 {
+		limit = fmt.Sprintf("LIMIT %d", filter.Limit)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, email_id, stage, error, payload, raw, created_at, replayed_at
+		FROM %s
+		%s
+		ORDER BY id
+		%s;
+	`, deadLettersTable(driver, schemaName), where, limit), valueArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := make([]models.DeadLetter, 0)
+	for rows.Next() {
+		var letter models.DeadLetter
+		var replayedAt sql.NullTime
+		if err := rows.Scan(&letter.ID, &letter.EmailID, &letter.Stage, &letter.Error, &letter.Payload, &letter.Raw, &letter.CreatedAt, &replayedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+
+		if replayedAt.Valid {
+			letter.ReplayedAt = &replayedAt.Time
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, rows.Err()
+}
+
+// markDeadLettersReplayed sets the date of the replay of the dead letters
+func markDeadLettersReplayed(db *sql.DB, driver, schemaName string, ids []int64) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	valueArgs := []any{time.Now().UTC()}
+	placeholders := make([]string, 0, len(ids))
+	for _, id := range ids {
+		valueArgs = append(valueArgs, id)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(valueArgs)))
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET replayed_at = $1 WHERE id IN (%s);`, deadLettersTable(driver, schemaName), strings.Join(placeholders, ","))
+	if _, err := db.Exec(query, valueArgs...); err != nil {
+		return fmt.Errorf("failed to mark dead letters as replayed: %w", err)
+	}
+
+	return nil
+}
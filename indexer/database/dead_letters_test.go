@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteDeadLetters(t *testing.T) {
+	conn := getSQLiteConn(t)
+	email := models.Email{ID: 7, Date: time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC), Subject: "Libya"}
+
+	letters := []models.DeadLetter{
+		models.NewEmailDeadLetter(models.StageInsert, email, errors.New("value too long")),
+		models.NewResultDeadLetter(models.EmailResult{Error: errors.New("invalid id"), Stage: models.StageScrape, Raw: "<tr><td>x</td></tr>"}),
+	}
+	assert.NoError(t, conn.SendDeadLetters(DBSchemaNameTest, letters))
+
+	t.Run("Must list the pending dead letters", func(t *testing.T) {
+		pending, err := conn.ListDeadLetters(DBSchemaNameTest, models.DeadLetterFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, pending, 2)
+
+		replayed, err := pending[0].Email()
+		assert.NoError(t, err)
+		assert.Equal(t, email, *replayed)
+		assert.Equal(t, "<tr><td>x</td></tr>", pending[1].Raw)
+		assert.Nil(t, pending[1].ReplayedAt)
+	})
+
+	t.Run("Must filter by stage and email", func(t *testing.T) {
+		scrape, err := conn.ListDeadLetters(DBSchemaNameTest, models.DeadLetterFilter{Stage: models.StageScrape})
+		assert.NoError(t, err)
+		assert.Len(t, scrape, 1)
+
+		byEmail, err := conn.ListDeadLetters(DBSchemaNameTest, models.DeadLetterFilter{EmailIDs: []uint32{7}})
+		assert.NoError(t, err)
+		assert.Len(t, byEmail, 1)
+		assert.Equal(t, "value too long", byEmail[0].Error)
+	})
+
+	t.Run("Must hide the replayed dead letters", func(t *testing.T) {
+		pending, err := conn.ListDeadLetters(DBSchemaNameTest, models.DeadLetterFilter{Stage: models.StageInsert})
+		assert.NoError(t, err)
+		assert.NoError(t, conn.MarkDeadLettersReplayed(DBSchemaNameTest, []int64{pending[0].ID}))
+
+		pending, err = conn.ListDeadLetters(DBSchemaNameTest, models.DeadLetterFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, pending, 1)
+
+		all, err := conn.ListDeadLetters(DBSchemaNameTest, models.DeadLetterFilter{IncludeReplayed: true})
+		assert.NoError(t, err)
+		assert.Len(t, all, 2)
+		assert.NotNil(t, all[0].ReplayedAt)
+	})
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"indexer/models"
+)
+
+// listEmailsWithoutEntities reads the first emails ordered by id that were not processed by the extraction
+func listEmailsWithoutEntities(db *sql.DB, driver, schemaName string, limit int) ([]models.Email, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT e.id, e.date, e.subject, e."from", e."to", e.content
+		FROM %s e
+		WHERE NOT EXISTS (SELECT 1 FROM %s x WHERE x.email_id = e.id)
+		ORDER BY e.id
+		LIMIT $1;
+	`, schemaTable(driver, schemaName, "emails"), schemaTable(driver, schemaName, "entity_extractions"))
+
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query emails without entities: %w", err)
+	}
+	defer rows.Close()
+
+	emails := make([]models.Email, 0)
+	for rows.Next() {
+		var e models.Email
+		if err := rows.Scan(&e.ID, &e.Date, &e.Subject, &e.From, &e.To, &e.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		emails = append(emails, e)
+	}
+
+	return emails, rows.Err()
+}
+
+// saveEmailEntities replaces the entities of the emails and marks them as processed in a transaction
+func saveEmailEntities(db *sql.DB, driver, schemaName string, emailIDs []uint32, entities []models.EmailEntity) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if len(emailIDs) == 0 {
+		return nil
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	entitiesTable := schemaTable(driver, schemaName, "email_entities")
+	extractionsTable := schemaTable(driver, schemaName, "entity_extractions")
+
+	placeholders := make([]string, 0, len(emailIDs))
+	ids := make([]any, 0, len(emailIDs))
+	for i, id := range emailIDs {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		ids = append(ids, id)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE email_id IN (%s);`, entitiesTable, strings.Join(placeholders, ",")), ids...); err != nil {
+		return fmt.Errorf("failed to delete entities: %w", err)
+	}
+
+	insert, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO %s (email_id, entity, type, field, start_offset, end_offset)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`, entitiesTable))
+	if err != nil {
+		return fmt.Errorf("failed to prepare entities insert: %w", err)
+	}
+	defer insert.Close()
+
+	for _, entity := range entities {
+		if _, err := insert.Exec(entity.EmailID, entity.Entity, entity.Type, entity.Field, entity.Start, entity.End); err != nil {
+			return fmt.Errorf("failed to insert entity of email %d: %w", entity.EmailID, err)
+		}
+	}
+
+	extracted, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO %s (email_id, extracted_at) VALUES ($1, $2)
+		ON CONFLICT (email_id) DO UPDATE SET extracted_at = excluded.extracted_at;
+	`, extractionsTable))
+	if err != nil {
+		return fmt.Errorf("failed to prepare extractions insert: %w", err)
+	}
+	defer extracted.Close()
+
+	now := time.Now().UTC()
+	for _, id := range emailIDs {
+		if _, err := extracted.Exec(id, now); err != nil {
+			return fmt.Errorf("failed to mark email %d as extracted: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// resetEntities deletes the entities and the extractions of the schema to extract them again
+func resetEntities(db *sql.DB, driver, schemaName string) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"email_entities", "entity_extractions"} {
+		if _, err := db.Exec(fmt.Sprintf(`DELETE FROM %s;`, schemaTable(driver, schemaName, table))); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
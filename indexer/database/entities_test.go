@@ -0,0 +1,45 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteEmailEntities(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	emails := []models.Email{
+		{ID: 1, Date: time.Now().UTC(), Subject: "Libya", Content: "content"},
+		{ID: 2, Date: time.Now().UTC(), Subject: "no entities", Content: "content"},
+	}
+	_, err := conn.SendMails(DBSchemaNameTest, emails)
+	assert.NoError(t, err)
+
+	pending, err := conn.ListEmailsWithoutEntities(DBSchemaNameTest, 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	entity := models.EmailEntity{EmailID: 1, Entity: "Libya", Type: models.EntityTypePlace, Field: models.EntityFieldSubject, Start: 0, End: 5}
+	assert.NoError(t, conn.SaveEmailEntities(DBSchemaNameTest, []uint32{1, 2}, []models.EmailEntity{entity}))
+
+	// the emails without entities are processed too
+	pending, err = conn.ListEmailsWithoutEntities(DBSchemaNameTest, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// saving again replaces the entities of the email
+	assert.NoError(t, conn.SaveEmailEntities(DBSchemaNameTest, []uint32{1}, []models.EmailEntity{entity}))
+	var total int
+	assert.NoError(t, conn.DB.QueryRow(`SELECT COUNT(*) FROM `+sqliteTable(DBSchemaNameTest, "email_entities")).Scan(&total))
+	assert.Equal(t, 1, total)
+
+	assert.NoError(t, conn.ResetEntities(DBSchemaNameTest))
+	pending, err = conn.ListEmailsWithoutEntities(DBSchemaNameTest, 1)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, uint32(1), pending[0].ID)
+}
@@ -3,6 +3,9 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
+
 	"indexer/config"
 )
 
@@ -22,9 +25,20 @@ func Close(db *sql.DB) error {
 	return db.Close()
 }
 
-// InitDb initializes the database connection
-func InitDb(cfg config.Config) (*Connection, error) {
-	db, err := NewConnection(cfg.DBConfig.Host, cfg.DBConfig.Name, cfg.DBConfig.User, cfg.DBConfig.Password, cfg.DBConfig.Port, cfg.DBConfig.SSL)
+// InitDb initializes the database connection of the driver configured
+func InitDb(cfg config.Config) (IDatabase, error) {
+	var db IDatabase
+	var err error
+
+	switch cfg.DBConfig.Driver {
+	case DriverSQLite:
+		db, err = NewSQLiteConnection(cfg.DBConfig.Path)
+	case DriverCockroach:
+		db, err = NewConnection(cfg.DBConfig.Host, cfg.DBConfig.Name, cfg.DBConfig.User, cfg.DBConfig.Password, cfg.DBConfig.Port, cfg.DBConfig.SSL)
+	default:
+		return nil, fmt.Errorf("database driver not supported: %s", cfg.DBConfig.Driver)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -41,3 +55,18 @@ func InitDb(cfg config.Config) (*Connection, error) {
 
 	return db, nil
 }
+
+// sqliteTable returns the quoted name of a table of the schema in SQLite
+func sqliteTable(schemaName, table string) string {
+	return fmt.Sprintf(`"%s_%s"`, schemaName, table)
+}
+
+// ensureFileDir creates the directory of the file if it doesn't exist
+func ensureFileDir(path string) error {
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return os.MkdirAll(dir, 0755)
+	}
+
+	return nil
+}
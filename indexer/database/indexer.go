@@ -1,29 +1,50 @@
 package database
 
 import (
+	"errors"
+	"time"
+
+	"indexer/metrics"
 	"indexer/models"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Indexer stores the emails in the schema of a collection
 type Indexer struct {
-	db IDatabase
+	db         IDatabase
+	schemaName string
 }
 
+// NewIndexer creates an Indexer of the default collection
 func NewIndexer(db IDatabase) *Indexer {
-	return &Indexer{db: db}
+	return &Indexer{db: db, schemaName: DBSchemaName}
+}
+
+// WithCollection returns a copy of the Indexer that stores the emails in the collection
+func (i *Indexer) WithCollection(name string) *Indexer {
+	return &Indexer{db: i.db, schemaName: name}
 }
 
 // IndexEmail indexes emails from a mailsCh channel
 // mailsCh: channel of ScraperResult
 // batchSize: number of emails to index at once
-func (i *Indexer) IndexEmail(mailsCh <-chan models.EmailResult, batchSize int) error {
+// the results with error are stored in the dead letters with the batches
+// a batch that fails because the database is not reachable doesn't stop the channel from being read,
+// the next batches are tried and the errors of the failed batches are returned at the end
+// returns the totals of the emails by stage
+func (i *Indexer) IndexEmail(mailsCh <-chan models.EmailResult, batchSize int) (models.IndexStats, error) {
+	var errs []error
 	batch := make([]models.Email, 0, batchSize)
-	totalInserted := 0
-	totalError := 0
+	letters := make([]models.DeadLetter, 0)
+	stats := models.IndexStats{}
 	for result := range mailsCh {
+		stats.Received++
 		if result.Error != nil {
-			totalError++
+			letter := models.NewResultDeadLetter(result)
+			stats.Errors++
+			stats.AddDeadLetters(letter)
+			letters = append(letters, letter)
 			continue
 		}
 
@@ -34,26 +55,119 @@ func (i *Indexer) IndexEmail(mailsCh <-chan models.EmailResult, batchSize int) e
 		batch = append(batch, *result.Email)
 
 		if len(batch) == batchSize {
-			if inserted, err := i.db.SendMails(DBSchemaName, batch); err != nil {
+			if err := i.sendBatch(batch, &stats); err != nil {
 				log.Error(err)
-			} else {
-				totalInserted += int(inserted)
-				totalError += len(batch) - int(inserted)
+				errs = append(errs, err)
 			}
 
 			batch = batch[:0]
+			letters = i.sendDeadLetters(letters)
 		}
 	}
 
+	i.sendDeadLetters(letters)
+
 	if len(batch) > 0 {
-		if inserted, err := i.db.SendMails(DBSchemaName, batch); err != nil {
-			return err
-		} else {
-			totalInserted += int(inserted)
-			totalError += len(batch) - int(inserted)
+		if err := i.sendBatch(batch, &stats); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	log.Info("Batch inserted: ", totalInserted, " Batch total errors: ", totalError)
+	log.Info("Batch inserted: ", stats.Inserted, " Batch total errors: ", stats.Errors)
+	return stats, errors.Join(errs...)
+}
+
+// sendBatch sends a batch to the database and adds the totals to the stats
+func (i *Indexer) sendBatch(batch []models.Email, stats *models.IndexStats) error {
+	inserted, letters, err := i.sendMails(batch)
+	if err != nil {
+		stats.Errors += len(batch)
+		return err
+	}
+
+	// the emails not inserted are duplicated or rejected, only the rejected ones are errors
+	stats.Inserted += int(inserted)
+	stats.Duplicated += len(batch) - int(inserted) - len(letters)
+	stats.Errors += len(letters)
+	stats.AddDeadLetters(letters...)
 	return nil
 }
+
+// sendMails sends a batch to the database
+// a failed batch is bisected to commit the good emails, the emails that fail alone are sent to the dead letters
+// returns an error only if the database is not reachable, in that case the batch is not the cause
+// returns the emails inserted and the dead letters of the emails rejected
+func (i *Indexer) sendMails(batch []models.Email) (int64, []models.DeadLetter, error) {
+	inserted, err := i.insertBatch(batch)
+	if err == nil {
+		return inserted, nil, nil
+	}
+
+	if pingErr := i.db.Ping(); pingErr != nil {
+		return 0, nil, err
+	}
+
+	log.Warn("Batch of ", len(batch), " emails failed, bisecting to find the rejected emails: ", err)
+
+	letters := make([]models.DeadLetter, 0)
+	inserted = i.bisect(batch, err, &letters)
+
+	i.sendDeadLetters(letters)
+	log.Warn("Emails rejected by the database: ", len(letters), " of ", len(batch))
+	return inserted, letters, nil
+}
+
+// sendDeadLetters stores the dead letters and records the metrics by stage
+// returns the slice empty to be reused
+func (i *Indexer) sendDeadLetters(letters []models.DeadLetter) []models.DeadLetter {
+	if len(letters) == 0 {
+		return letters
+	}
+
+	if err := i.db.SendDeadLetters(i.schemaName, letters); err != nil {
+		log.Error("Error storing the dead letters: ", err)
+	}
+
+	for _, letter := range letters {
+		metrics.DeadLetters.WithLabelValues(letter.Stage).Inc()
+	}
+
+	return letters[:0]
+}
+
+// bisect splits a failed batch in halves and inserts each one, the halves that fail are split again
+// err: error of the batch, it is the error of the dead letter when the batch has a single email
+// letters: the emails rejected are appended
+// returns the emails inserted
+func (i *Indexer) bisect(batch []models.Email, err error, letters *[]models.DeadLetter) int64 {
+	if len(batch) == 1 {
+		*letters = append(*letters, models.NewEmailDeadLetter(models.StageInsert, batch[0], err))
+		return 0
+	}
+
+	var inserted int64
+	middle := len(batch) / 2
+	for _, half := range [][]models.Email{batch[:middle], batch[middle:]} {
+		n, err := i.insertBatch(half)
+		if err != nil {
+			n = i.bisect(half, err, letters)
+		}
+		inserted += n
+	}
+
+	return inserted
+}
+
+// insertBatch sends a batch to the database and records the metrics of the insert
+func (i *Indexer) insertBatch(batch []models.Email) (int64, error) {
+	start := time.Now()
+	inserted, err := i.db.SendMails(i.schemaName, batch)
+	metrics.ObserveSendMails(start, err)
+	if err != nil {
+		return inserted, err
+	}
+
+	metrics.RowsInserted.Add(float64(inserted))
+	metrics.RowsConflicts.Add(float64(int64(len(batch)) - inserted))
+	return inserted, nil
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rejectingConnection is a SQLite connection that fails the batches with the rejected ids
+type rejectingConnection struct {
+	*SQLiteConnection
+	rejected map[uint32]bool
+}
+
+func (c *rejectingConnection) SendMails(schemaName string, emails []models.Email) (int64, error) {
+	for _, e := range emails {
+		if c.rejected[e.ID] {
+			return 0, fmt.Errorf("invalid email %d", e.ID)
+		}
+	}
+
+	return c.SQLiteConnection.SendMails(schemaName, emails)
+}
+
+func TestIndexEmailBisectsFailedBatches(t *testing.T) {
+	conn := &rejectingConnection{SQLiteConnection: getSQLiteConn(t), rejected: map[uint32]bool{13: true, 42: true}}
+	indexer := NewIndexer(conn).WithCollection(DBSchemaNameTest)
+
+	mailsCh := make(chan models.EmailResult)
+	go func() {
+		defer close(mailsCh)
+		for id := uint32(1); id <= 150; id++ {
+			mailsCh <- models.EmailResult{Email: &models.Email{ID: id, Date: time.Now(), Subject: "subject"}}
+		}
+		mailsCh <- models.EmailResult{Error: fmt.Errorf("invalid id"), Stage: models.StageScrape, Raw: "<tr><td>x</td></tr>"}
+	}()
+
+	stats, err := indexer.IndexEmail(mailsCh, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 148, stats.Inserted)
+	assert.Equal(t, 3, stats.Errors)
+
+	var total int
+	conn.DB.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s;`, sqliteTable(DBSchemaNameTest, "emails"))).Scan(&total)
+	assert.Equal(t, 148, total)
+
+	rows, err := conn.DB.Query(fmt.Sprintf(`SELECT email_id, stage, error FROM %s ORDER BY email_id, stage;`, deadLettersTable(DriverSQLite, DBSchemaNameTest)))
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	letters := make([]models.DeadLetter, 0)
+	for rows.Next() {
+		var letter models.DeadLetter
+		assert.NoError(t, rows.Scan(&letter.EmailID, &letter.Stage, &letter.Error))
+		letters = append(letters, letter)
+	}
+
+	assert.Equal(t, []models.DeadLetter{
+		{EmailID: 0, Stage: models.StageScrape, Error: "invalid id"},
+		{EmailID: 13, Stage: models.StageInsert, Error: "invalid email 13"},
+		{EmailID: 42, Stage: models.StageInsert, Error: "invalid email 42"},
+	}, letters)
+}
+
+func TestIndexEmailReturnsErrorIfDatabaseIsDown(t *testing.T) {
+	conn := &rejectingConnection{SQLiteConnection: getSQLiteConn(t), rejected: map[uint32]bool{1: true}}
+	indexer := NewIndexer(conn).WithCollection(DBSchemaNameTest)
+	conn.Close()
+
+	mailsCh := make(chan models.EmailResult, 1)
+	mailsCh <- models.EmailResult{Email: &models.Email{ID: 1, Date: time.Now()}}
+	close(mailsCh)
+
+	stats, err := indexer.IndexEmail(mailsCh, 100)
+	assert.Error(t, err)
+	assert.Equal(t, 1, stats.Errors)
+}
+
+func TestIndexEmailReturnsErrorOfTheBatchesBeforeTheLast(t *testing.T) {
+	conn := &rejectingConnection{SQLiteConnection: getSQLiteConn(t), rejected: map[uint32]bool{}}
+	indexer := NewIndexer(conn).WithCollection(DBSchemaNameTest)
+	conn.Close()
+
+	// the batches are full, none of them is sent at the end of the channel
+	mailsCh := make(chan models.EmailResult)
+	go func() {
+		defer close(mailsCh)
+		for id := uint32(1); id <= 4; id++ {
+			mailsCh <- models.EmailResult{Email: &models.Email{ID: id, Date: time.Now()}}
+		}
+	}()
+
+	stats, err := indexer.IndexEmail(mailsCh, 2)
+	assert.Error(t, err)
+	assert.Equal(t, 4, stats.Received)
+	assert.Equal(t, 4, stats.Errors)
+	assert.Equal(t, 0, stats.Inserted)
+}
+
+func TestIndexEmailDoesNotCountDuplicatesAsErrors(t *testing.T) {
+	conn := &rejectingConnection{SQLiteConnection: getSQLiteConn(t), rejected: map[uint32]bool{3: true}}
+	indexer := NewIndexer(conn).WithCollection(DBSchemaNameTest)
+
+	mailsCh := make(chan models.EmailResult)
+	go func() {
+		defer close(mailsCh)
+		for _, id := range []uint32{1, 2, 3, 1, 2} {
+			mailsCh <- models.EmailResult{Email: &models.Email{ID: id, Date: time.Now(), Subject: "subject"}}
+		}
+	}()
+
+	stats, err := indexer.IndexEmail(mailsCh, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Inserted)
+	assert.Equal(t, 2, stats.Duplicated)
+	assert.Equal(t, 1, stats.Errors)
+}
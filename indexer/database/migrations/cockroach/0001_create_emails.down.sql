@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "{{.Schema}}".emails_search;
+
+DROP TABLE IF EXISTS "{{.Schema}}".emails;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".emails (
+    id INT PRIMARY KEY,
+    date TIMESTAMP WITH TIME ZONE NOT NULL,
+    subject TEXT DEFAULT '',
+    "from" TEXT DEFAULT '',
+    "to" TEXT DEFAULT '',
+    content TEXT DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".emails_search (
+    id INT PRIMARY KEY,
+    search_vector TSVECTOR,
+    FOREIGN KEY (id) REFERENCES "{{.Schema}}".emails(id)
+);
+
+CREATE INVERTED INDEX IF NOT EXISTS idx_emails_search_vector
+ON "{{.Schema}}".emails_search (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_emails_date
+ON "{{.Schema}}".emails (date);
@@ -0,0 +1,12 @@
+-- emails rejected by the pipeline, payload is the email as JSON
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".dead_letters (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    email_id INT NOT NULL DEFAULT 0,
+    stage TEXT NOT NULL,
+    error TEXT NOT NULL,
+    payload TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_email_id
+ON "{{.Schema}}".dead_letters (email_id);
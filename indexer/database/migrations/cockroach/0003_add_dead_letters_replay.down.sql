@@ -0,0 +1,2 @@
+ALTER TABLE "{{.Schema}}".dead_letters DROP COLUMN IF EXISTS replayed_at;
+ALTER TABLE "{{.Schema}}".dead_letters DROP COLUMN IF EXISTS raw;
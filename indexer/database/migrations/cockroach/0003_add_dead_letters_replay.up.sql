@@ -0,0 +1,2 @@
+ALTER TABLE "{{.Schema}}".dead_letters ADD COLUMN IF NOT EXISTS raw TEXT NOT NULL DEFAULT '';
+ALTER TABLE "{{.Schema}}".dead_letters ADD COLUMN IF NOT EXISTS replayed_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}".index_page_state;
+DROP TABLE IF EXISTS "{{.Schema}}".index_runs;
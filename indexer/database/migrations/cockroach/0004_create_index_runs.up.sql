@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".index_runs (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    from_page INT NOT NULL,
+    to_page INT NOT NULL,
+    state TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- last state of every page, the page is updated by the run that indexes it
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".index_page_state (
+    page INT PRIMARY KEY,
+    run_id INT8 NOT NULL,
+    state TEXT NOT NULL,
+    total INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
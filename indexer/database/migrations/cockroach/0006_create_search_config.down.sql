@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}".emails_search_next;
+DROP TABLE IF EXISTS "{{.Schema}}".search_config;
@@ -0,0 +1,10 @@
+-- text search configuration of emails_search, next_config is the configuration of a reindex in progress
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".search_config (
+    id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    config TEXT NOT NULL,
+    next_config TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+INSERT INTO "{{.Schema}}".search_config (id, config) VALUES (1, 'english')
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}".entity_extractions;
+DROP TABLE IF EXISTS "{{.Schema}}".email_entities;
@@ -0,0 +1,22 @@
+-- people, organizations and places mentioned in the emails, the offsets are in bytes of the field
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".email_entities (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    email_id INT NOT NULL REFERENCES "{{.Schema}}".emails(id),
+    entity TEXT NOT NULL,
+    type TEXT NOT NULL,
+    field TEXT NOT NULL,
+    start_offset INT NOT NULL,
+    end_offset INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_entities_email_id
+ON "{{.Schema}}".email_entities (email_id);
+
+CREATE INDEX IF NOT EXISTS idx_email_entities_entity
+ON "{{.Schema}}".email_entities (entity, type);
+
+-- emails already processed by the extraction, with or without entities
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".entity_extractions (
+    email_id INT PRIMARY KEY,
+    extracted_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "{{.Schema}}".email_tags;
@@ -0,0 +1,10 @@
+-- labels of the emails added by the users of the API, the indexer never writes this table
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".email_tags (
+    email_id INT NOT NULL REFERENCES "{{.Schema}}".emails(id),
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (email_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_tags_tag
+ON "{{.Schema}}".email_tags (tag);
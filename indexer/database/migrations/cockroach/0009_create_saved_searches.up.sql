@@ -0,0 +1,22 @@
+-- searches saved by the users of the API, query is the QuerySearch as JSON
+-- last_seen_match_id is the last match returned to the owner
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".saved_searches (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    owner TEXT NOT NULL,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    evaluated_at TIMESTAMP WITH TIME ZONE,
+    last_seen_match_id INT8 NOT NULL DEFAULT 0,
+    UNIQUE (owner, name)
+);
+
+-- emails matched by the saved searches, recorded by the indexer after every run
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".saved_search_matches (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    saved_search_id INT8 NOT NULL REFERENCES "{{.Schema}}".saved_searches(id) ON DELETE CASCADE,
+    email_id INT NOT NULL,
+    run_id INT8,
+    matched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (saved_search_id, email_id)
+);
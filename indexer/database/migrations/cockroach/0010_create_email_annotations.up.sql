@@ -0,0 +1,14 @@
+-- notes of the users of the API on an email or on a range of characters of its content, the indexer never writes this table
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".email_annotations (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    email_id INT NOT NULL REFERENCES "{{.Schema}}".emails(id),
+    author TEXT NOT NULL,
+    note TEXT NOT NULL,
+    start_offset INT,
+    end_offset INT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_annotations_email_id
+ON "{{.Schema}}".email_annotations (email_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}".folder_items;
+DROP TABLE IF EXISTS "{{.Schema}}".folders;
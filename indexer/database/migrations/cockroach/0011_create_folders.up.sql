@@ -0,0 +1,20 @@
+-- folders of emails curated by the users of the API, the indexer never writes these tables
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".folders (
+    id INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (name)
+);
+
+-- emails of the folders, position is the order of the email in the folder
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".folder_items (
+    folder_id INT8 NOT NULL REFERENCES "{{.Schema}}".folders(id) ON DELETE CASCADE,
+    email_id INT NOT NULL REFERENCES "{{.Schema}}".emails(id),
+    position INT8 NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (folder_id, email_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_items_email_id
+ON "{{.Schema}}".folder_items (email_id);
@@ -0,0 +1 @@
+ALTER TABLE "{{.Schema}}".index_runs DROP COLUMN pages;
@@ -0,0 +1,2 @@
+-- number of pages of the run, the pages resumed by a run are not contiguous
+ALTER TABLE "{{.Schema}}".index_runs ADD COLUMN pages INT NOT NULL DEFAULT 0;
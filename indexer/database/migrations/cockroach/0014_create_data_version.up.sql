@@ -0,0 +1,10 @@
+-- version of the searchable data, the indexer increments it after the commands that change the emails, their search vectors or their entities
+-- the API polls it to invalidate its cache of searches
+CREATE TABLE IF NOT EXISTS "{{.Schema}}".data_version (
+    id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    version INT8 NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+INSERT INTO "{{.Schema}}".data_version (id, version) VALUES (1, 0)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_emails" (
+    id INTEGER PRIMARY KEY,
+    date TIMESTAMP NOT NULL,
+    subject TEXT DEFAULT '',
+    "from" TEXT DEFAULT '',
+    "to" TEXT DEFAULT '',
+    content TEXT DEFAULT ''
+);
+
+-- the rowid of the search table is the id of the email
+CREATE VIRTUAL TABLE IF NOT EXISTS "{{.Schema}}_emails_search" USING fts5(
+    subject, "from", "to", content,
+    tokenize = 'porter unicode61'
+);
+
+CREATE INDEX IF NOT EXISTS "{{.Schema}}_idx_emails_date"
+ON "{{.Schema}}_emails" (date);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "{{.Schema}}_dead_letters";
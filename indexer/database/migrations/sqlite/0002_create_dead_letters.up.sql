@@ -0,0 +1,12 @@
+-- emails rejected by the pipeline, payload is the email as JSON
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_dead_letters" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email_id INTEGER NOT NULL DEFAULT 0,
+    stage TEXT NOT NULL,
+    error TEXT NOT NULL,
+    payload TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "{{.Schema}}_idx_dead_letters_email_id"
+ON "{{.Schema}}_dead_letters" (email_id);
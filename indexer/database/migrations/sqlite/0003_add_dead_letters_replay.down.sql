@@ -0,0 +1,2 @@
+ALTER TABLE "{{.Schema}}_dead_letters" DROP COLUMN replayed_at;
+ALTER TABLE "{{.Schema}}_dead_letters" DROP COLUMN raw;
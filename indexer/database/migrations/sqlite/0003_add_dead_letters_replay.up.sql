@@ -0,0 +1,2 @@
+ALTER TABLE "{{.Schema}}_dead_letters" ADD COLUMN raw TEXT NOT NULL DEFAULT '';
+ALTER TABLE "{{.Schema}}_dead_letters" ADD COLUMN replayed_at TIMESTAMP;
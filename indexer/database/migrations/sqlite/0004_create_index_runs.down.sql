@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}_index_page_state";
+DROP TABLE IF EXISTS "{{.Schema}}_index_runs";
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_index_runs" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_page INTEGER NOT NULL,
+    to_page INTEGER NOT NULL,
+    state TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+-- last state of every page, the page is updated by the run that indexes it
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_index_page_state" (
+    page INTEGER PRIMARY KEY,
+    run_id INTEGER NOT NULL,
+    state TEXT NOT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL
+);
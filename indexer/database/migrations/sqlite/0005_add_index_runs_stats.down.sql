@@ -0,0 +1,11 @@
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN error_summary;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN dead_letters;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN emails_errors;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN emails_duplicated;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN emails_inserted;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN emails_received;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN pages_with_errors;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN pages_finished;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN parallelism;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN batch_size;
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN pagination;
@@ -0,0 +1,14 @@
+-- parameters of the run
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN pagination INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN batch_size INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN parallelism INT NOT NULL DEFAULT 0;
+
+-- totals by stage, the maps are stored as JSON
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN pages_finished INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN pages_with_errors INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN emails_received INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN emails_inserted INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN emails_duplicated INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN emails_errors INT NOT NULL DEFAULT 0;
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN dead_letters TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE "{{.Schema}}_index_runs" ADD COLUMN error_summary TEXT NOT NULL DEFAULT '{}';
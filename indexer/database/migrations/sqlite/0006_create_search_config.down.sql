@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}_emails_search_next";
+DROP TABLE IF EXISTS "{{.Schema}}_search_config";
@@ -0,0 +1,10 @@
+-- tokenizer of the emails_search table, next_config is the tokenizer of a reindex in progress
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_search_config" (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    config TEXT NOT NULL,
+    next_config TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO "{{.Schema}}_search_config" (id, config) VALUES (1, 'porter unicode61')
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}_entity_extractions";
+DROP TABLE IF EXISTS "{{.Schema}}_email_entities";
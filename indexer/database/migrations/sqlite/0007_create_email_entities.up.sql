@@ -0,0 +1,22 @@
+-- people, organizations and places mentioned in the emails, the offsets are in bytes of the field
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_email_entities" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email_id INTEGER NOT NULL REFERENCES "{{.Schema}}_emails"(id),
+    entity TEXT NOT NULL,
+    type TEXT NOT NULL,
+    field TEXT NOT NULL,
+    start_offset INTEGER NOT NULL,
+    end_offset INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "{{.Schema}}_idx_email_entities_email_id"
+ON "{{.Schema}}_email_entities" (email_id);
+
+CREATE INDEX IF NOT EXISTS "{{.Schema}}_idx_email_entities_entity"
+ON "{{.Schema}}_email_entities" (entity, type);
+
+-- emails already processed by the extraction, with or without entities
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_entity_extractions" (
+    email_id INTEGER PRIMARY KEY,
+    extracted_at TIMESTAMP NOT NULL
+);
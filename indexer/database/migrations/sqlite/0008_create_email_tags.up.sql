@@ -0,0 +1,10 @@
+-- labels of the emails added by the users of the API, the indexer never writes this table
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_email_tags" (
+    email_id INTEGER NOT NULL REFERENCES "{{.Schema}}_emails"(id),
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (email_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS "{{.Schema}}_idx_email_tags_tag"
+ON "{{.Schema}}_email_tags" (tag);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}_saved_search_matches";
+DROP TABLE IF EXISTS "{{.Schema}}_saved_searches";
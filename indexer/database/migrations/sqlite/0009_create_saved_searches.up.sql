@@ -0,0 +1,22 @@
+-- searches saved by the users of the API, query is the QuerySearch as JSON
+-- last_seen_match_id is the last match returned to the owner
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_saved_searches" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner TEXT NOT NULL,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    evaluated_at TIMESTAMP,
+    last_seen_match_id INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (owner, name)
+);
+
+-- emails matched by the saved searches, recorded by the indexer after every run
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_saved_search_matches" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    saved_search_id INTEGER NOT NULL REFERENCES "{{.Schema}}_saved_searches"(id) ON DELETE CASCADE,
+    email_id INTEGER NOT NULL,
+    run_id INTEGER,
+    matched_at TIMESTAMP NOT NULL,
+    UNIQUE (saved_search_id, email_id)
+);
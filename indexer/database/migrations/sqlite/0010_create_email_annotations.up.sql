@@ -0,0 +1,14 @@
+-- notes of the users of the API on an email or on a range of characters of its content, the indexer never writes this table
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_email_annotations" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email_id INTEGER NOT NULL REFERENCES "{{.Schema}}_emails"(id),
+    author TEXT NOT NULL,
+    note TEXT NOT NULL,
+    start_offset INTEGER,
+    end_offset INTEGER,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "{{.Schema}}_idx_email_annotations_email_id"
+ON "{{.Schema}}_email_annotations" (email_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.Schema}}_folder_items";
+DROP TABLE IF EXISTS "{{.Schema}}_folders";
@@ -0,0 +1,20 @@
+-- folders of emails curated by the users of the API, the indexer never writes these tables
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_folders" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (name)
+);
+
+-- emails of the folders, position is the order of the email in the folder
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_folder_items" (
+    folder_id INTEGER NOT NULL REFERENCES "{{.Schema}}_folders"(id) ON DELETE CASCADE,
+    email_id INTEGER NOT NULL REFERENCES "{{.Schema}}_emails"(id),
+    position INTEGER NOT NULL,
+    added_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (folder_id, email_id)
+);
+
+CREATE INDEX IF NOT EXISTS "{{.Schema}}_idx_folder_items_email_id"
+ON "{{.Schema}}_folder_items" (email_id);
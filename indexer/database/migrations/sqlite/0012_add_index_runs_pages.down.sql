@@ -0,0 +1 @@
+ALTER TABLE "{{.Schema}}_index_runs" DROP COLUMN pages;
@@ -0,0 +1 @@
+ALTER TABLE "{{.Schema}}_saved_search_matches" DROP COLUMN alert_pending;
@@ -0,0 +1,2 @@
+-- the new matches wait in the outbox until the webhook accepts their alert, a failed alert is sent again after the next evaluation
+ALTER TABLE "{{.Schema}}_saved_search_matches" ADD COLUMN alert_pending BOOLEAN NOT NULL DEFAULT 0;
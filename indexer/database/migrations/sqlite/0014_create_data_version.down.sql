@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "{{.Schema}}_data_version";
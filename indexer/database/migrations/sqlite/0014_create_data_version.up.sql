@@ -0,0 +1,10 @@
+-- version of the searchable data, the indexer increments it after the commands that change the emails, their search vectors or their entities
+-- the API polls it to invalidate its cache of searches
+CREATE TABLE IF NOT EXISTS "{{.Schema}}_data_version" (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    version INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO "{{.Schema}}_data_version" (id, version) VALUES (1, 0)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,299 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+// migrationFilename matches the files of the migrations: 0001_create_emails.up.sql
+var migrationFilename = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration represents a numbered change of the schema
+// Version: number of the migration, applied in ascending order
+// Name: name of the migration
+// Up: SQL to apply the migration
+// Down: SQL to revert the migration
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus represents a migration and if it is applied in the schema
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrator applies and reverts the migrations of a schema
+// the applied versions are stored in the schema_migrations table of the schema
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	schemaName string
+	migrations []Migration
+}
+
+// NewMigrator creates a Migrator with the migrations of the driver
+// the migrations are templates, {{.Schema}} is replaced with the schema name
+func NewMigrator(db *sql.DB, driver, schemaName string) (*Migrator, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, fmt.Errorf("invalid schema name: %s", schemaName)
+	}
+
+	migrations, err := LoadMigrations(driver, schemaName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, driver: driver, schemaName: schemaName, migrations: migrations}, nil
+}
+
+// LoadMigrations reads the migrations of the driver sorted by version
+func LoadMigrations(driver, schemaName string) ([]Migration, error) {
+	directory := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationsFS, directory)
+	if err != nil {
+		return nil, fmt.Errorf("migrations not found for driver %s: %w", driver, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilename.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, matches[2])
+		}
+
+		sql, err := renderMigration(path.Join(directory, entry.Name()), schemaName)
+		if err != nil {
+			return nil, err
+		}
+
+		if matches[3] == "up" {
+			migration.Up = sql
+		} else {
+			migration.Down = sql
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has not up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// renderMigration reads the file and replaces the schema name
+func renderMigration(filename, schemaName string) (string, error) {
+	content, err := migrationsFS.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("error reading migration %s: %w", filename, err)
+	}
+
+	tmpl, err := template.New(filename).Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("error parsing migration %s: %w", filename, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Schema string }{Schema: schemaName}); err != nil {
+		return "", fmt.Errorf("error rendering migration %s: %w", filename, err)
+	}
+
+	return buf.String(), nil
+}
+
+// Up applies the pending migrations
+// steps: number of migrations to apply, 0 to apply all
+// returns the migrations applied
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.execute(migration.Up, fmt.Sprintf(`INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s);`,
+			m.migrationsTable(), m.placeholder(1), m.placeholder(2), m.placeholder(3)),
+			migration.Version, migration.Name, time.Now().UTC())
+		if err != nil {
+			return done, fmt.Errorf("error applying migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the last applied migrations
+// steps: number of migrations to revert, 0 to revert all
+// returns the migrations reverted
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if migration.Down == "" {
+			return done, fmt.Errorf("migration %d_%s can't be reverted, it has not down file", migration.Version, migration.Name)
+		}
+
+		err := m.execute(migration.Down, fmt.Sprintf(`DELETE FROM %s WHERE version = %s;`, m.migrationsTable(), m.placeholder(1)), migration.Version)
+		if err != nil {
+			return done, fmt.Errorf("error reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status returns every migration and if it is applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		item := MigrationStatus{Migration: migration, Applied: ok}
+		if ok {
+			item.AppliedAt = &appliedAt
+		}
+		status = append(status, item)
+	}
+
+	return status, nil
+}
+
+// execute runs the migration and updates the schema_migrations table in a transaction
+func (m *Migrator) execute(migrationSQL string, versionQuery string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrationSQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(versionQuery, args...); err != nil {
+		return fmt.Errorf("failed to update %s: %w", m.migrationsTable(), err)
+	}
+
+	return tx.Commit()
+}
+
+// appliedVersions creates the schema_migrations table if it doesn't exist
+// and returns the versions applied with the date
+func (m *Migrator) appliedVersions() (map[int]time.Time, error) {
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			version INT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		);`, m.migrationsTable()),
+	}
+
+	if m.driver == DriverCockroach {
+		queries = append([]string{fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s";`, m.schemaName)}, queries...)
+	}
+
+	for _, q := range queries {
+		if _, err := m.db.Exec(q); err != nil {
+			return nil, fmt.Errorf("error creating %s: %w", m.migrationsTable(), err)
+		}
+	}
+
+	rows, err := m.db.Query(fmt.Sprintf(`SELECT version, applied_at FROM %s;`, m.migrationsTable()))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", m.migrationsTable(), err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", m.migrationsTable(), err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// migrationsTable returns the quoted name of the schema_migrations table of the schema
+func (m *Migrator) migrationsTable() string {
+	return schemaTable(m.driver, m.schemaName, "schema_migrations")
+}
+
+// placeholder returns the n placeholder of a query in the driver
+func (m *Migrator) placeholder(n int) string {
+	if m.driver == DriverSQLite {
+		return "?"
+	}
+
+	return fmt.Sprintf("$%d", n)
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	for _, driver := range []string{DriverCockroach, DriverSQLite} {
+		t.Run("Must load the same versions for "+driver, func(t *testing.T) {
+			migrations, err := LoadMigrations(driver, DBSchemaNameTest)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, migrations)
+
+			for i, migration := range migrations {
+				assert.Equal(t, i+1, migration.Version)
+				assert.NotEmpty(t, migration.Up)
+				assert.NotEmpty(t, migration.Down)
+				assert.Contains(t, migration.Up, DBSchemaNameTest)
+			}
+		})
+	}
+
+	cockroach, _ := LoadMigrations(DriverCockroach, DBSchemaNameTest)
+	sqlite, _ := LoadMigrations(DriverSQLite, DBSchemaNameTest)
+	assert.Equal(t, len(cockroach), len(sqlite), "the drivers must have the same migrations")
+}
+
+func TestSQLiteMigrator(t *testing.T) {
+	conn, err := NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	migrator, err := conn.NewMigrator(DBSchemaNameTest)
+	assert.NoError(t, err)
+
+	t.Run("Must apply all the migrations", func(t *testing.T) {
+		applied, err := migrator.Up(0)
+		assert.NoError(t, err)
+		assert.Len(t, applied, len(migrator.migrations))
+
+		status, err := migrator.Status()
+		assert.NoError(t, err)
+		for _, s := range status {
+			assert.True(t, s.Applied)
+			assert.NotNil(t, s.AppliedAt)
+		}
+
+		exists, err := conn.IsSchemaCreated(DBSchemaNameTest)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("Must not apply the migrations twice", func(t *testing.T) {
+		applied, err := migrator.Up(0)
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("Must revert all the migrations", func(t *testing.T) {
+		reverted, err := migrator.Down(0)
+		assert.NoError(t, err)
+		assert.Len(t, reverted, len(migrator.migrations))
+
+		exists, err := conn.IsSchemaCreated(DBSchemaNameTest)
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("Must apply one step", func(t *testing.T) {
+		applied, err := migrator.Up(1)
+		assert.NoError(t, err)
+		assert.Len(t, applied, 1)
+		assert.Equal(t, 1, applied[0].Version)
+	})
+}
@@ -0,0 +1,274 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"indexer/models"
+)
+
+// ErrRunNotFound is returned when the index run doesn't exist
+var ErrRunNotFound = errors.New("index run not found")
+
+// schemaTable returns the name of a table of the schema in the driver
+func schemaTable(driver, schemaName, table string) string {
+	if driver == DriverSQLite {
+		return sqliteTable(schemaName, table)
+	}
+
+	return fmt.Sprintf(`"%s".%s`, schemaName, table)
+}
+
+// createIndexRun stores a new run and sets its id
+func createIndexRun(db *sql.DB, driver, schemaName string, run *models.IndexRun) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (from_page, to_page, pages, pagination, batch_size, parallelism, state, error, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id;
+	`, schemaTable(driver, schemaName, "index_runs"))
+
+	err := db.QueryRow(query, run.FromPage, run.ToPage, run.Pages, run.Pagination, run.BatchSize, run.Parallelism, string(run.State), run.Error, run.StartedAt).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create index run: %w", err)
+	}
+
+	return nil
+}
+
+// updateIndexRun updates the state and the totals of the run
+func updateIndexRun(db *sql.DB, driver, schemaName string, run models.IndexRun) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	deadLetters, err := json.Marshal(emptyIfNil(run.Stats.DeadLetters))
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letters of run %d: %w", run.ID, err)
+	}
+
+	errorSummary, err := json.Marshal(emptyIfNil(run.Stats.ErrorSummary))
+	if err != nil {
+		return fmt.Errorf("failed to marshal error summary of run %d: %w", run.ID, err)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET
+			state = $1, error = $2, finished_at = $3,
+			pages_finished = $4, pages_with_errors = $5,
+			emails_received = $6, emails_inserted = $7, emails_duplicated = $8, emails_errors = $9,
+			dead_letters = $10, error_summary = $11
+		WHERE id = $12;
+	`, schemaTable(driver, schemaName, "index_runs"))
+
+	_, err = db.Exec(query, string(run.State), run.Error, run.FinishedAt,
+		run.PagesFinished, run.PagesWithErrors,
+		run.Stats.Received, run.Stats.Inserted, run.Stats.Duplicated, run.Stats.Errors,
+		string(deadLetters), string(errorSummary), run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update index run %d: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+// listIndexRuns reads the last runs ordered from the newest
+// limit: maximum number of runs, ignored if 0
+func listIndexRuns(db *sql.DB, driver, schemaName string, limit int) ([]models.IndexRun, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`%s ORDER BY started_at DESC, id DESC`, selectIndexRunsQuery(driver, schemaName))
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := db.Query(query + ";")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]models.IndexRun, 0)
+	for rows.Next() {
+		run, err := scanIndexRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+
+	return runs, rows.Err()
+}
+
+// getIndexRun reads a run by id
+// returns ErrRunNotFound if the run doesn't exist
+func getIndexRun(db *sql.DB, driver, schemaName string, id int64) (*models.IndexRun, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRow(selectIndexRunsQuery(driver, schemaName)+" WHERE id = $1;", id)
+	run, err := scanIndexRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrRunNotFound, id)
+	}
+
+	return run, err
+}
+
+// selectIndexRunsQuery returns the select of the columns read by scanIndexRun
+func selectIndexRunsQuery(driver, schemaName string) string {
+	return fmt.Sprintf(`
+		SELECT id, from_page, to_page, pages, pagination, batch_size, parallelism, state, error, started_at, finished_at,
+			pages_finished, pages_with_errors, emails_received, emails_inserted, emails_duplicated, emails_errors,
+			dead_letters, error_summary
+		FROM %s`, schemaTable(driver, schemaName, "index_runs"))
+}
+
+// scanIndexRun reads a run of a row of selectIndexRunsQuery
+func scanIndexRun(row interface{ Scan(dest ...any) error }) (*models.IndexRun, error) {
+	var run models.IndexRun
+	var state, deadLetters, errorSummary string
+	var finishedAt sql.NullTime
+
+	err := row.Scan(&run.ID, &run.FromPage, &run.ToPage, &run.Pages, &run.Pagination, &run.BatchSize, &run.Parallelism, &state, &run.Error, &run.StartedAt, &finishedAt,
+		&run.PagesFinished, &run.PagesWithErrors, &run.Stats.Received, &run.Stats.Inserted, &run.Stats.Duplicated, &run.Stats.Errors,
+		&deadLetters, &errorSummary)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan index run: %w", err)
+	}
+
+	run.State = models.IndexRunStateType(state)
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+
+	if err := json.Unmarshal([]byte(deadLetters), &run.Stats.DeadLetters); err != nil {
+		return nil, fmt.Errorf("failed to read dead letters of run %d: %w", run.ID, err)
+	}
+
+	if err := json.Unmarshal([]byte(errorSummary), &run.Stats.ErrorSummary); err != nil {
+		return nil, fmt.Errorf("failed to read error summary of run %d: %w", run.ID, err)
+	}
+
+	return &run, nil
+}
+
+// emptyIfNil returns an empty map to store {} instead of null
+func emptyIfNil(m map[string]int) map[string]int {
+	if m == nil {
+		return map[string]int{}
+	}
+
+	return m
+}
+
+// savePageResults stores the state of the pages in a transaction
+// every page keeps only its last state
+func savePageResults(db *sql.DB, driver, schemaName string, runID int64, results []models.PageResult) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO %s (page, run_id, state, total, error, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (page) DO UPDATE SET
+			run_id = excluded.run_id,
+			state = excluded.state,
+			total = excluded.total,
+			error = excluded.error,
+			updated_at = excluded.updated_at;
+	`, schemaTable(driver, schemaName, "index_page_state")))
+	if err != nil {
+		return fmt.Errorf("failed to prepare page state upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, result := range results {
+		if _, err := stmt.Exec(result.Page, runID, string(result.State), result.Total, result.Error, now); err != nil {
+			return fmt.Errorf("failed to save state of page %d: %w", result.Page, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// loadPageResults reads the state of the pages, the key is the page number
+func loadPageResults(db *sql.DB, driver, schemaName string) (map[string]models.PageResult, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT page, state, total, error FROM %s ORDER BY page;`, schemaTable(driver, schemaName, "index_page_state")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query page state: %w", err)
+	}
+	defer rows.Close()
+
+	results := make(map[string]models.PageResult)
+	for rows.Next() {
+		var result models.PageResult
+		var state string
+		if err := rows.Scan(&result.Page, &state, &result.Total, &result.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan page state: %w", err)
+		}
+
+		result.State = models.PageResultStateType(state)
+		results[strconv.Itoa(result.Page)] = result
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteIndexRuns(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	run := models.IndexRun{FromPage: 1, ToPage: 3, State: models.IndexRunStateRunning, StartedAt: time.Now().UTC()}
+	assert.NoError(t, conn.CreateIndexRun(DBSchemaNameTest, &run))
+	assert.NotZero(t, run.ID)
+
+	t.Run("Must keep the last state of every page", func(t *testing.T) {
+		pending := []models.PageResult{
+			{Page: 1, State: models.PageResultStatePending},
+			{Page: 2, State: models.PageResultStatePending},
+		}
+		assert.NoError(t, conn.SavePageResults(DBSchemaNameTest, run.ID, pending))
+		assert.NoError(t, conn.SavePageResults(DBSchemaNameTest, run.ID, []models.PageResult{
+			{Page: 2, State: models.PageResultStateFinished, Total: 200, Error: "invalid id"},
+		}))
+
+		results, err := conn.LoadPageResults(DBSchemaNameTest)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]models.PageResult{
+			"1": {Page: 1, State: models.PageResultStatePending},
+			"2": {Page: 2, State: models.PageResultStateFinished, Total: 200, Error: "invalid id"},
+		}, results)
+	})
+
+	t.Run("Must finish the run with the totals", func(t *testing.T) {
+		finishedAt := time.Now().UTC()
+		run.State = models.IndexRunStateFinished
+		run.FinishedAt = &finishedAt
+		run.AddPageResult(models.PageResult{Page: 1, State: models.PageResultStateFinished})
+		run.AddPageResult(models.PageResult{Page: 2, State: models.PageResultStateFinished, Error: "invalid id"})
+		run.Stats = models.IndexStats{Received: 400, Inserted: 397, Duplicated: 1, Errors: 3}
+		run.Stats.AddDeadLetters(
+			models.DeadLetter{Stage: models.StageScrape, Error: "invalid id 12a"},
+			models.DeadLetter{Stage: models.StageInsert, Error: "value too long 300"},
+			models.DeadLetter{Stage: models.StageInsert, Error: "value too long 301"},
+		)
+		assert.NoError(t, conn.UpdateIndexRun(DBSchemaNameTest, run))
+
+		stored, err := conn.GetIndexRun(DBSchemaNameTest, run.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.IndexRunStateFinished, stored.State)
+		assert.NotNil(t, stored.FinishedAt)
+		assert.Equal(t, 2, stored.PagesFinished)
+		assert.Equal(t, 1, stored.PagesWithErrors)
+		assert.Equal(t, run.Stats, stored.Stats)
+		assert.Equal(t, map[string]int{"invalid id Na": 1, "value too long N": 2}, stored.Stats.ErrorSummary)
+	})
+
+	t.Run("Must list the runs from the newest", func(t *testing.T) {
+		// a resumed run indexes some pages of its range
+		newest := models.IndexRun{FromPage: 4, ToPage: 9, Pages: 2, State: models.IndexRunStateRunning, StartedAt: time.Now().UTC().Add(time.Minute)}
+		assert.NoError(t, conn.CreateIndexRun(DBSchemaNameTest, &newest))
+
+		runs, err := conn.ListIndexRuns(DBSchemaNameTest, 0)
+		assert.NoError(t, err)
+		assert.Len(t, runs, 2)
+		assert.Equal(t, newest.ID, runs[0].ID)
+		assert.Equal(t, 2, runs[0].PageCount())
+		assert.Equal(t, 3, runs[1].PageCount())
+		assert.Nil(t, runs[0].FinishedAt)
+		assert.Empty(t, runs[0].Stats.DeadLetters)
+
+		runs, err = conn.ListIndexRuns(DBSchemaNameTest, 1)
+		assert.NoError(t, err)
+		assert.Len(t, runs, 1)
+	})
+
+	t.Run("Must return not found", func(t *testing.T) {
+		_, err := conn.GetIndexRun(DBSchemaNameTest, 999)
+		assert.ErrorIs(t, err, ErrRunNotFound)
+	})
+}
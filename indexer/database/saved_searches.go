@@ -0,0 +1,366 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"indexer/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// savedQueryWordPattern is the format of the words of the saved queries, the API keeps only letters and numbers
+var savedQueryWordPattern = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+// savedQueryOperators are the comparisons of the date filter
+var savedQueryOperators = map[string]bool{"=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// listSavedSearches reads the saved searches of the schema ordered by id
+// the searches with a query that can't be read are skipped
+func listSavedSearches(db *sql.DB, driver, schemaName string) ([]models.SavedSearch, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, owner, name, query, created_at, evaluated_at
+		FROM %s
+		ORDER BY id;
+	`, schemaTable(driver, schemaName, "saved_searches")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := make([]models.SavedSearch, 0)
+	for rows.Next() {
+		var search models.SavedSearch
+		var query string
+		var evaluatedAt sql.NullTime
+		if err := rows.Scan(&search.ID, &search.Owner, &search.Name, &query, &search.CreatedAt, &evaluatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(query), &search.Query); err != nil {
+			log.WithFields(log.Fields{"savedSearch": search.ID, "error": err}).Warn("Invalid query of saved search")
+			continue
+		}
+
+		if evaluatedAt.Valid {
+			search.EvaluatedAt = &evaluatedAt.Time
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, rows.Err()
+}
+
+// matchSavedSearch returns the ids of the emails that match the query ordered by id
+// it follows the contract of models.SavedQuery, the emails are the ones of the search of the API with the same query
+func matchSavedSearch(db *sql.DB, driver, schemaName string, query models.SavedQuery) ([]uint32, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	conditions := make([]string, 0)
+	valueArgs := make([]any, 0)
+	addCondition := func(condition string, value any) {
+		valueArgs = append(valueArgs, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(valueArgs)))
+	}
+
+	if terms, ok := savedQueryTerms(driver, query); ok {
+		if driver == DriverSQLite {
+			searchTable := schemaTable(driver, schemaName, "emails_search")
+			addCondition("e.id IN (SELECT rowid FROM "+searchTable+" WHERE "+searchTable+" MATCH $%d)", terms)
+		} else {
+			searchConfig := fmt.Sprintf("(SELECT config FROM %s WHERE id = 1)::REGCONFIG", schemaTable(driver, schemaName, "search_config"))
+			addCondition("e.id IN (SELECT id FROM "+schemaTable(driver, schemaName, "emails_search")+" WHERE search_vector @@ to_tsquery("+searchConfig+", $%d))", terms)
+		}
+	}
+
+	if date := query.DateSearch.Date; date != nil {
+		operator := query.DateSearch.Operator
+		if !savedQueryOperators[operator] {
+			operator = "<="
+		}
+
+		if driver == DriverSQLite {
+			addCondition("date(e.date) "+operator+" $%d", date.UTC().Format("2006-01-02"))
+		} else {
+			addCondition("e.date::date "+operator+" $%d::date", date.UTC().Format("2006-01-02"))
+		}
+	}
+
+	dateColumn := "e.date::date"
+	dateValue := "$%d::date"
+	if driver == DriverSQLite {
+		dateColumn = "date(e.date)"
+		dateValue = "$%d"
+	}
+
+	if query.DateFrom != nil {
+		addCondition(dateColumn+" >= "+dateValue, query.DateFrom.UTC().Format("2006-01-02"))
+	}
+
+	if query.DateTo != nil {
+		addCondition(dateColumn+" <= "+dateValue, query.DateTo.UTC().Format("2006-01-02"))
+	}
+
+	if from := strings.TrimSpace(query.From); from != "" {
+		addCondition(`lower(e."from") LIKE $%d ESCAPE '\'`, "%"+strings.ToLower(escapeLike(from))+"%")
+	}
+
+	if to := strings.TrimSpace(query.To); to != "" {
+		addCondition(`lower(e."to") LIKE $%d ESCAPE '\'`, "%"+strings.ToLower(escapeLike(to))+"%")
+	}
+
+	for _, entity := range query.Entities {
+		addCondition("e.id IN (SELECT email_id FROM "+schemaTable(driver, schemaName, "email_entities")+" WHERE lower(entity) = lower($%d))", entity)
+	}
+
+	for _, tag := range query.Tags {
+		addCondition("e.id IN (SELECT email_id FROM "+schemaTable(driver, schemaName, "email_tags")+" WHERE tag = $%d)", tag)
+	}
+
+	if annotation := strings.TrimSpace(query.Annotation); annotation != "" {
+		addCondition("e.id IN (SELECT email_id FROM "+schemaTable(driver, schemaName, "email_annotations")+` WHERE lower(note) LIKE $%d ESCAPE '\')`, "%"+strings.ToLower(escapeLike(annotation))+"%")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT e.id FROM %s e %s ORDER BY e.id;`, schemaTable(driver, schemaName, "emails"), where), valueArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matches: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint32, 0)
+	for rows.Next() {
+		var id uint32
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// savedQueryTerms joins the words of the query with the AND or OR operator of the driver
+// it returns false if the words don't search, as the API the words are not searched if they have less than 2 characters
+// the words are quoted in FTS5 to search AND, OR and NOT as words
+func savedQueryTerms(driver string, query models.SavedQuery) (string, bool) {
+	words := make([]string, 0)
+	for _, word := range strings.Fields(query.Query) {
+		if savedQueryWordPattern.MatchString(word) {
+			words = append(words, word)
+		}
+	}
+
+	if len(strings.Join(words, " ")) < 2 {
+		return "", false
+	}
+
+	separator := " & "
+	if query.TypeSearch == models.SavedQueryTypeOR {
+		separator = " | "
+	}
+
+	if driver == DriverSQLite {
+		for i, word := range words {
+			words[i] = `"` + word + `"`
+		}
+
+		separator = " AND "
+		if query.TypeSearch == models.SavedQueryTypeOR {
+			separator = " OR "
+		}
+	}
+
+	return strings.Join(words, separator), true
+}
+
+// saveSavedSearchMatches records the emails that were not matched before by the saved search and returns them
+// the first evaluation records the current matches as already seen, they are not returned
+// alert: the new matches wait for the webhook to accept their alert, see listPendingAlerts
+func saveSavedSearchMatches(db *sql.DB, driver, schemaName string, searchID, runID int64, emailIDs []uint32, matchedAt time.Time, alert bool) ([]uint32, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	searchesTable := schemaTable(driver, schemaName, "saved_searches")
+	matchesTable := schemaTable(driver, schemaName, "saved_search_matches")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	var evaluatedAt sql.NullTime
+	err = tx.QueryRow(fmt.Sprintf(`SELECT evaluated_at FROM %s WHERE id = $1;`, searchesTable), searchID).Scan(&evaluatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved search %d: %w", searchID, err)
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`SELECT email_id FROM %s WHERE saved_search_id = $1;`, matchesTable), searchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matches: %w", err)
+	}
+
+	matched := make(map[uint32]bool)
+	for rows.Next() {
+		var id uint32
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+		matched[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read matches: %w", err)
+	}
+
+	insert, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO %s (saved_search_id, email_id, run_id, matched_at, alert_pending)
+		VALUES ($1, $2, $3, $4, $5);
+	`, matchesTable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare matches insert: %w", err)
+	}
+	defer insert.Close()
+
+	// the matches of the first evaluation are not new, they are never alerted
+	run := sql.NullInt64{Int64: runID, Valid: runID > 0}
+	pending := alert && evaluatedAt.Valid
+	newIDs := make([]uint32, 0)
+	for _, id := range emailIDs {
+		if matched[id] {
+			continue
+		}
+
+		if _, err := insert.Exec(searchID, id, run, matchedAt, pending); err != nil {
+			return nil, fmt.Errorf("failed to insert match of email %d: %w", id, err)
+		}
+		newIDs = append(newIDs, id)
+	}
+
+	update := `UPDATE %s SET evaluated_at = $1 WHERE id = $2;`
+	if !evaluatedAt.Valid {
+		update = `UPDATE %s SET evaluated_at = $1, last_seen_match_id = (SELECT COALESCE(MAX(id), 0) FROM ` + matchesTable + ` WHERE saved_search_id = $2) WHERE id = $2;`
+		newIDs = newIDs[:0]
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(update, searchesTable), matchedAt, searchID); err != nil {
+		return nil, fmt.Errorf("failed to update saved search %d: %w", searchID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return newIDs, nil
+}
+
+// listPendingAlerts returns the ids of the emails matched by the saved search that wait for their alert ordered by id
+// the matches of the failed alerts are still pending, they are sent again with the new ones
+func listPendingAlerts(db *sql.DB, driver, schemaName string, searchID int64) ([]uint32, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT email_id FROM %s
+		WHERE saved_search_id = $1 AND alert_pending
+		ORDER BY email_id;
+	`, schemaTable(driver, schemaName, "saved_search_matches")), searchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending alerts: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint32, 0)
+	for rows.Next() {
+		var id uint32
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan pending alert: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// markAlertsSent removes the matches of the saved search from the pending alerts once the webhook accepted them
+func markAlertsSent(db *sql.DB, driver, schemaName string, searchID int64, emailIDs []uint32) error {
+	if err := ValidateDBConnection(db); err != nil {
+		return err
+	}
+
+	if len(emailIDs) == 0 {
+		return nil
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	update, err := tx.Prepare(fmt.Sprintf(`
+		UPDATE %s SET alert_pending = false
+		WHERE saved_search_id = $1 AND email_id = $2;
+	`, schemaTable(driver, schemaName, "saved_search_matches")))
+	if err != nil {
+		return fmt.Errorf("failed to prepare alerts update: %w", err)
+	}
+	defer update.Close()
+
+	for _, id := range emailIDs {
+		if _, err := update.Exec(searchID, id); err != nil {
+			return fmt.Errorf("failed to mark alert of email %d as sent: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// escapeLike escapes the wildcards of LIKE with a backslash
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
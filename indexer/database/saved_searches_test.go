@@ -0,0 +1,153 @@
+package database
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteSavedSearches(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	emails := []models.Email{
+		{ID: 1, Date: time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC), Subject: "Libya update", From: "Jake Sullivan", Content: "the embassy is running"},
+		{ID: 2, Date: time.Date(2012, 9, 11, 22, 0, 0, 0, time.UTC), Subject: "Benghazi", From: "Cheryl Mills", To: "H", Content: "call me about libya"},
+	}
+	_, err := conn.SendMails(DBSchemaNameTest, emails)
+	assert.NoError(t, err)
+
+	_, err = conn.DB.Exec(`INSERT INTO `+sqliteTable(DBSchemaNameTest, "saved_searches")+` (owner, name, query) VALUES ($1, $2, $3)`,
+		"analyst", "libya", `{"query":"libya","type":"AND","dateSearch":{"operator":""},"entities":[],"tags":["follow-up"]}`)
+	assert.NoError(t, err)
+	_, err = conn.DB.Exec(`INSERT INTO ` + sqliteTable(DBSchemaNameTest, "email_tags") + ` (email_id, tag) VALUES (1, 'follow-up')`)
+	assert.NoError(t, err)
+
+	searches, err := conn.ListSavedSearches(DBSchemaNameTest)
+	assert.NoError(t, err)
+	assert.Len(t, searches, 1)
+	assert.Nil(t, searches[0].EvaluatedAt)
+	assert.Equal(t, []string{"follow-up"}, searches[0].Query.Tags)
+
+	ids, err := conn.MatchSavedSearch(DBSchemaNameTest, searches[0].Query)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1}, ids)
+
+	// the first evaluation records the current matches as seen
+	newIDs, err := conn.SaveSavedSearchMatches(DBSchemaNameTest, searches[0].ID, 1, ids, time.Now().UTC(), true)
+	assert.NoError(t, err)
+	assert.Empty(t, newIDs)
+
+	var lastSeen int64
+	assert.NoError(t, conn.DB.QueryRow(`SELECT last_seen_match_id FROM `+sqliteTable(DBSchemaNameTest, "saved_searches")).Scan(&lastSeen))
+	assert.NotZero(t, lastSeen)
+
+	_, err = conn.DB.Exec(`INSERT INTO ` + sqliteTable(DBSchemaNameTest, "email_tags") + ` (email_id, tag) VALUES (2, 'follow-up')`)
+	assert.NoError(t, err)
+
+	ids, err = conn.MatchSavedSearch(DBSchemaNameTest, searches[0].Query)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 2}, ids)
+
+	newIDs, err = conn.SaveSavedSearchMatches(DBSchemaNameTest, searches[0].ID, 2, ids, time.Now().UTC(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{2}, newIDs)
+
+	// only the new matches wait for their alert, until the webhook accepts it
+	pending, err := conn.ListPendingAlerts(DBSchemaNameTest, searches[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{2}, pending)
+
+	assert.NoError(t, conn.MarkAlertsSent(DBSchemaNameTest, searches[0].ID, pending))
+	pending, err = conn.ListPendingAlerts(DBSchemaNameTest, searches[0].ID)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	searches, err = conn.ListSavedSearches(DBSchemaNameTest)
+	assert.NoError(t, err)
+	assert.NotNil(t, searches[0].EvaluatedAt)
+}
+
+func TestSQLiteMatchSavedSearch(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	emails := []models.Email{
+		{ID: 1, Date: time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC), Subject: "Libya update", From: "Jake Sullivan", Content: "the embassy is running"},
+		{ID: 2, Date: time.Date(2012, 9, 11, 22, 0, 0, 0, time.UTC), Subject: "Benghazi", From: "Cheryl Mills", To: "H", Content: "call me about libya"},
+		{ID: 3, Date: time.Date(2012, 9, 12, 8, 0, 0, 0, time.UTC), Subject: "Schedule", Content: "meeting at noon"},
+	}
+	_, err := conn.SendMails(DBSchemaNameTest, emails)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.SaveEmailEntities(DBSchemaNameTest, []uint32{2}, []models.EmailEntity{
+		{EmailID: 2, Entity: "Benghazi", Type: models.EntityTypePlace, Field: models.EntityFieldSubject, Start: 0, End: 8},
+	}))
+	_, err = conn.DB.Exec(`INSERT INTO `+sqliteTable(DBSchemaNameTest, "email_annotations")+` (email_id, author, note, created_at, updated_at) VALUES ($1, $2, $3, $4, $4)`, 1, "analyst", "Check the 100% figure", time.Now().UTC())
+	assert.NoError(t, err)
+
+	date := time.Date(2012, 9, 11, 0, 0, 0, 0, time.UTC)
+	firstDate := time.Date(2011, 3, 14, 0, 0, 0, 0, time.UTC)
+	ttc := []struct {
+		name     string
+		query    models.SavedQuery
+		expected []uint32
+	}{
+		{"must match all the emails without filters", models.SavedQuery{}, []uint32{1, 2, 3}},
+		{"must match all the words", models.SavedQuery{Query: "libya call", TypeSearch: models.SavedQueryTypeAND}, []uint32{2}},
+		{"must match any word", models.SavedQuery{Query: "libya noon", TypeSearch: models.SavedQueryTypeOR}, []uint32{1, 2, 3}},
+		{"must filter by date", models.SavedQuery{DateSearch: models.SavedDateSearch{Date: &date, Operator: ">="}}, []uint32{2, 3}},
+		{"must filter by entity", models.SavedQuery{Entities: []string{"benghazi"}}, []uint32{2}},
+		{"must filter by annotation", models.SavedQuery{Annotation: "100%"}, []uint32{1}},
+		{"must filter by the range of days included", models.SavedQuery{DateFrom: &firstDate, DateTo: &date}, []uint32{1, 2}},
+		{"must filter by the sender without case", models.SavedQuery{From: "CHERYL"}, []uint32{2}},
+		{"must filter by the recipients", models.SavedQuery{To: "h"}, []uint32{2}},
+		{"must escape the wildcards of the annotation", models.SavedQuery{Annotation: "1_0"}, []uint32{}},
+		{"must ignore the words that are not sanitized", models.SavedQuery{Query: `libya call"`, TypeSearch: models.SavedQueryTypeAND}, []uint32{1, 2}},
+		{"must search the keywords as words", models.SavedQuery{Query: "libya OR noon", TypeSearch: models.SavedQueryTypeAND}, []uint32{}},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := conn.MatchSavedSearch(DBSchemaNameTest, tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+// TestSQLiteSavedQueriesContract matches the shared cases of the API with the queries stored by the API
+// the API runs the same cases with its search, both must find the same emails
+func TestSQLiteSavedQueriesContract(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "api", "services", "testdata", "saved_queries.json"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var contract struct {
+		Emails []models.Email `json:"emails"`
+		Cases  []struct {
+			Name   string            `json:"name"`
+			Stored models.SavedQuery `json:"stored"`
+			IDs    []uint32          `json:"ids"`
+		} `json:"cases"`
+	}
+	if !assert.NoError(t, json.Unmarshal(data, &contract)) {
+		return
+	}
+
+	conn := getSQLiteConn(t)
+	_, err = conn.SendMails(DBSchemaNameTest, contract.Emails)
+	assert.NoError(t, err)
+
+	for _, tt := range contract.Cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			ids, err := conn.MatchSavedSearch(DBSchemaNameTest, tt.Stored)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.IDs, ids)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"indexer/models"
+)
+
+// validSearchConfig are the characters of a text search config or a FTS5 tokenizer with its options
+var validSearchConfig = regexp.MustCompile(`^[a-zA-Z0-9_]+( [a-zA-Z0-9_]+)*$`)
+
+// ValidateSearchConfig ensures the search configuration is safe to use in the definition of the search table
+// the FTS5 tokenizers of SQLite take options separated by spaces, ex: unicode61 remove_diacritics 2
+func ValidateSearchConfig(driver, config string) error {
+	if driver != DriverSQLite {
+		return ValidateIsSafeString(config)
+	}
+
+	if !validSearchConfig.MatchString(config) {
+		return fmt.Errorf("invalid search config: %s", config)
+	}
+
+	return nil
+}
+
+// searchVectorExpression returns the expression of the search vector of the email e, the config is the parameter $1
+func searchVectorExpression() string {
+	return `to_tsvector($1::REGCONFIG, COALESCE(e.subject, '') || ' ' || COALESCE(e."from", '') || ' ' || COALESCE(e."to", '') || ' ' || COALESCE(e.content, ''))`
+}
+
+// getSearchConfig reads the search configuration of the schema
+func getSearchConfig(db *sql.DB, driver, schemaName string) (models.SearchConfig, error) {
+	config := models.SearchConfig{}
+	if err := ValidateDBConnection(db); err != nil {
+		return config, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return config, err
+	}
+
+	query := fmt.Sprintf(`SELECT config, next_config, updated_at FROM %s WHERE id = 1;`, schemaTable(driver, schemaName, "search_config"))
+	if err := db.QueryRow(query).Scan(&config.Config, &config.NextConfig, &config.UpdatedAt); err != nil {
+		return config, fmt.Errorf("failed to read search config: %w", err)
+	}
+
+	return config, nil
+}
+
+// startSearchReindex creates the table of the new search vectors
+// a reindex in progress with the same config is resumed, with another config it is started again
+// returns the last id already indexed in the new table
+func startSearchReindex(db *sql.DB, driver, schemaName, config string) (uint32, error) {
+	current, err := getSearchConfig(db, driver, schemaName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ValidateSearchConfig(driver, config); err != nil {
+		return 0, err
+	}
+
+	nextTable := schemaTable(driver, schemaName, "emails_search_next")
+	if current.NextConfig == nil || *current.NextConfig != config {
+		statements := []string{fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, nextTable)}
+		if driver == DriverSQLite {
+			statements = append(statements, fmt.Sprintf(`CREATE VIRTUAL TABLE %s USING fts5(subject, "from", "to", content, tokenize = '%s');`, nextTable, config))
+		} else {
+			statements = append(statements,
+				fmt.Sprintf(`CREATE TABLE %s (id INT PRIMARY KEY, search_vector TSVECTOR, FOREIGN KEY (id) REFERENCES %s(id));`, nextTable, schemaTable(driver, schemaName, "emails")),
+				fmt.Sprintf(`CREATE INVERTED INDEX ON %s (search_vector);`, nextTable))
+		}
+
+		for _, statement := range statements {
+			if _, err := db.Exec(statement); err != nil {
+				return 0, fmt.Errorf("failed to create the new search table: %w", err)
+			}
+		}
+
+		query := fmt.Sprintf(`UPDATE %s SET next_config = $1 WHERE id = 1;`, schemaTable(driver, schemaName, "search_config"))
+		if _, err := db.Exec(query, config); err != nil {
+			return 0, fmt.Errorf("failed to save the config of the reindex: %w", err)
+		}
+	}
+
+	idColumn := "id"
+	if driver == DriverSQLite {
+		idColumn = "rowid"
+	}
+
+	var lastID uint32
+	query := fmt.Sprintf(`SELECT COALESCE(MAX(%s), 0) FROM %s;`, idColumn, nextTable)
+	if err := db.QueryRow(query).Scan(&lastID); err != nil {
+		return 0, fmt.Errorf("failed to read the progress of the reindex: %w", err)
+	}
+
+	return lastID, nil
+}
+
+// reindexSearchBatch computes the search vectors of the next limit emails after afterID in the new table
+// returns the last id of the batch and the number of emails, 0 when all the emails are indexed
+func reindexSearchBatch(db *sql.DB, driver, schemaName, config string, afterID uint32, limit int) (uint32, int, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return 0, 0, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return 0, 0, err
+	}
+
+	emailsTable := schemaTable(driver, schemaName, "emails")
+	nextTable := schemaTable(driver, schemaName, "emails_search_next")
+
+	var lastID uint32
+	var total int
+	query := fmt.Sprintf(`
+		SELECT COALESCE(MAX(id), 0), COUNT(*)
+		FROM (SELECT id FROM %s WHERE id > $1 ORDER BY id LIMIT $2) AS batch;
+	`, emailsTable)
+	if err := db.QueryRow(query, afterID, limit).Scan(&lastID, &total); err != nil {
+		return 0, 0, fmt.Errorf("failed to read the next batch: %w", err)
+	}
+
+	if total == 0 {
+		return afterID, 0, nil
+	}
+
+	var args []any
+	if driver == DriverSQLite {
+		query = fmt.Sprintf(`
+			INSERT INTO %s (rowid, subject, "from", "to", content)
+			SELECT e.id, e.subject, e."from", e."to", e.content
+			FROM %s e
+			WHERE e.id > $1 AND e.id <= $2;
+		`, nextTable, emailsTable)
+		args = []any{afterID, lastID}
+	} else {
+		query = fmt.Sprintf(`
+			INSERT INTO %s (id, search_vector)
+			SELECT e.id, %s
+			FROM %s e
+			WHERE e.id > $2 AND e.id <= $3
+			ON CONFLICT (id) DO NOTHING;
+		`, nextTable, searchVectorExpression(), emailsTable)
+		args = []any{config, afterID, lastID}
+	}
+
+	if _, err := db.Exec(query, args...); err != nil {
+		return 0, 0, fmt.Errorf("failed to reindex emails %d to %d: %w", afterID+1, lastID, err)
+	}
+
+	return lastID, total, nil
+}
+
+// swapSearchIndex replaces the search table with the new one in a transaction
+// the emails inserted during the reindex are added to the new table before the swap and the ones inserted during the swap after it
+// the transaction only has the renames and the update of the config, cockroachdb doesn't allow schema changes after writes
+func swapSearchIndex(db *sql.DB, driver, schemaName string) error {
+	current, err := getSearchConfig(db, driver, schemaName)
+	if err != nil {
+		return err
+	}
+
+	if current.NextConfig == nil {
+		return fmt.Errorf("there is no reindex in progress")
+	}
+
+	searchTable := schemaTable(driver, schemaName, "emails_search")
+	nextTable := schemaTable(driver, schemaName, "emails_search_next")
+	oldTable := schemaTable(driver, schemaName, "emails_search_old")
+	configTable := schemaTable(driver, schemaName, "search_config")
+
+	// a previous swap could stop before dropping the old table
+	if _, err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, oldTable)); err != nil {
+		return fmt.Errorf("failed to drop the old search table: %w", err)
+	}
+
+	if err := catchUpSearchIndex(db, driver, schemaName, nextTable, *current.NextConfig); err != nil {
+		return fmt.Errorf("failed to reindex the emails inserted during the reindex: %w", err)
+	}
+
+	updateConfig := fmt.Sprintf(`UPDATE %s SET config = next_config, next_config = NULL, updated_at = now() WHERE id = 1;`, configTable)
+	if driver == DriverSQLite {
+		updateConfig = fmt.Sprintf(`UPDATE %s SET config = next_config, next_config = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = 1;`, configTable)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s;`, searchTable, oldTable),
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s;`, nextTable, searchTable),
+		updateConfig,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to swap the search table: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := catchUpSearchIndex(db, driver, schemaName, searchTable, *current.NextConfig); err != nil {
+		return fmt.Errorf("failed to reindex the emails inserted during the swap: %w", err)
+	}
+
+	if _, err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, oldTable)); err != nil {
+		return fmt.Errorf("failed to drop the old search table: %w", err)
+	}
+
+	return nil
+}
+
+// catchUpSearchIndex adds the emails missing in the search table, the table uses the text search config
+func catchUpSearchIndex(db *sql.DB, driver, schemaName, table, config string) error {
+	emailsTable := schemaTable(driver, schemaName, "emails")
+
+	if driver == DriverSQLite {
+		_, err := db.Exec(fmt.Sprintf(`
+			INSERT INTO %s (rowid, subject, "from", "to", content)
+			SELECT e.id, e.subject, e."from", e."to", e.content
+			FROM %s e
+			WHERE e.id NOT IN (SELECT rowid FROM %s);
+		`, table, emailsTable, table))
+		return err
+	}
+
+	_, err := db.Exec(fmt.Sprintf(`
+		INSERT INTO %s (id, search_vector)
+		SELECT e.id, %s
+		FROM %s e
+		WHERE NOT EXISTS (SELECT 1 FROM %s n WHERE n.id = e.id);
+	`, table, searchVectorExpression(), emailsTable, table), config)
+	return err
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteReindexSearch(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	newEmail := func(id uint32, subject string) models.Email {
+		return models.Email{ID: id, Date: time.Now().UTC(), Subject: subject, From: "from", To: "to", Content: "content"}
+	}
+	_, err := conn.SendMails(DBSchemaNameTest, []models.Email{newEmail(1, "running"), newEmail(2, "meetings"), newEmail(3, "benghazi")})
+	assert.NoError(t, err)
+
+	current, err := conn.GetSearchConfig(DBSchemaNameTest)
+	assert.NoError(t, err)
+	assert.Equal(t, "porter unicode61", current.Config)
+	assert.Nil(t, current.NextConfig)
+
+	_, err = conn.StartSearchReindex(DBSchemaNameTest, "unicode61'; DROP TABLE x")
+	assert.Error(t, err)
+
+	lastID, err := conn.StartSearchReindex(DBSchemaNameTest, "unicode61")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), lastID)
+
+	lastID, total, err := conn.ReindexSearchBatch(DBSchemaNameTest, "unicode61", lastID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), lastID)
+	assert.Equal(t, 2, total)
+
+	t.Run("Must resume the reindex with the same config", func(t *testing.T) {
+		resumed, err := conn.StartSearchReindex(DBSchemaNameTest, "unicode61")
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(2), resumed)
+	})
+
+	// the emails inserted during the reindex are added on the swap
+	_, err = conn.SendMails(DBSchemaNameTest, []models.Email{newEmail(4, "running")})
+	assert.NoError(t, err)
+
+	lastID, total, err = conn.ReindexSearchBatch(DBSchemaNameTest, "unicode61", lastID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	_, total, err = conn.ReindexSearchBatch(DBSchemaNameTest, "unicode61", lastID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+
+	assert.NoError(t, conn.SwapSearchIndex(DBSchemaNameTest))
+
+	current, err = conn.GetSearchConfig(DBSchemaNameTest)
+	assert.NoError(t, err)
+	assert.Equal(t, "unicode61", current.Config)
+	assert.Nil(t, current.NextConfig)
+
+	// without the porter stemmer "run" doesn't match "running"
+	var matches int
+	searchTable := sqliteTable(DBSchemaNameTest, "emails_search")
+	assert.NoError(t, conn.DB.QueryRow(`SELECT COUNT(*) FROM `+searchTable+` WHERE `+searchTable+` MATCH 'run'`).Scan(&matches))
+	assert.Equal(t, 0, matches)
+	assert.NoError(t, conn.DB.QueryRow(`SELECT COUNT(*) FROM `+searchTable+` WHERE `+searchTable+` MATCH 'running'`).Scan(&matches))
+	assert.Equal(t, 2, matches)
+
+	assert.Error(t, conn.SwapSearchIndex(DBSchemaNameTest))
+}
+
+// TestReindexSearch runs the reindex on cockroachdb, the swap renames the tables after the emails are written
+func TestReindexSearch(t *testing.T) {
+	conn, err := getConn()
+	assert.NoError(t, err)
+
+	_, err = conn.Open()
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	schemaName := DBSchemaNameTest + "_reindex"
+	if !assert.NoError(t, conn.CreateSchemaIfNotExist(schemaName)) {
+		return
+	}
+	defer conn.DB.Exec(fmt.Sprintf(`DROP SCHEMA IF EXISTS %s CASCADE;`, schemaName))
+
+	newEmail := func(id uint32, subject string) models.Email {
+		return models.Email{ID: id, Date: time.Now().UTC(), Subject: subject, From: "from", To: "to", Content: "content"}
+	}
+	_, err = conn.SendMails(schemaName, []models.Email{newEmail(1, "running"), newEmail(2, "meetings"), newEmail(3, "benghazi")})
+	assert.NoError(t, err)
+
+	lastID, err := conn.StartSearchReindex(schemaName, "simple")
+	assert.NoError(t, err)
+
+	lastID, total, err := conn.ReindexSearchBatch(schemaName, "simple", lastID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+
+	// the emails inserted during the reindex are added on the swap
+	_, err = conn.SendMails(schemaName, []models.Email{newEmail(4, "running")})
+	assert.NoError(t, err)
+
+	_, _, err = conn.ReindexSearchBatch(schemaName, "simple", lastID, 1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, conn.SwapSearchIndex(schemaName))
+
+	current, err := conn.GetSearchConfig(schemaName)
+	assert.NoError(t, err)
+	assert.Equal(t, "simple", current.Config)
+	assert.Nil(t, current.NextConfig)
+
+	// without the english stemmer "run" doesn't match "running"
+	var matches int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s.emails_search WHERE search_vector @@ to_tsquery('simple', $1)`, schemaName)
+	assert.NoError(t, conn.DB.QueryRow(query, "run").Scan(&matches))
+	assert.Equal(t, 0, matches)
+	assert.NoError(t, conn.DB.QueryRow(query, "running").Scan(&matches))
+	assert.Equal(t, 2, matches)
+
+	var indexed int
+	assert.NoError(t, conn.DB.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s.emails_search`, schemaName)).Scan(&indexed))
+	assert.Equal(t, 4, indexed)
+}
@@ -0,0 +1,427 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"indexer/models"
+
+	_ "modernc.org/sqlite" // SQLite driver without cgo, compiled with FTS5
+)
+
+// SQLiteConnection holds the configuration of an embedded SQLite database
+// SQLite has no schemas, the tables of a schema are prefixed with its name: <schema>_emails
+type SQLiteConnection struct {
+	Path string
+	DB   *sql.DB
+}
+
+// NewSQLiteConnection validates input and returns a SQLiteConnection instance
+// path: file of the database, it is created if it doesn't exist
+func NewSQLiteConnection(path string) (*SQLiteConnection, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path not specified")
+	}
+
+	c := &SQLiteConnection{Path: path}
+
+	db, err := c.Open()
+	if err != nil {
+		return nil, err
+	}
+	c.DB = db
+
+	return c, nil
+}
+
+// Open opens the database connection without pool configuration
+// the database uses WAL to allow the API to read while the indexer writes
+// the dates are stored in the SQLite format to be used by the date functions
+func (c *SQLiteConnection) Open() (*sql.DB, error) {
+	if err := ensureFileDir(c.Path); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", c.BuildConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("error opening connection: %w", err)
+	}
+	c.DB = db
+	return c.DB, nil
+}
+
+// OpenWithPool opens the database connection and configures connection pooling
+func (c *SQLiteConnection) OpenWithPool(maxOpenConns, maxIdleConns int) (*sql.DB, error) {
+	db, err := c.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	c.DB = db
+	c.DB.SetMaxOpenConns(maxOpenConns)
+	c.DB.SetMaxIdleConns(maxIdleConns)
+	return db, nil
+}
+
+// BuildConnectionString builds the connection string for sql.Open
+func (c *SQLiteConnection) BuildConnectionString() string {
+	return fmt.Sprintf("file:%s?_time_format=sqlite&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", c.Path)
+}
+
+// SendMails sends the emails to the database
+// the mails is send in batches, only the new emails are added to the search table
+func (c *SQLiteConnection) SendMails(schemaName string, emails []models.Email) (int64, error) {
+	if err := ValidateDBConnection(c.DB); err != nil {
+		return 0, err
+	}
+
+	if len(emails) == 0 {
+		return 0, nil
+	}
+
+	query, valueArgs, err := c.createInsertQuery(schemaName, emails)
+	if err != nil {
+		return 0, err
+	}
+
+	querySearch, valueArgsSearch := c.createSearchQuery(schemaName, emails)
+
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.Exec(query, valueArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to batch insert emails: %w", err)
+	}
+
+	_, err = tx.Exec(querySearch, valueArgsSearch...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to batch insert emails search: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	rowsInserted, err := rows.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsInserted, nil
+}
+
+// SendDeadLetters stores the emails rejected by the pipeline in the dead_letters table
+func (c *SQLiteConnection) SendDeadLetters(schemaName string, letters []models.DeadLetter) error {
+	return insertDeadLetters(c.DB, DriverSQLite, schemaName, letters)
+}
+
+// ListDeadLetters reads the dead letters that match the filter ordered by id
+func (c *SQLiteConnection) ListDeadLetters(schemaName string, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	return listDeadLetters(c.DB, DriverSQLite, schemaName, filter)
+}
+
+// MarkDeadLettersReplayed sets the date of the replay of the dead letters
+func (c *SQLiteConnection) MarkDeadLettersReplayed(schemaName string, ids []int64) error {
+	return markDeadLettersReplayed(c.DB, DriverSQLite, schemaName, ids)
+}
+
+// CreateIndexRun stores a new run of the indexer and sets its id
+func (c *SQLiteConnection) CreateIndexRun(schemaName string, run *models.IndexRun) error {
+	return createIndexRun(c.DB, DriverSQLite, schemaName, run)
+}
+
+// UpdateIndexRun updates the state of a run of the indexer
+func (c *SQLiteConnection) UpdateIndexRun(schemaName string, run models.IndexRun) error {
+	return updateIndexRun(c.DB, DriverSQLite, schemaName, run)
+}
+
+// ListIndexRuns reads the last runs of the indexer ordered from the newest
+func (c *SQLiteConnection) ListIndexRuns(schemaName string, limit int) ([]models.IndexRun, error) {
+	return listIndexRuns(c.DB, DriverSQLite, schemaName, limit)
+}
+
+// GetIndexRun reads a run of the indexer by id
+func (c *SQLiteConnection) GetIndexRun(schemaName string, id int64) (*models.IndexRun, error) {
+	return getIndexRun(c.DB, DriverSQLite, schemaName, id)
+}
+
+// SavePageResults stores the state of the pages indexed by a run
+func (c *SQLiteConnection) SavePageResults(schemaName string, runID int64, results []models.PageResult) error {
+	return savePageResults(c.DB, DriverSQLite, schemaName, runID, results)
+}
+
+// LoadPageResults reads the state of the pages, the key is the page number
+func (c *SQLiteConnection) LoadPageResults(schemaName string) (map[string]models.PageResult, error) {
+	return loadPageResults(c.DB, DriverSQLite, schemaName)
+}
+
+// StreamEmails reads the emails that match the filter ordered by id
+// fn is called once per email, if it returns an error the reading stops
+func (c *SQLiteConnection) StreamEmails(schemaName string, filter models.EmailFilter, fn func(models.Email) error) error {
+	if err := ValidateDBConnection(c.DB); err != nil {
+		return err
+	}
+
+	query, valueArgs, err := c.createSelectQuery(schemaName, filter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.DB.Query(query, valueArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to query emails: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.Email
+		if err := rows.Scan(&e.ID, &e.Date, &e.Subject, &e.From, &e.To, &e.Content); err != nil {
+			return fmt.Errorf("failed to scan email: %w", err)
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ListEmailIDs reads the sorted ids of the emails between fromID and toID
+func (c *SQLiteConnection) ListEmailIDs(schemaName string, fromID, toID uint32) ([]uint32, error) {
+	return listEmailIDs(c.DB, DriverSQLite, schemaName, fromID, toID)
+}
+
+// GetSearchConfig reads the text search configuration of the search table
+func (c *SQLiteConnection) GetSearchConfig(schemaName string) (models.SearchConfig, error) {
+	return getSearchConfig(c.DB, DriverSQLite, schemaName)
+}
+
+// StartSearchReindex creates or resumes the new search table and returns the last id indexed
+func (c *SQLiteConnection) StartSearchReindex(schemaName, config string) (uint32, error) {
+	return startSearchReindex(c.DB, DriverSQLite, schemaName, config)
+}
+
+// ReindexSearchBatch computes the search vectors of the emails after afterID in the new search table
+func (c *SQLiteConnection) ReindexSearchBatch(schemaName, config string, afterID uint32, limit int) (uint32, int, error) {
+	return reindexSearchBatch(c.DB, DriverSQLite, schemaName, config, afterID, limit)
+}
+
+// SwapSearchIndex replaces the search table with the new one
+func (c *SQLiteConnection) SwapSearchIndex(schemaName string) error {
+	return swapSearchIndex(c.DB, DriverSQLite, schemaName)
+}
+
+// ListEmailsWithoutEntities reads the first emails not processed by the entity extraction
+func (c *SQLiteConnection) ListEmailsWithoutEntities(schemaName string, limit int) ([]models.Email, error) {
+	return listEmailsWithoutEntities(c.DB, DriverSQLite, schemaName, limit)
+}
+
+// SaveEmailEntities replaces the entities of the emails and marks them as processed
+func (c *SQLiteConnection) SaveEmailEntities(schemaName string, emailIDs []uint32, entities []models.EmailEntity) error {
+	return saveEmailEntities(c.DB, DriverSQLite, schemaName, emailIDs, entities)
+}
+
+// ResetEntities deletes the entities and the extractions to extract them again
+func (c *SQLiteConnection) ResetEntities(schemaName string) error {
+	return resetEntities(c.DB, DriverSQLite, schemaName)
+}
+
+// BumpDataVersion increments the version of the searchable data, the API invalidates its cache when it changes
+func (c *SQLiteConnection) BumpDataVersion(schemaName string) error {
+	return bumpDataVersion(c.DB, DriverSQLite, schemaName)
+}
+
+// ListSavedSearches reads the searches saved in the API ordered by id
+func (c *SQLiteConnection) ListSavedSearches(schemaName string) ([]models.SavedSearch, error) {
+	return listSavedSearches(c.DB, DriverSQLite, schemaName)
+}
+
+// MatchSavedSearch returns the ids of the emails that match the saved query
+func (c *SQLiteConnection) MatchSavedSearch(schemaName string, query models.SavedQuery) ([]uint32, error) {
+	return matchSavedSearch(c.DB, DriverSQLite, schemaName, query)
+}
+
+// SaveSavedSearchMatches records the emails not matched before by the saved search and returns them
+// alert: the new matches are pending alerts until MarkAlertsSent
+func (c *SQLiteConnection) SaveSavedSearchMatches(schemaName string, searchID, runID int64, emailIDs []uint32, matchedAt time.Time, alert bool) ([]uint32, error) {
+	return saveSavedSearchMatches(c.DB, DriverSQLite, schemaName, searchID, runID, emailIDs, matchedAt, alert)
+}
+
+// ListPendingAlerts returns the ids of the emails matched by the saved search that wait for their alert
+func (c *SQLiteConnection) ListPendingAlerts(schemaName string, searchID int64) ([]uint32, error) {
+	return listPendingAlerts(c.DB, DriverSQLite, schemaName, searchID)
+}
+
+// MarkAlertsSent removes the matches of the saved search from the pending alerts
+func (c *SQLiteConnection) MarkAlertsSent(schemaName string, searchID int64, emailIDs []uint32) error {
+	return markAlertsSent(c.DB, DriverSQLite, schemaName, searchID, emailIDs)
+}
+
+// StreamAnnotations reads the annotations ordered by email and id and calls fn for each one
+func (c *SQLiteConnection) StreamAnnotations(schemaName string, fn func(models.Annotation) error) error {
+	return streamAnnotations(c.DB, DriverSQLite, schemaName, fn)
+}
+
+// Ping checks if the database is reachable
+func (c *SQLiteConnection) Ping() error {
+	return Ping(c.DB)
+}
+
+// Close safely closes the database connection
+func (c *SQLiteConnection) Close() error {
+	return Close(c.DB)
+}
+
+// CreateSchemaIfNotExist applies the pending migrations of the schema
+// and registers it as a collection
+func (c *SQLiteConnection) CreateSchemaIfNotExist(schemaName string) error {
+	migrator, err := c.NewMigrator(schemaName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := migrator.Up(0); err != nil {
+		return fmt.Errorf("error migrating schema: %w", err)
+	}
+
+	return c.RegisterCollection(schemaName, "")
+}
+
+// NewMigrator returns the migrator of the schema
+func (c *SQLiteConnection) NewMigrator(schemaName string) (*Migrator, error) {
+	return NewMigrator(c.DB, DriverSQLite, schemaName)
+}
+
+// RegisterCollection adds the schema to the registry of collections
+func (c *SQLiteConnection) RegisterCollection(name, description string) error {
+	return registerCollection(c.DB, DriverSQLite, name, description)
+}
+
+// ListCollections returns the collections of the registry
+func (c *SQLiteConnection) ListCollections() ([]models.Collection, error) {
+	return listCollections(c.DB, DriverSQLite)
+}
+
+// IsSchemaCreated checks if the emails table of the schema exists
+func (c *SQLiteConnection) IsSchemaCreated(schemaName string) (bool, error) {
+	query := `SELECT EXISTS (
+    SELECT 1
+    FROM sqlite_master
+    WHERE type = 'table' AND name = ?
+	) AS schema_exists;`
+
+	var exists bool
+	err := c.DB.QueryRow(query, schemaName+"_emails").Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking if schema exists: %w", err)
+	}
+
+	return exists, nil
+}
+
+// createInsertQuery creates the insert query and validate tableName is correct
+func (c *SQLiteConnection) createInsertQuery(schemaName string, emails []models.Email) (query string, valueArgs []any, err error) {
+	valuesFlags := make([]string, 0, len(emails))
+	valueArgs = make([]any, 0, len(emails)*6)
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return "", nil, err
+	}
+
+	for _, e := range emails {
+		valuesFlags = append(valuesFlags, "(?, ?, ?, ?, ?, ?)")
+		valueArgs = append(valueArgs, e.ID, e.Date.UTC(), e.Subject, e.From, e.To, e.Content)
+	}
+
+	query = fmt.Sprintf(`
+		INSERT INTO %s (id, date, subject, "from", "to", content)
+		VALUES %s
+		ON CONFLICT (id) DO NOTHING;
+	`, sqliteTable(schemaName, "emails"), strings.Join(valuesFlags, ","))
+
+	return query, valueArgs, nil
+}
+
+// createSearchQuery creates the query to copy the emails of the batch to the search table
+// the emails already indexed are skipped because FTS5 has not ON CONFLICT
+func (c *SQLiteConnection) createSearchQuery(schemaName string, emails []models.Email) (query string, valueArgs []any) {
+	placeholders := make([]string, 0, len(emails))
+	valueArgs = make([]any, 0, len(emails))
+	for _, e := range emails {
+		placeholders = append(placeholders, "?")
+		valueArgs = append(valueArgs, e.ID)
+	}
+
+	emailsTable := sqliteTable(schemaName, "emails")
+	searchTable := sqliteTable(schemaName, "emails_search")
+	query = fmt.Sprintf(`
+		INSERT INTO %s (rowid, subject, "from", "to", content)
+		SELECT e.id, e.subject, e."from", e."to", e.content
+		FROM %s e
+		WHERE e.id IN (%s)
+		AND e.id NOT IN (SELECT rowid FROM %s);
+	`, searchTable, emailsTable, strings.Join(placeholders, ","), searchTable)
+
+	return query, valueArgs
+}
+
+// createSelectQuery creates the query to read the emails that match the filter
+func (c *SQLiteConnection) createSelectQuery(schemaName string, filter models.EmailFilter) (query string, valueArgs []any, err error) {
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return "", nil, err
+	}
+
+	conditions := make([]string, 0, 5)
+	valueArgs = make([]any, 0, 5)
+
+	if len(filter.IDs) > 0 {
+		placeholders := make([]string, 0, len(filter.IDs))
+		for _, id := range filter.IDs {
+			placeholders = append(placeholders, "?")
+			valueArgs = append(valueArgs, id)
+		}
+		conditions = append(conditions, fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	if filter.FromID > 0 {
+		conditions = append(conditions, "id >= ?")
+		valueArgs = append(valueArgs, filter.FromID)
+	}
+
+	if filter.ToID > 0 {
+		conditions = append(conditions, "id <= ?")
+		valueArgs = append(valueArgs, filter.ToID)
+	}
+
+	if filter.DateFrom != nil {
+		conditions = append(conditions, "date >= ?")
+		valueArgs = append(valueArgs, filter.DateFrom.UTC())
+	}
+
+	if filter.DateTo != nil {
+		conditions = append(conditions, "date <= ?")
+		valueArgs = append(valueArgs, filter.DateTo.UTC())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query = fmt.Sprintf(`
+		SELECT id, date, COALESCE(subject, ''), COALESCE("from", ''), COALESCE("to", ''), COALESCE(content, '')
+		FROM %s
+		%s
+		ORDER BY id;
+	`, sqliteTable(schemaName, "emails"), where)
+
+	return query, valueArgs, nil
+}
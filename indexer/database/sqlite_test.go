@@ -0,0 +1,78 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getSQLiteConn(t *testing.T) *SQLiteConnection {
+	conn, err := NewSQLiteConnection(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	assert.NoError(t, conn.CreateSchemaIfNotExist(DBSchemaNameTest))
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSQLiteIsSchemaCreated(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	exists, err := conn.IsSchemaCreated(DBSchemaNameTest)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = conn.IsSchemaCreated("not_created")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSQLiteSendMails(t *testing.T) {
+	conn := getSQLiteConn(t)
+	date := time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	testMails := []models.Email{
+		{ID: 1, Date: date, Subject: "this is a secret message", From: "Hillary Clinton", To: "Bill Clinton", Content: "running late"},
+		{ID: 2, Date: date.AddDate(0, 1, 0), Subject: "this is a private message", From: "Hillary Clinton", To: "Bill Clinton", Content: "see you"},
+		{ID: 2, Date: date.AddDate(0, 1, 0), Subject: "this is a private message", From: "Hillary Clinton", To: "Bill Clinton", Content: "see you"},
+	}
+
+	t.Run("Must insert 2 rows and skip the duplicated", func(t *testing.T) {
+		rows, err := conn.SendMails(DBSchemaNameTest, testMails)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), rows)
+
+		rows, err = conn.SendMails(DBSchemaNameTest, testMails[:1])
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), rows)
+
+		var searchTotal int64
+		conn.DB.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s;`, sqliteTable(DBSchemaNameTest, "emails_search"))).Scan(&searchTotal)
+		assert.Equal(t, int64(2), searchTotal)
+	})
+
+	t.Run("Must find the stemmed words", func(t *testing.T) {
+		var id int64
+		err := conn.DB.QueryRow(fmt.Sprintf(`SELECT rowid FROM %s WHERE %s MATCH 'run';`,
+			sqliteTable(DBSchemaNameTest, "emails_search"), sqliteTable(DBSchemaNameTest, "emails_search"))).Scan(&id)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), id)
+	})
+
+	t.Run("Must stream the emails filtered by date", func(t *testing.T) {
+		from := date.AddDate(0, 0, 1)
+		emails := make([]models.Email, 0)
+		err := conn.StreamEmails(DBSchemaNameTest, models.EmailFilter{DateFrom: &from}, func(e models.Email) error {
+			emails = append(emails, e)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, emails, 1)
+		assert.Equal(t, uint32(2), emails[0].ID)
+		assert.True(t, testMails[1].Date.Equal(emails[0].Date))
+	})
+}
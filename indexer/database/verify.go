@@ -0,0 +1,38 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// listEmailIDs reads the sorted ids of the emails of the schema between fromID and toID
+func listEmailIDs(db *sql.DB, driver, schemaName string, fromID, toID uint32) ([]uint32, error) {
+	if err := ValidateDBConnection(db); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateIsSafeString(schemaName); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id FROM %s
+		WHERE id >= $1 AND id <= $2
+		ORDER BY id;
+	`, schemaTable(driver, schemaName, "emails")), fromID, toID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email ids: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint32, 0)
+	for rows.Next() {
+		var id uint32
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan email id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
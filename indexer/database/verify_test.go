@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteListEmailIDs(t *testing.T) {
+	conn := getSQLiteConn(t)
+
+	emails := make([]models.Email, 0)
+	for _, id := range []uint32{1, 2, 5, 7, 12} {
+		emails = append(emails, models.Email{ID: id, Date: time.Now().UTC(), Subject: "subject", From: "from", To: "to", Content: "content"})
+	}
+	_, err := conn.SendMails(DBSchemaNameTest, emails)
+	assert.NoError(t, err)
+
+	ids, err := conn.ListEmailIDs(DBSchemaNameTest, 2, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{2, 5, 7}, ids)
+
+	assert.Equal(t, []models.IDRange{{From: 3, To: 4}, {From: 6, To: 6}, {From: 8, To: 9}}, models.FindGaps(ids, 2, 9))
+	assert.Equal(t, []models.IDRange{}, models.FindGaps(ids, 5, 5))
+	assert.Equal(t, []models.IDRange{{From: 1, To: 3}}, models.FindGaps(nil, 1, 3))
+}
@@ -0,0 +1,175 @@
+package entities
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"indexer/models"
+)
+
+//go:embed gazetteer/*.txt
+var gazetteerFiles embed.FS
+
+// gazetteers are the dictionary files by type of entity
+var gazetteers = map[string]string{
+	models.EntityTypePerson:       "gazetteer/people.txt",
+	models.EntityTypeOrganization: "gazetteer/organizations.txt",
+	models.EntityTypePlace:        "gazetteer/places.txt",
+}
+
+// namePattern is a sequence of capitalized words, ex: Cheryl Mills
+const namePattern = `[A-Z][a-zA-Z'\-]+(?: [A-Z][a-zA-Z'\-]+){0,2}`
+
+// patterns find the entities that are not in the gazetteers from the words around them
+// the first group is the name of the entity
+var patterns = []struct {
+	entityType string
+	pattern    *regexp.Regexp
+}{
+	{models.EntityTypePerson, regexp.MustCompile(`\b(?:Secretary|Ambassador|President|Senator|Minister|Prime Minister|Foreign Minister|General|Gen\.|Governor|Mr\.|Mrs\.|Ms\.|Dr\.) (` + namePattern + `)`)},
+	{models.EntityTypeOrganization, regexp.MustCompile(`\b((?:Ministry|Department|Embassy|Bureau|Office) of (?:the )?` + namePattern + `)`)},
+	{models.EntityTypeOrganization, regexp.MustCompile(`\b(` + namePattern + ` (?:Foundation|Council|Institute|Agency|Committee|Party|Bank|University|Corporation|Commission))\b`)},
+}
+
+// alias is a name of the gazetteer and the entity it refers to
+type alias struct {
+	entity     string
+	entityType string
+}
+
+// Extractor finds the people, organizations and places mentioned in the emails
+// the names of the gazetteers are matched first, then the patterns, the matches of the patterns
+// that overlap a name of the gazetteers are discarded
+type Extractor struct {
+	names   *regexp.Regexp
+	aliases map[string]alias
+}
+
+// NewExtractor creates an extractor with the gazetteers embedded in the binary
+func NewExtractor() (*Extractor, error) {
+	aliases := make(map[string]alias)
+	for entityType, file := range gazetteers {
+		content, err := gazetteerFiles.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading gazetteer %s: %w", file, err)
+		}
+
+		if err := parseGazetteer(string(content), entityType, aliases); err != nil {
+			return nil, fmt.Errorf("error parsing gazetteer %s: %w", file, err)
+		}
+	}
+
+	return newExtractor(aliases), nil
+}
+
+// parseGazetteer adds the names of the gazetteer to aliases
+// every line is an entity with its aliases separated by |, the lines starting with # are comments
+func parseGazetteer(content, entityType string, aliases map[string]alias) error {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		names := strings.Split(line, "|")
+		entity := strings.TrimSpace(names[0])
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			aliases[name] = alias{entity: entity, entityType: entityType}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// newExtractor builds the regular expression of the names, the longest names are tried first
+func newExtractor(aliases map[string]alias) *Extractor {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+
+	// the names ending in a dot can't use \b after them, ex: U.S.
+	return &Extractor{
+		names:   regexp.MustCompile(`(?:^|[^\w])(` + strings.Join(quoted, "|") + `)(?:[^\w]|$)`),
+		aliases: aliases,
+	}
+}
+
+// Extract returns the entities of the field of the email ordered by offset
+// the offsets are in bytes of the text of the field
+func (e *Extractor) Extract(emailID uint32, field, text string) []models.EmailEntity {
+	found := make([]models.EmailEntity, 0)
+	for _, match := range e.findNames(text) {
+		a := e.aliases[text[match[0]:match[1]]]
+		found = append(found, models.EmailEntity{EmailID: emailID, Entity: a.entity, Type: a.entityType, Field: field, Start: match[0], End: match[1]})
+	}
+
+	for _, p := range patterns {
+		for _, match := range p.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := match[2], match[3]
+			if overlaps(found, start, end) {
+				continue
+			}
+
+			found = append(found, models.EmailEntity{EmailID: emailID, Entity: text[start:end], Type: p.entityType, Field: field, Start: start, End: end})
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Start < found[j].Start })
+	return found
+}
+
+// ExtractEmail returns the entities of the subject and the content of the email
+func (e *Extractor) ExtractEmail(email models.Email) []models.EmailEntity {
+	found := e.Extract(email.ID, models.EntityFieldSubject, email.Subject)
+	return append(found, e.Extract(email.ID, models.EntityFieldContent, email.Content)...)
+}
+
+// findNames returns the offsets of the names of the gazetteers in the text
+// the separators around the names are part of the match, the search continues after the name
+func (e *Extractor) findNames(text string) [][]int {
+	matches := make([][]int, 0)
+	for offset := 0; offset < len(text); {
+		match := e.names.FindStringSubmatchIndex(text[offset:])
+		if match == nil {
+			break
+		}
+
+		matches = append(matches, []int{offset + match[2], offset + match[3]})
+		offset += match[3]
+	}
+
+	return matches
+}
+
+// overlaps checks if the range overlaps an entity already found
+func overlaps(found []models.EmailEntity, start, end int) bool {
+	for _, entity := range found {
+		if start < entity.End && entity.Start < end {
+			return true
+		}
+	}
+
+	return false
+}
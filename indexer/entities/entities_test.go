@@ -0,0 +1,48 @@
+package entities
+
+import (
+	"testing"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	extractor, err := NewExtractor()
+	assert.NoError(t, err)
+
+	text := "Cheryl Mills spoke with Ambassador Feltmann about Benghazi and the U.S. Embassy of the Netherlands, the Gates Foundation and USAID."
+	found := extractor.Extract(1234, models.EntityFieldContent, text)
+
+	names := make([]string, 0, len(found))
+	for _, entity := range found {
+		assert.Equal(t, uint32(1234), entity.EmailID)
+		assert.Equal(t, models.EntityFieldContent, entity.Field)
+		names = append(names, entity.Type+":"+entity.Entity+":"+text[entity.Start:entity.End])
+	}
+
+	assert.Equal(t, []string{
+		"person:Cheryl Mills:Cheryl Mills",
+		"person:Feltmann:Feltmann",
+		"place:Benghazi:Benghazi",
+		"place:United States:U.S.",
+		"organization:Embassy of the Netherlands:Embassy of the Netherlands",
+		"organization:Gates Foundation:Gates Foundation",
+		"organization:USAID:USAID",
+	}, names)
+}
+
+func TestExtractWholeWords(t *testing.T) {
+	extractor, err := NewExtractor()
+	assert.NoError(t, err)
+
+	// Iran is not found inside Iranian and UN inside UNICEF
+	assert.Empty(t, extractor.Extract(1, models.EntityFieldSubject, "Iranian UNICEF"))
+
+	found := extractor.ExtractEmail(models.Email{ID: 7, Subject: "Libya", Content: "Call from Secretary Clinton"})
+	assert.Len(t, found, 2)
+	assert.Equal(t, models.EntityFieldSubject, found[0].Field)
+	assert.Equal(t, "Libya", found[0].Entity)
+	assert.Equal(t, "Hillary Clinton", found[1].Entity)
+}
@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"indexer/models"
+)
+
+// AnnotationsWriter writes one annotation per line in JSON format
+// the annotations are exported in their own file next to the emails to keep the formats of the emails standard
+type AnnotationsWriter struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewAnnotationsWriter creates the file and returns an AnnotationsWriter
+func NewAnnotationsWriter(path string) (*AnnotationsWriter, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bufio.NewWriter(file)
+	return &AnnotationsWriter{file: file, buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+}
+
+// AnnotationsPath returns the file of the annotations of an export
+// emails.jsonl is exported with emails.annotations.jsonl, a directory of .eml files with annotations.jsonl inside it
+func AnnotationsPath(format Format, out string) string {
+	if format == FormatEML {
+		return filepath.Join(out, "annotations.jsonl")
+	}
+
+	return strings.TrimSuffix(out, filepath.Ext(out)) + ".annotations.jsonl"
+}
+
+// Write writes the annotation as a JSON line
+func (w *AnnotationsWriter) Write(annotation models.Annotation) error {
+	if err := w.encoder.Encode(annotation); err != nil {
+		return fmt.Errorf("error encoding annotation %d: %w", annotation.ID, err)
+	}
+
+	return nil
+}
+
+// Close flushes the buffer and closes the file
+func (w *AnnotationsWriter) Close() error {
+	if err := w.buffer.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("error flushing file: %w", err)
+	}
+
+	return w.file.Close()
+}
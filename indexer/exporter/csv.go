@@ -0,0 +1,64 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"indexer/models"
+)
+
+var csvHeader = []string{"id", "date", "subject", "from", "to", "content"}
+
+// CSVWriter writes the emails as rows of a CSV file with header
+type CSVWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+// NewCSVWriter creates the file, writes the header and returns a CSVWriter
+func NewCSVWriter(path string) (*CSVWriter, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(csvHeader); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error writing csv header: %w", err)
+	}
+
+	return &CSVWriter{file: file, writer: writer}, nil
+}
+
+// Write writes the email as a CSV row
+func (w *CSVWriter) Write(email models.Email) error {
+	record := []string{
+		strconv.FormatUint(uint64(email.ID), 10),
+		email.Date.UTC().Format(time.RFC3339),
+		email.Subject,
+		email.From,
+		email.To,
+		email.Content,
+	}
+
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("error writing email %d: %w", email.ID, err)
+	}
+
+	return nil
+}
+
+// Close flushes the writer and closes the file
+func (w *CSVWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("error flushing file: %w", err)
+	}
+
+	return w.file.Close()
+}
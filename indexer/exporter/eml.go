@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"indexer/models"
+)
+
+const (
+	messageIDDomain = "clinton-emails.wikileaks.org"
+	unknownAddress  = "unknown@clinton-emails.invalid" // .invalid is reserved, it never resolves
+	emlDateLayout   = "Mon, 02 Jan 2006 15:04:05 -0700"
+)
+
+// EMLWriter writes each email in a .eml file named with the email id
+type EMLWriter struct {
+	directory string
+}
+
+// NewEMLWriter creates the directory and returns an EMLWriter
+func NewEMLWriter(directory string) (*EMLWriter, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory %s: %w", directory, err)
+	}
+
+	return &EMLWriter{directory: directory}, nil
+}
+
+// Write writes the email in <directory>/<id>.eml
+func (w *EMLWriter) Write(email models.Email) error {
+	filename := filepath.Join(w.directory, strconv.FormatUint(uint64(email.ID), 10)+".eml")
+	if err := os.WriteFile(filename, BuildMessage(email), 0644); err != nil {
+		return fmt.Errorf("error writing email %d: %w", email.ID, err)
+	}
+
+	return nil
+}
+
+// Close does nothing, every email is written in its own file
+func (w *EMLWriter) Close() error {
+	return nil
+}
+
+// BuildMessage builds an RFC 5322 message with CRLF line endings
+// the content is sent as text/html encoded in quoted-printable
+func BuildMessage(email models.Email) []byte {
+	var buf bytes.Buffer
+
+	writeHeader := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+
+	writeHeader("Message-ID", MessageID(email.ID))
+	writeHeader("Date", email.Date.UTC().Format(emlDateLayout))
+	writeHeader("From", formatFrom(email.From))
+	writeHeader("To", formatTo(email.To))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", sanitizeHeader(email.Subject)))
+	writeHeader("X-Email-ID", strconv.FormatUint(uint64(email.ID), 10))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", `text/html; charset="utf-8"`)
+	writeHeader("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(email.Content))
+	qp.Close()
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
+
+// MessageID returns the Message-ID of the email
+func MessageID(id uint32) string {
+	return fmt.Sprintf("<%d@%s>", id, messageIDDomain)
+}
+
+// formatFrom formats the sender, if it is not a valid address
+// the text is kept as display name of an unknown address
+func formatFrom(value string) string {
+	value = sanitizeHeader(value)
+	if address, err := mail.ParseAddress(value); err == nil {
+		return address.String()
+	}
+
+	return (&mail.Address{Name: value, Address: unknownAddress}).String()
+}
+
+// formatTo formats the recipients, if they are empty returns an empty group
+func formatTo(value string) string {
+	value = sanitizeHeader(value)
+	if value == "" {
+		return "undisclosed-recipients:;"
+	}
+
+	if list, err := mail.ParseAddressList(value); err == nil {
+		addresses := make([]string, 0, len(list))
+		for _, address := range list {
+			addresses = append(addresses, address.String())
+		}
+		return strings.Join(addresses, ", ")
+	}
+
+	names := strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' })
+	addresses := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		addresses = append(addresses, (&mail.Address{Name: name, Address: unknownAddress}).String())
+	}
+
+	if len(addresses) == 0 {
+		return "undisclosed-recipients:;"
+	}
+
+	return strings.Join(addresses, ", ")
+}
+
+// sanitizeHeader removes line breaks to avoid injecting headers
+func sanitizeHeader(value string) string {
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	return strings.TrimSpace(value)
+}
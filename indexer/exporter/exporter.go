@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+
+	"indexer/models"
+)
+
+// Format represents the output format of an export
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+	FormatEML   Format = "eml"
+	FormatMbox  Format = "mbox"
+)
+
+// NewFormat creates a Format from a string
+// returns an error if the format is not supported
+func NewFormat(format string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(format)))
+	if !f.IsValid() {
+		return "", fmt.Errorf("format not supported: %s", format)
+	}
+
+	return f, nil
+}
+
+func (f Format) IsValid() bool {
+	return f == FormatJSONL ||
+		f == FormatCSV ||
+		f == FormatEML ||
+		f == FormatMbox
+}
+
+// Writer writes emails to an export destination
+// Write: writes one email
+// Close: flushes the pending data and closes the destination
+type Writer interface {
+	Write(email models.Email) error
+	Close() error
+}
+
+// NewWriter creates the writer of the format
+// path: file to write, for FormatEML is the directory where the .eml files are created
+func NewWriter(format Format, path string) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return NewJSONLWriter(path)
+	case FormatCSV:
+		return NewCSVWriter(path)
+	case FormatEML:
+		return NewEMLWriter(path)
+	case FormatMbox:
+		return NewMboxWriter(path)
+	default:
+		return nil, fmt.Errorf("format not supported: %s", format)
+	}
+}
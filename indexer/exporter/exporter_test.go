@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEmail = models.Email{
+	ID:      42,
+	Date:    time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC),
+	Subject: "Libya – update",
+	From:    "Jake Sullivan",
+	To:      "H; Huma Abedin",
+	Content: "<p>From the embassy</p>\nFrom now on we meet daily",
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg, err := mail.ReadMessage(bytes.NewReader(BuildMessage(testEmail)))
+	assert.NoError(t, err)
+
+	t.Run("Must be parseable headers", func(t *testing.T) {
+		assert.Equal(t, "<42@clinton-emails.wikileaks.org>", msg.Header.Get("Message-ID"))
+
+		date, err := msg.Header.Date()
+		assert.NoError(t, err)
+		assert.True(t, testEmail.Date.Equal(date))
+
+		from, err := msg.Header.AddressList("From")
+		assert.NoError(t, err)
+		assert.Equal(t, "Jake Sullivan", from[0].Name)
+
+		to, err := msg.Header.AddressList("To")
+		assert.NoError(t, err)
+		assert.Len(t, to, 2)
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		assert.NoError(t, err)
+		assert.Equal(t, testEmail.Subject, subject)
+	})
+
+	t.Run("Must be the same content with CRLF line breaks", func(t *testing.T) {
+		body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		assert.NoError(t, err)
+		content := strings.ReplaceAll(testEmail.Content, "\n", "\r\n")
+		assert.Equal(t, content, strings.TrimRight(string(body), "\r\n"))
+	})
+
+	t.Run("Must be an empty group without recipients", func(t *testing.T) {
+		email := testEmail
+		email.To = ""
+		msg, err := mail.ReadMessage(bytes.NewReader(BuildMessage(email)))
+		assert.NoError(t, err)
+		assert.Equal(t, "undisclosed-recipients:;", msg.Header.Get("To"))
+	})
+}
+
+func TestBuildMboxEntry(t *testing.T) {
+	entry := string(BuildMboxEntry(testEmail))
+
+	assert.True(t, strings.HasPrefix(entry, "From MAILER-DAEMON Mon Mar 14 09:30:00 2011\n"))
+	assert.NotContains(t, entry, "\r\n")
+	assert.Contains(t, entry, "\n>From now on")
+	assert.True(t, strings.HasSuffix(entry, "\n\n"))
+}
+
+func TestWriters(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, format := range []Format{FormatJSONL, FormatCSV, FormatEML, FormatMbox} {
+		t.Run("Must write "+string(format), func(t *testing.T) {
+			path := filepath.Join(dir, "emails."+string(format))
+			writer, err := NewWriter(format, path)
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Write(testEmail))
+			assert.NoError(t, writer.Close())
+
+			if format == FormatEML {
+				path = filepath.Join(path, "42.eml")
+			}
+
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.NotZero(t, info.Size())
+		})
+	}
+}
+
+func TestAnnotationsWriter(t *testing.T) {
+	assert.Equal(t, filepath.Join("data", "emails.annotations.jsonl"), AnnotationsPath(FormatCSV, filepath.Join("data", "emails.csv")))
+	assert.Equal(t, filepath.Join("data", "eml", "annotations.jsonl"), AnnotationsPath(FormatEML, filepath.Join("data", "eml")))
+
+	path := filepath.Join(t.TempDir(), "emails.annotations.jsonl")
+	writer, err := NewAnnotationsWriter(path)
+	assert.NoError(t, err)
+
+	start, end := 5, 12
+	assert.NoError(t, writer.Write(models.Annotation{ID: 1, EmailID: 42, Author: "analyst", Note: "the embassy", Start: &start, End: &end}))
+	assert.NoError(t, writer.Write(models.Annotation{ID: 2, EmailID: 42, Author: "reviewer", Note: "follow up"}))
+	assert.NoError(t, writer.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"start":5,"end":12`)
+	assert.NotContains(t, lines[1], `"start"`)
+}
@@ -0,0 +1,23 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// createFile creates the file and its directory if it doesn't exist
+func createFile(path string) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("error creating directory %s: %w", dir, err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file %s: %w", path, err)
+	}
+
+	return file, nil
+}
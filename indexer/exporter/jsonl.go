@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"indexer/models"
+)
+
+// JSONLWriter writes one email per line in JSON format
+type JSONLWriter struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewJSONLWriter creates the file and returns a JSONLWriter
+func NewJSONLWriter(path string) (*JSONLWriter, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bufio.NewWriter(file)
+	return &JSONLWriter{file: file, buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+}
+
+// Write writes the email as a JSON line
+func (w *JSONLWriter) Write(email models.Email) error {
+	if err := w.encoder.Encode(email); err != nil {
+		return fmt.Errorf("error encoding email %d: %w", email.ID, err)
+	}
+
+	return nil
+}
+
+// Close flushes the buffer and closes the file
+func (w *JSONLWriter) Close() error {
+	if err := w.buffer.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("error flushing file: %w", err)
+	}
+
+	return w.file.Close()
+}
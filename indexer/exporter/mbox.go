@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	"indexer/models"
+)
+
+const mboxDateLayout = "Mon Jan _2 15:04:05 2006"
+
+// fromLine matches the lines that must be quoted in the mboxrd format
+var fromLine = regexp.MustCompile(`^>*From `)
+
+// MboxWriter writes all the emails in a single mbox file using the mboxrd variant
+type MboxWriter struct {
+	file   *os.File
+	buffer *bufio.Writer
+}
+
+// NewMboxWriter creates the file and returns a MboxWriter
+func NewMboxWriter(path string) (*MboxWriter, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MboxWriter{file: file, buffer: bufio.NewWriter(file)}, nil
+}
+
+// Write appends the email to the mbox file
+func (w *MboxWriter) Write(email models.Email) error {
+	if _, err := w.buffer.Write(BuildMboxEntry(email)); err != nil {
+		return fmt.Errorf("error writing email %d: %w", email.ID, err)
+	}
+
+	return nil
+}
+
+// Close flushes the buffer and closes the file
+func (w *MboxWriter) Close() error {
+	if err := w.buffer.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("error flushing file: %w", err)
+	}
+
+	return w.file.Close()
+}
+
+// BuildMboxEntry builds the mbox entry of the email
+// the message uses LF line endings and the lines starting with "From " are quoted
+func BuildMboxEntry(email models.Email) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From MAILER-DAEMON " + email.Date.UTC().Format(mboxDateLayout) + "\n")
+
+	message := bytes.ReplaceAll(BuildMessage(email), []byte("\r\n"), []byte("\n"))
+	for _, line := range bytes.SplitAfter(message, []byte("\n")) {
+		if fromLine.Match(line) {
+			buf.WriteByte('>')
+		}
+		buf.Write(line)
+	}
+
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"indexer/models"
+)
+
+const unknownAddress = "unknown@clinton-emails.invalid" // address used by the exporter when the original is not valid
+
+var messageIDPattern = regexp.MustCompile(`^<(\d+)@`)
+
+// readEML reads a .eml file or every .eml file of a directory
+func readEML(path string, emailsQueue chan<- models.EmailResult) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.eml"))
+		if err != nil {
+			return fmt.Errorf("error listing %s: %w", path, err)
+		}
+		sort.Strings(files)
+	}
+
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			sendEmail(emailsQueue, nil, file, "", err)
+			continue
+		}
+
+		email, err := ParseMessage(raw)
+		sendEmail(emailsQueue, email, filepath.Base(file), string(raw), err)
+	}
+
+	return nil
+}
+
+// ParseMessage parses an RFC 5322 message into an email
+// the id is read from the X-Email-ID header or the Message-ID written by the exporter
+func ParseMessage(raw []byte) (*models.Email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing message: %w", err)
+	}
+
+	id, err := parseID(msg.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := msg.Header.Date()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing date: %w", err)
+	}
+
+	decoder := new(mime.WordDecoder)
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding subject: %w", err)
+	}
+
+	content, err := readBody(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Email{
+		ID:      id,
+		Date:    date.UTC(),
+		Subject: subject,
+		From:    parseAddresses(msg.Header, "From"),
+		To:      parseAddresses(msg.Header, "To"),
+		Content: content,
+	}, nil
+}
+
+// parseID reads the id of the email from the headers
+func parseID(header mail.Header) (uint32, error) {
+	value := strings.TrimSpace(header.Get("X-Email-ID"))
+	if value == "" {
+		matches := messageIDPattern.FindStringSubmatch(strings.TrimSpace(header.Get("Message-ID")))
+		if len(matches) < 2 {
+			return 0, errors.New("the message has not X-Email-ID header or a numeric Message-ID")
+		}
+		value = matches[1]
+	}
+
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %s: %w", value, err)
+	}
+
+	return uint32(id), nil
+}
+
+// parseAddresses returns the addresses of the header as text
+// the addresses created by the exporter for invalid values are returned as the original name
+func parseAddresses(header mail.Header, key string) string {
+	value := header.Get(key)
+	list, err := header.AddressList(key)
+	if err != nil {
+		if value == "undisclosed-recipients:;" {
+			return ""
+		}
+		decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+		if err != nil {
+			return value
+		}
+		return decoded
+	}
+
+	addresses := make([]string, 0, len(list))
+	for _, address := range list {
+		switch {
+		case address.Address == unknownAddress:
+			addresses = append(addresses, address.Name)
+		case address.Name == "":
+			addresses = append(addresses, address.Address)
+		default:
+			addresses = append(addresses, address.Name+" <"+address.Address+">")
+		}
+	}
+
+	return strings.Join(addresses, ", ")
+}
+
+// readBody reads the body decoding the Content-Transfer-Encoding
+func readBody(msg *mail.Message) (string, error) {
+	var reader io.Reader = msg.Body
+	switch strings.ToLower(strings.TrimSpace(msg.Header.Get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		reader = quotedprintable.NewReader(reader)
+	case "base64":
+		reader = base64.NewDecoder(base64.StdEncoding, reader)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("error reading body: %w", err)
+	}
+
+	content := strings.ReplaceAll(string(body), "\r\n", "\n")
+	return strings.TrimRight(content, "\n"), nil
+}
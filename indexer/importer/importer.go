@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"indexer/models"
+)
+
+// Format represents the format of a dump to import
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatEML   Format = "eml"
+	FormatMbox  Format = "mbox"
+)
+
+func (f Format) IsValid() bool {
+	return f == FormatJSONL ||
+		f == FormatEML ||
+		f == FormatMbox
+}
+
+// DetectFormat returns the format of the path
+// format: format requested by the user, if empty it is detected from the path
+// a directory is read as .eml files, .mbox as mbox and any other file as jsonl
+func DetectFormat(format string, path string) (Format, error) {
+	if format != "" {
+		f := Format(strings.ToLower(strings.TrimSpace(format)))
+		if !f.IsValid() {
+			return "", fmt.Errorf("format not supported: %s", format)
+		}
+		return f, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	if info.IsDir() {
+		return FormatEML, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mbox", ".mbx":
+		return FormatMbox, nil
+	case ".eml":
+		return FormatEML, nil
+	default:
+		return FormatJSONL, nil
+	}
+}
+
+// Read reads the dump and sends every email to emailsQueue
+// the emails that are not valid are sent with an error to be counted by the indexer
+// emailsQueue is closed when the dump is read
+func Read(format Format, path string, emailsQueue chan<- models.EmailResult) error {
+	defer close(emailsQueue)
+
+	switch format {
+	case FormatJSONL:
+		return readJSONL(path, emailsQueue)
+	case FormatEML:
+		return readEML(path, emailsQueue)
+	case FormatMbox:
+		return readMbox(path, emailsQueue)
+	default:
+		return fmt.Errorf("format not supported: %s", format)
+	}
+}
+
+// sendEmail validates the email and sends it to the queue
+// source: position of the email in the dump, used in the error message
+// raw: the email as it is in the dump, stored in the dead letters if the email is not valid
+func sendEmail(emailsQueue chan<- models.EmailResult, email *models.Email, source string, raw string, err error) {
+	if err == nil {
+		err = email.Validate()
+	}
+
+	if err != nil {
+		emailsQueue <- models.EmailResult{Email: email, Error: fmt.Errorf("%s: %w", source, err), Stage: models.StageImport, Raw: raw}
+		return
+	}
+
+	emailsQueue <- models.EmailResult{Email: email}
+}
+
+// ParseRaw parses an email as it is in a dump, a JSON line or an RFC 5322 message
+// used to replay the dead letters of the import
+func ParseRaw(raw string) (*models.Email, error) {
+	if strings.HasPrefix(strings.TrimSpace(raw), "{") {
+		email := &models.Email{}
+		if err := json.Unmarshal([]byte(raw), email); err != nil {
+			return nil, fmt.Errorf("error parsing JSON: %w", err)
+		}
+		return email, nil
+	}
+
+	return ParseMessage([]byte(raw))
+}
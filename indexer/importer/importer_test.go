@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"indexer/exporter"
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEmails = []models.Email{
+	{
+		ID:      1,
+		Date:    time.Date(2010, 5, 1, 10, 0, 0, 0, time.UTC),
+		Subject: "this is a testing email",
+		From:    "Hillary Clinton <hillary@clinton.com>",
+		To:      "Bill Clinton <bill@clinton.com>",
+		Content: "<p>this is a testing email content</p>\nFrom the desk of H",
+	},
+	{
+		ID:      2,
+		Date:    time.Date(2011, 3, 14, 9, 30, 0, 0, time.UTC),
+		Subject: "Libya – update",
+		From:    "Jake Sullivan",
+		To:      "H",
+		Content: "<p>this is a secret message content</p>",
+	},
+}
+
+// readAll reads the dump and returns the emails and the errors
+func readAll(t *testing.T, format Format, path string) ([]models.Email, []error) {
+	emailsCh := make(chan models.EmailResult)
+	go func() {
+		assert.NoError(t, Read(format, path, emailsCh))
+	}()
+
+	emails := make([]models.Email, 0)
+	errs := make([]error, 0)
+	for result := range emailsCh {
+		if result.Error != nil {
+			errs = append(errs, result.Error)
+			continue
+		}
+		emails = append(emails, *result.Email)
+	}
+
+	return emails, errs
+}
+
+func TestRoundTrip(t *testing.T) {
+	formats := map[Format]exporter.Format{
+		FormatJSONL: exporter.FormatJSONL,
+		FormatEML:   exporter.FormatEML,
+		FormatMbox:  exporter.FormatMbox,
+	}
+
+	for format, exportFormat := range formats {
+		t.Run("Must read the same emails from "+string(format), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "emails."+string(format))
+			writer, err := exporter.NewWriter(exportFormat, path)
+			assert.NoError(t, err)
+			for _, email := range testEmails {
+				assert.NoError(t, writer.Write(email))
+			}
+			assert.NoError(t, writer.Close())
+
+			detected, err := DetectFormat("", path)
+			assert.NoError(t, err)
+			assert.Equal(t, format, detected)
+
+			emails, errs := readAll(t, format, path)
+			assert.Empty(t, errs)
+			assert.Len(t, emails, len(testEmails))
+			for i, email := range emails {
+				assert.Equal(t, testEmails[i].ID, email.ID)
+				assert.True(t, testEmails[i].Date.Equal(email.Date))
+				assert.Equal(t, testEmails[i].Subject, email.Subject)
+				assert.Equal(t, testEmails[i].From, email.From)
+				assert.Equal(t, testEmails[i].To, email.To)
+				assert.Equal(t, testEmails[i].Content, email.Content)
+			}
+		})
+	}
+}
+
+func TestInvalidEmails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emails.jsonl")
+	writer, err := exporter.NewWriter(exporter.FormatJSONL, path)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(testEmails[0]))
+	assert.NoError(t, writer.Write(models.Email{ID: 0, Date: time.Now()}))
+	assert.NoError(t, writer.Write(models.Email{ID: 3}))
+	assert.NoError(t, writer.Close())
+
+	emails, errs := readAll(t, FormatJSONL, path)
+	assert.Len(t, emails, 1)
+	assert.Len(t, errs, 2)
+}
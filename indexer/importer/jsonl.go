@@ -0,0 +1,44 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"indexer/models"
+)
+
+const maxLineSize = 64 * 1024 * 1024 // the content of some emails is bigger than the default buffer
+
+// readJSONL reads a file with one JSON email per line
+func readJSONL(path string, emailsQueue chan<- models.EmailResult) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		email := &models.Email{}
+		err := json.Unmarshal([]byte(text), email)
+		sendEmail(emailsQueue, email, fmt.Sprintf("line %d", line), text, err)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"indexer/models"
+)
+
+// quotedFromLine matches the lines quoted by the mboxrd format
+var quotedFromLine = regexp.MustCompile(`^>+From `)
+
+// readMbox reads an mbox archive, the quoted "From " lines are unquoted as in the mboxrd format
+func readMbox(path string, emailsQueue chan<- models.EmailResult) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var message bytes.Buffer
+	total := 0
+	previousBlank := true
+
+	flush := func() {
+		if message.Len() == 0 {
+			return
+		}
+		total++
+		email, err := ParseMessage(message.Bytes())
+		sendEmail(emailsQueue, email, fmt.Sprintf("message %d", total), message.String(), err)
+		message.Reset()
+	}
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case previousBlank && bytes.HasPrefix(line, []byte("From ")):
+				flush()
+			case quotedFromLine.Match(line):
+				message.Write(line[1:])
+			default:
+				message.Write(line)
+			}
+			previousBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+	}
+
+	flush()
+	return nil
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"indexer/cmd"
 	"indexer/config"
 	"indexer/database"
 	"indexer/logger"
+	"indexer/metrics"
+	"indexer/notifier"
+	"indexer/scraper"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -26,7 +34,38 @@ func main() {
 	}
 	defer db.Close()
 
+	if address := config.GetConfig().Metrics.Address; address != "" {
+		metricsServer := metrics.StartServer(address)
+		defer metricsServer.Close()
+	}
+
 	c := cmd.NewCmd(db, config.GetConfig().Scrapper.Parallelism, config.GetConfig().Scrapper.Delay)
-	c.Execute()
+	c.SetPipelineConfig(scraper.PipelineConfig{
+		ListingWorkers: config.GetConfig().Scrapper.ListingWorkers,
+		ParseWorkers:   config.GetConfig().Scrapper.ParseWorkers,
+		ContentWorkers: config.GetConfig().Scrapper.ContentWorkers,
+		InsertWorkers:  config.GetConfig().Scrapper.InsertWorkers,
+		QueueSize:      config.GetConfig().Scrapper.QueueSize,
+	})
+
+	if webhookURL := config.GetConfig().SavedSearches.WebhookURL; webhookURL != "" {
+		webhook, err := notifier.NewWebhook(webhookURL, config.GetConfig().SavedSearches.WebhookTimeout)
+		if err != nil {
+			log.Error("Saved search alerts disabled: ", err)
+		} else {
+			c.SetWebhook(webhook)
+		}
+	}
 
+	// the first signal stops the cli and the running index, a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c.Execute(ctx)
+	stop()
+
+	log.Info("Shutting down")
+	if err := c.Shutdown(config.GetConfig().ShutdownTimeout); err != nil {
+		log.Error(err)
+	}
 }
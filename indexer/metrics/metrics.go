@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"indexer/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+const namespace = "indexer"
+
+var (
+	// PagesByState is the number of pages in each state of the current status
+	PagesByState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pages",
+		Help:      "Number of pages by state",
+	}, []string{"state"})
+
+	// EmailsScraped is the number of emails read from the listing pages
+	EmailsScraped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_scraped_total",
+		Help:      "Total number of emails scraped",
+	})
+
+	// ContentFetchErrors is the number of errors fetching the content of an email
+	ContentFetchErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "content_fetch_errors_total",
+		Help:      "Total number of errors fetching the email content",
+	})
+
+	// SendMailsDuration is the duration of each SendMails batch
+	SendMailsDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "send_mails_duration_seconds",
+		Help:      "Duration of the SendMails batches",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"result"})
+
+	// RowsInserted is the number of rows inserted in the emails table
+	RowsInserted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_inserted_total",
+		Help:      "Total number of rows inserted",
+	})
+
+	// RowsConflicts is the number of rows skipped because they already exist
+	RowsConflicts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_conflicts_total",
+		Help:      "Total number of rows skipped by a conflict in the insert",
+	})
+
+	// DeadLetters is the number of emails rejected by the pipeline
+	DeadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dead_letters_total",
+		Help:      "Total number of emails sent to the dead letters by stage",
+	}, []string{"stage"})
+
+	// PipelineWorkers is the number of workers of each stage of the scrape pipeline
+	PipelineWorkers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pipeline_workers",
+		Help:      "Number of workers by stage of the scrape pipeline",
+	}, []string{"stage"})
+
+	// PipelineQueueLength is the number of items waiting in the queue of each stage
+	PipelineQueueLength = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pipeline_queue_length",
+		Help:      "Number of items waiting in the queue of the stage",
+	}, []string{"stage"})
+
+	// PipelineQueueCapacity is the size of the queue of each stage
+	PipelineQueueCapacity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pipeline_queue_capacity",
+		Help:      "Size of the queue of the stage",
+	}, []string{"stage"})
+
+	// PipelineBlockedSeconds is the time the previous stage waited for space in the queue of each stage
+	PipelineBlockedSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pipeline_blocked_seconds_total",
+		Help:      "Time waiting for space in the queue of the stage, the backpressure of the stage",
+	}, []string{"stage"})
+
+	// CollectorParallelism is the parallelism configured in the current collector
+	CollectorParallelism = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "collector_parallelism",
+		Help:      "Current parallelism of the collector",
+	})
+)
+
+// ObserveSendMails records the duration of a SendMails batch
+// start: time when the batch started
+// err: error returned by SendMails if any
+func ObserveSendMails(start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	SendMailsDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveQueue records the length of the queue of the stage and the time the sender waited for space
+// start: time when the sender started to wait
+func ObserveQueue(stage string, length int, start time.Time) {
+	PipelineBlockedSeconds.WithLabelValues(stage).Add(time.Since(start).Seconds())
+	PipelineQueueLength.WithLabelValues(stage).Set(float64(length))
+}
+
+// SetPagesByState updates the pages gauge from the status map
+func SetPagesByState(status map[string]models.PageResult) {
+	counts := map[models.PageResultStateType]int{
+		models.PageResultStatePending:     0,
+		models.PageResultStateProcessing:  0,
+		models.PageResultStateFinished:    0,
+		models.PageResultStateInterrupted: 0,
+	}
+
+	for _, page := range status {
+		counts[page.State]++
+	}
+
+	for state, total := range counts {
+		PagesByState.WithLabelValues(string(state)).Set(float64(total))
+	}
+}
+
+// StartServer starts an http listener that exposes /metrics
+// address: address to listen, ex: :2112
+// returns the server to be able to close it
+func StartServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Info("Metrics server listening on ", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Error in metrics server: ", err)
+		}
+	}()
+
+	return server
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Annotation represents a note of a user of the API on an email
+// Start and End: range of characters of the content, nil if the note is on the whole email
+type Annotation struct {
+	ID        int64     `json:"id"`
+	EmailID   uint32    `json:"emailId"`
+	Author    string    `json:"author"`
+	Note      string    `json:"note"`
+	Start     *int      `json:"start,omitempty"`
+	End       *int      `json:"end,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Collection represents a corpus of emails stored in its own schema
+// Name: name of the collection, it is the name of the schema
+// Description: description of the corpus
+// CreatedAt: date when the collection was registered
+type Collection struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Stages of the pipeline where an email can be rejected
+const (
+	StageScrape = "scrape" // the row of the listing page can't be parsed
+	StageImport = "import" // the email of the dump can't be parsed or is not valid
+	StageInsert = "insert" // the database rejected the email
+	// StageMissing is an id missing in the collection found by verify, it is fetched directly on replay
+	StageMissing = "missing"
+)
+
+// DeadLetter represents an email rejected by the pipeline
+// ID: id of the dead letter
+// EmailID: id of the email, 0 if it is unknown
+// Stage: stage of the pipeline where the email was rejected
+// Error: error returned by the stage
+// Payload: the email as JSON, empty if it couldn't be parsed
+// Raw: input of the stage, the HTML row or the message of the dump
+// CreatedAt: date when the email was rejected
+// ReplayedAt: date when the email was sent again to the pipeline, nil if it is pending
+type DeadLetter struct {
+	ID         int64      `json:"id"`
+	EmailID    uint32     `json:"emailId"`
+	Stage      string     `json:"stage"`
+	Error      string     `json:"error"`
+	Payload    string     `json:"payload"`
+	Raw        string     `json:"raw"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReplayedAt *time.Time `json:"replayedAt"`
+}
+
+// DeadLetterFilter represents the filters to read dead letters
+// Stage: only this stage, ignored if empty
+// EmailIDs: only these emails, ignored if empty
+// IncludeReplayed: include the dead letters already replayed
+// Limit: maximum number of dead letters, ignored if 0
+type DeadLetterFilter struct {
+	Stage           string
+	EmailIDs        []uint32
+	IncludeReplayed bool
+	Limit           int
+}
+
+// NewEmailDeadLetter creates the dead letter of an email rejected in the stage
+// the invalid UTF-8 is replaced to store the payload and the error as text
+func NewEmailDeadLetter(stage string, email Email, err error) DeadLetter {
+	return NewResultDeadLetter(EmailResult{Email: &email, Error: err, Stage: stage})
+}
+
+// NewResultDeadLetter creates the dead letter of a result with error of a source
+func NewResultDeadLetter(result EmailResult) DeadLetter {
+	letter := DeadLetter{
+		Stage:     result.Stage,
+		Raw:       strings.ToValidUTF8(result.Raw, "�"),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if result.Error != nil {
+		letter.Error = strings.ToValidUTF8(result.Error.Error(), "�")
+	}
+
+	if result.Email != nil {
+		payload, _ := json.Marshal(result.Email)
+		letter.EmailID = result.Email.ID
+		letter.Payload = string(payload)
+	}
+
+	return letter
+}
+
+// Email returns the email of the payload
+func (d DeadLetter) Email() (*Email, error) {
+	if d.Payload == "" {
+		return nil, errors.New("the dead letter has not payload")
+	}
+
+	email := &Email{}
+	if err := json.Unmarshal([]byte(d.Payload), email); err != nil {
+		return nil, err
+	}
+
+	return email, nil
+}
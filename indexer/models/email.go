@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
 
 // Email represents an email
 type Email struct {
@@ -11,3 +16,24 @@ type Email struct {
 	To      string    `json:"to"`
 	Content string    `json:"content"`
 }
+
+// Validate checks the email can be stored
+// the id and the date are required and the text must be valid UTF-8
+func (e *Email) Validate() error {
+	if e.ID == 0 {
+		return errors.New("id is required")
+	}
+
+	if e.Date.IsZero() {
+		return errors.New("date is required")
+	}
+
+	fields := map[string]string{"subject": e.Subject, "from": e.From, "to": e.To, "content": e.Content}
+	for name, value := range fields {
+		if !utf8.ValidString(value) {
+			return fmt.Errorf("%s is not valid UTF-8", name)
+		}
+	}
+
+	return nil
+}
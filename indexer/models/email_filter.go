@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// EmailFilter represents the filters to read emails from the database
+// IDs: only these ids, ignored if empty
+// FromID: minimum id, ignored if 0
+// ToID: maximum id, ignored if 0
+// DateFrom: minimum date, ignored if nil
+// DateTo: maximum date, ignored if nil
+type EmailFilter struct {
+	IDs      []uint32
+	FromID   uint32
+	ToID     uint32
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+// Types of the entities extracted from the emails
+const (
+	EntityTypePerson       = "person"
+	EntityTypeOrganization = "organization"
+	EntityTypePlace        = "place"
+)
+
+// Fields of the email where the entities are extracted
+const (
+	EntityFieldSubject = "subject"
+	EntityFieldContent = "content"
+)
+
+// EmailEntity represents a mention of a person, an organization or a place in an email
+// EmailID: id of the email
+// Entity: name of the entity, the canonical name of the gazetteer or the text matched by a pattern
+// Type: person, organization or place
+// Field: field of the email, subject or content
+// Start: offset in bytes of the start of the mention in the field
+// End: offset in bytes of the end of the mention in the field
+type EmailEntity struct {
+	EmailID uint32 `json:"emailId"`
+	Entity  string `json:"entity"`
+	Type    string `json:"type"`
+	Field   string `json:"field"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// IndexRunStateType represents the state of an index run
+type IndexRunStateType string
+
+const (
+	IndexRunStateRunning  IndexRunStateType = "running"
+	IndexRunStateFinished IndexRunStateType = "finished"
+	IndexRunStateFailed   IndexRunStateType = "failed"
+	// IndexRunStateInterrupted is a run stopped by a shutdown before scraping all its pages
+	IndexRunStateInterrupted IndexRunStateType = "interrupted"
+)
+
+// IndexRun represents an invocation of the index command
+// ID: id of the run
+// FromPage: first page to index
+// ToPage: last page to index
+// Pages: number of pages to index, they are not contiguous when the run resumes the unfinished pages
+// Pagination: emails by page of the listing
+// BatchSize: emails by insert
+// Parallelism: parallel requests of the scraper
+// State: state of the run
+// Error: error that stopped the run
+// StartedAt: date when the run started
+// FinishedAt: date when the run finished, nil if it is running
+// PagesFinished: pages scraped
+// PagesWithErrors: pages scraped with an error in a row
+// Stats: totals of the emails by stage
+type IndexRun struct {
+	ID              int64             `json:"id"`
+	FromPage        int               `json:"fromPage"`
+	ToPage          int               `json:"toPage"`
+	Pages           int               `json:"pages"`
+	Pagination      int               `json:"pagination"`
+	BatchSize       int               `json:"batchSize"`
+	Parallelism     int               `json:"parallelism"`
+	State           IndexRunStateType `json:"state"`
+	Error           string            `json:"error"`
+	StartedAt       time.Time         `json:"startedAt"`
+	FinishedAt      *time.Time        `json:"finishedAt"`
+	PagesFinished   int               `json:"pagesFinished"`
+	PagesWithErrors int               `json:"pagesWithErrors"`
+	Stats           IndexStats        `json:"stats"`
+}
+
+// Duration returns the duration of the run, until now if it is running
+func (r IndexRun) Duration() time.Duration {
+	if r.FinishedAt == nil {
+		return time.Since(r.StartedAt)
+	}
+
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// PageCount returns the number of pages of the run
+// the runs stored before the count have every page of the range
+func (r IndexRun) PageCount() int {
+	if r.Pages > 0 {
+		return r.Pages
+	}
+
+	return r.ToPage - r.FromPage + 1
+}
+
+// AddPageResult counts the pages finished
+func (r *IndexRun) AddPageResult(result PageResult) {
+	if result.State != PageResultStateFinished {
+		return
+	}
+
+	r.PagesFinished++
+	if result.Error != "" {
+		r.PagesWithErrors++
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Types of search of the saved queries
+const (
+	SavedQueryTypeAND = "AND"
+	SavedQueryTypeOR  = "OR"
+)
+
+// SavedDateSearch represents the date filter of a saved query
+// Operator: =, <, <=, > or >=
+type SavedDateSearch struct {
+	Date     *time.Time `json:"date,omitempty"`
+	Operator string     `json:"operator"`
+}
+
+// SavedQuery represents the QuerySearch of the API stored with a saved search
+// Query: words to search separated by spaces, already sanitized by the API
+// TypeSearch: AND or OR
+// Entities: entities mentioned in the emails
+// Tags: tags of the emails
+// Annotation: text contained in the notes of the emails
+// From, To: text contained in the sender and the recipients, without case
+// DateFrom, DateTo: first and last day of the emails, both included
+//
+// the query is matched as the search of the API with the same QuerySearch, the matches of the indexer are the results of the API:
+// the words are searched in the search index, the query doesn't search if its words have less than 2 characters,
+// all the filters are required, the text filters are compared without case and the days are the days in UTC
+// the shared cases of both are in api/services/testdata/saved_queries.json
+type SavedQuery struct {
+	Query      string          `json:"query"`
+	TypeSearch string          `json:"type"`
+	DateSearch SavedDateSearch `json:"dateSearch"`
+	Entities   []string        `json:"entities"`
+	Tags       []string        `json:"tags"`
+	Annotation string          `json:"annotation"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	DateFrom   *time.Time      `json:"dateFrom,omitempty"`
+	DateTo     *time.Time      `json:"dateTo,omitempty"`
+}
+
+// SavedSearch represents a search saved by a user of the API
+// EvaluatedAt: last time the indexer recorded the matches, nil if it was never evaluated
+type SavedSearch struct {
+	ID          int64      `json:"id"`
+	Owner       string     `json:"owner"`
+	Name        string     `json:"name"`
+	Query       SavedQuery `json:"query"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	EvaluatedAt *time.Time `json:"evaluatedAt,omitempty"`
+}
+
+// SavedSearchAlert represents the new matches of a saved search sent to the webhook
+type SavedSearchAlert struct {
+	SavedSearchID int64     `json:"savedSearchId"`
+	Owner         string    `json:"owner"`
+	Name          string    `json:"name"`
+	Collection    string    `json:"collection"`
+	RunID         int64     `json:"runId,omitempty"`
+	EmailIDs      []uint32  `json:"emailIds"`
+	MatchedAt     time.Time `json:"matchedAt"`
+}
@@ -1,8 +1,22 @@
 package models
 
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+const maxErrorSummaryLength = 120 // Maximum length of an error in the summary of the stats
+
+var numbersPattern = regexp.MustCompile(`\d+`)
+
+// EmailResult represents an email read by a source or the error reading it
+// Stage: stage of the source, used in the dead letter when there is an error
+// Raw: input of the source, used to replay the dead letter
 type EmailResult struct {
 	Email *Email `json:"email"`
 	Error error  `json:"error"`
+	Stage string `json:"stage"`
+	Raw   string `json:"raw"`
 }
 
 // PageResultStateType represents the state of a page
@@ -12,6 +26,8 @@ const (
 	PageResultStatePending    PageResultStateType = "pending"
 	PageResultStateProcessing PageResultStateType = "processing"
 	PageResultStateFinished   PageResultStateType = "finished"
+	// PageResultStateInterrupted is a page stopped by a shutdown, it is indexed again with index --resume
+	PageResultStateInterrupted PageResultStateType = "interrupted"
 )
 
 // PageResult represents the result of a page
@@ -23,5 +39,78 @@ type PageResult struct {
 	Page  int                 `json:"page"`
 	Error string              `json:"error"`
 	Total int                 `json:"total"`
-	State PageResultStateType `json:"state"` // PageResultStatePending, PageResultStateProcessing, PageResultStateFinished
+	State PageResultStateType `json:"state"` // PageResultStatePending, PageResultStateProcessing, PageResultStateFinished, PageResultStateInterrupted
+}
+
+// IndexStats represents the totals of an indexing process
+// Received: emails read from the source, with or without error
+// Inserted: emails inserted in the database
+// Duplicated: emails skipped because they already exist
+// Errors: emails with error or not inserted
+// DeadLetters: emails sent to the dead letters by stage
+// ErrorSummary: dead letters by error, the numbers of the error are replaced with N to group them
+type IndexStats struct {
+	Received     int            `json:"received"`
+	Inserted     int            `json:"inserted"`
+	Duplicated   int            `json:"duplicated"`
+	Errors       int            `json:"errors"`
+	DeadLetters  map[string]int `json:"deadLetters"`
+	ErrorSummary map[string]int `json:"errorSummary"`
+}
+
+// AddDeadLetters counts the dead letters by stage and by error
+func (s *IndexStats) AddDeadLetters(letters ...DeadLetter) {
+	if len(letters) == 0 {
+		return
+	}
+
+	if s.DeadLetters == nil {
+		s.DeadLetters = make(map[string]int)
+	}
+
+	if s.ErrorSummary == nil {
+		s.ErrorSummary = make(map[string]int)
+	}
+
+	for _, letter := range letters {
+		s.DeadLetters[letter.Stage]++
+		s.ErrorSummary[summarizeError(letter.Error)]++
+	}
+}
+
+// Merge adds the totals of other stats
+func (s *IndexStats) Merge(other IndexStats) {
+	s.Received += other.Received
+	s.Inserted += other.Inserted
+	s.Duplicated += other.Duplicated
+	s.Errors += other.Errors
+
+	for stage, total := range other.DeadLetters {
+		if s.DeadLetters == nil {
+			s.DeadLetters = make(map[string]int)
+		}
+		s.DeadLetters[stage] += total
+	}
+
+	for message, total := range other.ErrorSummary {
+		if s.ErrorSummary == nil {
+			s.ErrorSummary = make(map[string]int)
+		}
+		s.ErrorSummary[message] += total
+	}
+}
+
+// summarizeError returns the error without the numbers and cut to maxErrorSummaryLength bytes
+// the cut is done at the start of a character to keep the summary valid UTF-8
+func summarizeError(message string) string {
+	message = numbersPattern.ReplaceAllString(message, "N")
+	if len(message) > maxErrorSummaryLength {
+		cut := maxErrorSummaryLength
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut]
+	}
+
+	return message
 }
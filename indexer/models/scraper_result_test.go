@@ -0,0 +1,19 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeError(t *testing.T) {
+	assert.Equal(t, "invalid email N of page N", summarizeError("invalid email 1234 of page 7"))
+
+	// the cut doesn't split the characters of two bytes
+	message := "a" + strings.Repeat("é", 100)
+	summary := summarizeError(message)
+	assert.True(t, utf8.ValidString(summary))
+	assert.Equal(t, "a"+strings.Repeat("é", 59), summary)
+}
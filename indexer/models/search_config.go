@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SearchConfig represents the text search configuration of the search table of a collection
+// Config: configuration of the search table, the text search config in cockroach and the FTS5 tokenizer in SQLite
+// NextConfig: configuration of the reindex in progress, nil if there is none
+// UpdatedAt: date when the configuration changed
+type SearchConfig struct {
+	Config     string    `json:"config"`
+	NextConfig *string   `json:"nextConfig"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,67 @@
+package models
+
+// IDRange represents a range of email ids, both ends included
+type IDRange struct {
+	From uint32 `json:"from"`
+	To   uint32 `json:"to"`
+}
+
+// Len returns the number of ids of the range
+func (r IDRange) Len() int {
+	return int(r.To-r.From) + 1
+}
+
+// IDs returns the ids of the range
+func (r IDRange) IDs() []uint32 {
+	ids := make([]uint32, 0, r.Len())
+	for id := r.From; id <= r.To && id >= r.From; id++ {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// FindGaps returns the ranges of ids between from and to that are not in ids
+// ids must be sorted, the ids out of the range are ignored
+func FindGaps(ids []uint32, from, to uint32) []IDRange {
+	gaps := make([]IDRange, 0)
+	next := from
+	for _, id := range ids {
+		if id < next || id > to {
+			continue
+		}
+
+		if id > next {
+			gaps = append(gaps, IDRange{From: next, To: id - 1})
+		}
+		next = id + 1
+	}
+
+	if next <= to && next >= from {
+		gaps = append(gaps, IDRange{From: next, To: to})
+	}
+
+	return gaps
+}
+
+// VerifyReport represents the reconciliation of a collection with the site
+// Expected: range of ids reported by the site
+// Stored: ids of the range stored in the collection
+// Gaps: ranges of ids of the site missing in the collection
+// ShortPages: finished pages with less rows than the pagination, the last page is not included
+type VerifyReport struct {
+	Expected   IDRange      `json:"expected"`
+	Stored     int          `json:"stored"`
+	Gaps       []IDRange    `json:"gaps"`
+	ShortPages []PageResult `json:"shortPages"`
+}
+
+// Missing returns the number of ids missing in the collection
+func (r VerifyReport) Missing() int {
+	total := 0
+	for _, gap := range r.Gaps {
+		total += gap.Len()
+	}
+
+	return total
+}
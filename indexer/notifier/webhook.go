@@ -0,0 +1,130 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"indexer/models"
+)
+
+// Webhook posts the alerts of the saved searches as JSON to a local URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a Webhook of the URL
+// the host of the URL must be localhost, a loopback or a private address, the alerts are not sent outside the network
+func NewWebhook(rawURL string, timeout time.Duration) (*Webhook, error) {
+	if err := ValidateLocalURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	// the address is checked again when dialing, the name could resolve to another address after the validation
+	dialer := &net.Dialer{Timeout: timeout, Control: controlLocalAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	// the redirects are not followed, the location could be outside the network
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &Webhook{url: rawURL, client: client}, nil
+}
+
+// controlLocalAddress rejects the connections to addresses that are not a loopback or private
+func controlLocalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid webhook address %s: %w", address, err)
+	}
+
+	if !isLocalIP(net.ParseIP(host)) {
+		return fmt.Errorf("invalid webhook address %s: the address must be a loopback or a private address", address)
+	}
+
+	return nil
+}
+
+// isLocalIP checks the IP is a loopback or a private address
+func isLocalIP(ip net.IP) bool {
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
+// ValidateLocalURL checks the URL is http or https and its host is localhost, a loopback or a private address
+// a name is valid if all its addresses are local, the name of a container of the same network
+func ValidateLocalURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid webhook url %s: the scheme must be http or https", rawURL)
+	}
+
+	host := u.Hostname()
+	if host == "localhost" {
+		return nil
+	}
+
+	// the names are resolved, all their addresses must be local
+	addresses := []string{host}
+	if net.ParseIP(host) == nil {
+		addresses, err = net.LookupHost(host)
+		if err != nil {
+			return fmt.Errorf("invalid webhook url %s: %w", rawURL, err)
+		}
+	}
+
+	for _, address := range addresses {
+		if !isLocalIP(net.ParseIP(address)) {
+			return fmt.Errorf("invalid webhook url %s: the host must be localhost, a loopback or a private address", rawURL)
+		}
+	}
+
+	return nil
+}
+
+// Send posts the alert, the responses that are not 2xx are errors
+func (w *Webhook) Send(ctx context.Context, alert models.SavedSearchAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLocalURL(t *testing.T) {
+	ttc := []struct {
+		name  string
+		url   string
+		valid bool
+	}{
+		{"must accept localhost", "http://localhost:9000/alerts", true},
+		{"must accept a loopback", "http://127.0.0.1:9000/alerts", true},
+		{"must accept a private address", "https://10.0.0.12/alerts", true},
+		{"must reject a public address", "http://8.8.8.8/alerts", false},
+		{"must reject another scheme", "ftp://localhost/alerts", false},
+		{"must reject an invalid url", "http://[::1", false},
+	}
+
+	for _, tt := range ttc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLocalURL(tt.url)
+			assert.Equal(t, tt.valid, err == nil, err)
+		})
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	var received models.SavedSearchAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook(server.URL, time.Second)
+	assert.NoError(t, err)
+
+	alert := models.SavedSearchAlert{SavedSearchID: 3, Owner: "analyst", Name: "libya", Collection: "emails_hillary", RunID: 12, EmailIDs: []uint32{4, 8}}
+	assert.NoError(t, webhook.Send(context.Background(), alert))
+	assert.Equal(t, alert.EmailIDs, received.EmailIDs)
+	assert.Equal(t, "libya", received.Name)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	webhook, err = NewWebhook(failing.URL, time.Second)
+	assert.NoError(t, err)
+	assert.Error(t, webhook.Send(context.Background(), alert))
+}
+
+func TestWebhookDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed = true
+	}))
+	defer target.Close()
+
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+
+	webhook, err := NewWebhook(redirect.URL, time.Second)
+	assert.NoError(t, err)
+	assert.Error(t, webhook.Send(context.Background(), models.SavedSearchAlert{}))
+	assert.False(t, followed)
+}
+
+func TestControlLocalAddress(t *testing.T) {
+	assert.NoError(t, controlLocalAddress("tcp", "127.0.0.1:9000", nil))
+	assert.NoError(t, controlLocalAddress("tcp", "[::1]:9000", nil))
+	assert.NoError(t, controlLocalAddress("tcp", "192.168.1.4:80", nil))
+	assert.Error(t, controlLocalAddress("tcp", "8.8.8.8:80", nil))
+	assert.Error(t, controlLocalAddress("tcp", "8.8.8.8", nil))
+}
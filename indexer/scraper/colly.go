@@ -1,15 +1,18 @@
 package scraper
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
+	"indexer/metrics"
 	"indexer/models"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
 	log "github.com/sirupsen/logrus"
 )
@@ -17,9 +20,11 @@ import (
 // Scrapper represents the scraper
 // parallelism: number of parallel requests
 // delay: delay between requests in seconds
+// pipeline: workers and queues of the stages
 type Scrapper struct {
 	parallelism int
 	delay       int
+	pipeline    PipelineConfig
 }
 
 // NewScrapper creates a new scrapper
@@ -37,9 +42,26 @@ func NewScrapper(parallelism int, delay int) *Scrapper {
 	return &Scrapper{
 		parallelism: parallelism,
 		delay:       delay,
+		pipeline:    DefaultPipelineConfig(parallelism),
 	}
 }
 
+// SetPipelineConfig sets the workers and the queues of the stages
+// the stages without workers use 1
+func (s *Scrapper) SetPipelineConfig(pipeline PipelineConfig) {
+	s.pipeline = pipeline.normalize()
+}
+
+// PipelineConfig returns the workers and the queues of the stages
+func (s *Scrapper) PipelineConfig() PipelineConfig {
+	return s.pipeline
+}
+
+// Parallelism returns the number of parallel requests
+func (s *Scrapper) Parallelism() int {
+	return s.parallelism
+}
+
 // PaginationWikileaks represents the pagination parameter for the WikiLeaks API
 type PaginationWikileaks int
 
@@ -62,104 +84,118 @@ func (p PaginationWikileaks) IsValid() bool {
 // ScrapeEmails scrapes emails from the WikiLeaks API
 // fromPage: start page
 // toPage: end page
+// the rest of the params are the same as ScrapePages
+func (s *Scrapper) ScrapeEmails(ctx context.Context, fromPage int, toPage int, pagination PaginationWikileaks, emailsQueue chan<- models.EmailResult, pagesQueueUpdater chan<- models.PageResult, intervalUpdate int) error {
+	err := s.validateParams(fromPage, toPage, int(pagination))
+	if err != nil {
+		closeQueues(emailsQueue, pagesQueueUpdater)
+		return err
+	}
+
+	pages := make([]int, 0, toPage-fromPage+1)
+	for page := fromPage; page <= toPage; page++ {
+		pages = append(pages, page)
+	}
+
+	return s.ScrapePages(ctx, pages, pagination, emailsQueue, pagesQueueUpdater, intervalUpdate)
+}
+
+// ScrapePages scrapes the emails of the pages from the WikiLeaks API
+// the emails go through the stages of the pipeline: listing fetch -> row parse -> content fetch
+// every stage has its workers and a buffered queue, a full queue blocks the previous stage
+// when ctx is cancelled no new requests are made, the queues are drained and the pages not scraped are left unfinished
+// pages: pages to scrape
 // pagination: pagination parameter
-// emailsQueue: channel to send the emails
+// emailsQueue: channel to send the emails, it is the queue of the insert stage
 // pagesQueueUpdater: channel to update the pages state
 // intervalUpdate: interval to update the pages state
-func (s *Scrapper) ScrapeEmails(fromPage int, toPage int, pagination PaginationWikileaks, emailsQueue chan<- models.EmailResult, pagesQueueUpdater chan<- models.PageResult, intervalUpdate int) error {
+// returns an error wrapping ctx.Err() if the scrape was interrupted
+func (s *Scrapper) ScrapePages(ctx context.Context, pages []int, pagination PaginationWikileaks, emailsQueue chan<- models.EmailResult, pagesQueueUpdater chan<- models.PageResult, intervalUpdate int) error {
+
+	// the queues are closed even if the params are not valid to stop the readers
+	defer closeQueues(emailsQueue, pagesQueueUpdater)
 
 	if emailsQueue == nil {
 		return errors.New("emailsQueue is nil")
 	}
 
-	err := s.validateParams(fromPage, toPage, int(pagination))
-	if err != nil {
-		return err
+	if len(pages) == 0 {
+		return errors.New("there are no pages to scrape")
+	}
+
+	for _, page := range pages {
+		if page < 1 {
+			return fmt.Errorf("page must be greater than 0: %d", page)
+		}
 	}
 
 	if !pagination.IsValid() {
 		return fmt.Errorf("the pagination send is not supported by the api: %d", pagination)
 	}
 
-	semaphore := models.NewSemaphore(50)
-	c := SetupWikileaksCollector(s.parallelism, s.delay, true)
+	pipeline := s.pipeline
+	rowsQueue := make(chan rowTask, pipeline.QueueSize)
+	contentQueue := make(chan contentTask, pipeline.QueueSize)
+	tracker := newPageTracker(pagesQueueUpdater, intervalUpdate)
+
+	s.setPipelineMetrics(emailsQueue)
+	defer s.resetPipelineMetrics()
+
+	var parseWg, contentWg sync.WaitGroup
+	for i := 0; i < pipeline.ParseWorkers; i++ {
+		parseWg.Add(1)
+		go func() {
+			defer parseWg.Done()
+			parseRows(ctx, rowsQueue, contentQueue, emailsQueue, tracker)
+		}()
+	}
+
+	for i := 0; i < pipeline.ContentWorkers; i++ {
+		contentWg.Add(1)
+		go func() {
+			defer contentWg.Done()
+			s.fetchContents(ctx, contentQueue, emailsQueue, tracker)
+		}()
+	}
+
+	c := SetupWikileaksCollector(pipeline.ListingWorkers, s.delay, true)
+	// cancels the requests in flight
+	c.Context = ctx
 
 	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+
 		log.Info("Visiting:", r.URL.String())
 	})
 
+	// send the rows to the parse stage, blocks the listing workers if the queue is full
 	c.OnHTML(".table.search-result tbody", func(e *colly.HTMLElement) {
-		var total int64 = 0
-		var wg sync.WaitGroup
+		page, err := strconv.Atoi(e.Request.Ctx.Get("page"))
+		if err != nil {
+			log.Errorf("failed to convert page to int: page %s, error %v", e.Request.Ctx.Get("page"), err)
+			return
+		}
 
-		// process rows
+		total := 0
 		e.ForEach("tr", func(i int, row *colly.HTMLElement) {
-			semaphore.Acquire()
-			wg.Add(1)
-			go func() {
-				defer semaphore.Release()
-				defer wg.Done()
-				current := atomic.AddInt64(&total, 1)
-
-				// update page state every intervalUpdate rows
-				if pagesQueueUpdater != nil && int(current)%intervalUpdate == 0 {
-					pageStr := e.Request.Ctx.Get("page")
-					page, err := strconv.Atoi(pageStr)
-
-					if err == nil {
-						s.updatePageState(pagesQueueUpdater, page, int(current), nil, models.PageResultStateProcessing)
-					}
-				}
-
-				err := error(nil)
-				email := models.Email{}
-
-				// log every 10 rows just in debug
-				pageStr := e.Request.Ctx.Get("page")
-
-				row.ForEach("td", func(tdIndex int, columns *colly.HTMLElement) {
-					// if there is an error, stop processing the row
-					if err != nil {
-						return
-					}
-					err = processRow(tdIndex, columns, &email)
-					if err != nil {
-						log.WithFields(log.Fields{"error": err, "id": email.ID}).Error("Error processing email")
-					}
-
-					// scrapt email content if it is the last column
-					if tdIndex == 4 && email.ID > 0 {
-						content, err := getMailContent(email.ID, c)
-						if err != nil {
-							log.WithFields(log.Fields{"error": err, "id": email.ID}).Error("Error getting email content")
-						}
-						email.Content = content
-					}
-				})
-
-				// log every 10 rows just in debug
-				if current%10 == 0 {
-					log.Trace("Page ", pageStr, " processed ", current, " rows")
-				}
-
-				if err == nil {
-					e.Request.Ctx.Put("error", "")
-				} else {
-					e.Request.Ctx.Put("error", err.Error())
-				}
-
-				emailsQueue <- models.EmailResult{Email: &email, Error: err}
+			raw, err := goquery.OuterHtml(row.DOM)
+			if err != nil {
+				log.WithFields(log.Fields{"error": err, "page": page}).Error("Error reading row")
+				return
+			}
 
-			}()
+			total++
+			send(rowsQueue, rowTask{page: page, raw: raw}, StageParse)
 		})
 
-		wg.Wait()
-		e.Request.Ctx.Put("total", strconv.Itoa(int(total)))
+		e.Request.Ctx.Put("total", strconv.Itoa(total))
 	})
 
-	// update page state when the page is finished
+	// the page is finished by the tracker when all its rows leave the pipeline
 	c.OnScraped(func(r *colly.Response) {
-		var scrapeErrStr string = ""
 		pageStr := r.Request.Ctx.Get("page")
 		page, err := strconv.Atoi(pageStr)
 		if err != nil {
@@ -167,25 +203,17 @@ func (s *Scrapper) ScrapeEmails(fromPage int, toPage int, pagination PaginationW
 			return
 		}
 
-		totalStr := r.Request.Ctx.Get("total")
-		total, err := strconv.Atoi(totalStr)
-		if err != nil {
-			log.Errorf("failed to convert total to int: total %s, error %v", totalStr, err)
-			return
-		}
-
-		if errStr := r.Request.Ctx.Get("error"); errStr != "" {
-			scrapeErrStr = errStr
-		}
-
-		if pagesQueueUpdater != nil {
-			pagesQueueUpdater <- models.PageResult{Page: page, Error: scrapeErrStr, Total: total, State: models.PageResultStateFinished}
-		}
+		total, _ := strconv.Atoi(r.Request.Ctx.Get("total"))
+		tracker.listed(page, total)
 		log.Debug("Scraped page finish:", r.Request.URL.String())
 	})
 
-	log.Info("Setup Scraping pages from", fromPage, "to", toPage)
-	for page := fromPage; page <= toPage; page++ {
+	log.Info("Setup Scraping ", len(pages), " pages")
+	for _, page := range pages {
+		if ctx.Err() != nil {
+			break
+		}
+
 		url := s.getPageUrlWithPagination(page, pagination)
 		ctx := colly.NewContext()
 		ctx.Put("page", strconv.Itoa(page))
@@ -195,19 +223,104 @@ func (s *Scrapper) ScrapeEmails(fromPage int, toPage int, pagination PaginationW
 			log.Error("Error creating request:", err)
 		}
 	}
-	log.Info("Setup Finish Scraping pages from ", fromPage, " to ", toPage)
+	log.Info("Setup Finish Scraping ", len(pages), " pages")
 
+	// every stage closes the queue of the next one when its workers finish
 	c.Wait()
+	close(rowsQueue)
+	parseWg.Wait()
+	close(contentQueue)
+	contentWg.Wait()
+	tracker.close()
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("scraping interrupted: %w", ctx.Err())
+	}
+
+	return nil
+}
+
+// closeQueues closes the queues of the emails and the pages, they can be nil
+func closeQueues(emailsQueue chan<- models.EmailResult, pagesQueueUpdater chan<- models.PageResult) {
+	if emailsQueue != nil {
+		close(emailsQueue)
+	}
 
 	if pagesQueueUpdater != nil {
 		close(pagesQueueUpdater)
 	}
+}
 
-	if emailsQueue != nil {
-		close(emailsQueue)
+// parseRows is a worker of the parse stage
+// the rows with error are sent to the indexer to be stored in the dead letters
+// the rows are discarded when ctx is cancelled, their page is not finished
+func parseRows(ctx context.Context, rowsQueue <-chan rowTask, contentQueue chan<- contentTask, emailsQueue chan<- models.EmailResult, tracker *pageTracker) {
+	for task := range rowsQueue {
+		if ctx.Err() != nil {
+			continue
+		}
+
+		email, err := ParseRow(task.raw)
+		if err != nil {
+			log.WithFields(log.Fields{"error": err, "page": task.page}).Error("Error processing email")
+			metrics.EmailsScraped.Inc()
+			// the row is kept to replay the dead letter when the parser is fixed
+			send(emailsQueue, models.EmailResult{Email: &models.Email{}, Error: err, Stage: models.StageScrape, Raw: task.raw}, StageInsert)
+			tracker.rowDone(task.page, err)
+			continue
+		}
+
+		send(contentQueue, contentTask{page: task.page, email: email}, StageContent)
 	}
+}
 
-	return nil
+// fetchContents is a worker of the content stage
+// the emails without content are sent to the indexer as before, the error is logged
+// the emails are discarded when ctx is cancelled, their page is not finished
+func (s *Scrapper) fetchContents(ctx context.Context, contentQueue <-chan contentTask, emailsQueue chan<- models.EmailResult, tracker *pageTracker) {
+	fetcher := newContentFetcher(ctx, s.delay)
+	for task := range contentQueue {
+		if ctx.Err() != nil {
+			continue
+		}
+
+		content, err := fetcher.Fetch(task.email.ID)
+		if ctx.Err() != nil {
+			continue
+		}
+
+		if err != nil {
+			metrics.ContentFetchErrors.Inc()
+			log.WithFields(log.Fields{"error": err, "id": task.email.ID}).Error("Error getting email content")
+		}
+		task.email.Content = content
+
+		metrics.EmailsScraped.Inc()
+		send(emailsQueue, models.EmailResult{Email: task.email}, StageInsert)
+		tracker.rowDone(task.page, nil)
+	}
+}
+
+// setPipelineMetrics records the workers and the size of the queues
+func (s *Scrapper) setPipelineMetrics(emailsQueue chan<- models.EmailResult) {
+	metrics.CollectorParallelism.Set(float64(s.pipeline.ListingWorkers))
+	metrics.PipelineWorkers.WithLabelValues(StageListing).Set(float64(s.pipeline.ListingWorkers))
+	metrics.PipelineWorkers.WithLabelValues(StageParse).Set(float64(s.pipeline.ParseWorkers))
+	metrics.PipelineWorkers.WithLabelValues(StageContent).Set(float64(s.pipeline.ContentWorkers))
+	metrics.PipelineQueueCapacity.WithLabelValues(StageParse).Set(float64(s.pipeline.QueueSize))
+	metrics.PipelineQueueCapacity.WithLabelValues(StageContent).Set(float64(s.pipeline.QueueSize))
+	metrics.PipelineQueueCapacity.WithLabelValues(StageInsert).Set(float64(cap(emailsQueue)))
+}
+
+// resetPipelineMetrics sets the workers and the queues to 0 when the scraping finishes
+func (s *Scrapper) resetPipelineMetrics() {
+	metrics.CollectorParallelism.Set(0)
+	for _, stage := range []string{StageListing, StageParse, StageContent} {
+		metrics.PipelineWorkers.WithLabelValues(stage).Set(0)
+	}
+	for _, stage := range []string{StageParse, StageContent, StageInsert} {
+		metrics.PipelineQueueLength.WithLabelValues(stage).Set(0)
+	}
 }
 
 // GetLastPage gets the last page of the emails
@@ -243,27 +356,161 @@ func (s *Scrapper) GetLastPage(pagination PaginationWikileaks) (int, error) {
 	return lastPage, nil
 }
 
+// CountEmails gets the number of emails listed by the site
+// it counts the rows of the last page, the other pages have pagination rows
+func (s *Scrapper) CountEmails(pagination PaginationWikileaks) (int, error) {
+	lastPage, err := s.GetLastPage(pagination)
+	if err != nil {
+		return 0, err
+	}
+
+	c := SetupWikileaksCollector(1, s.delay, false)
+	rows := 0
+	c.OnHTML(".table.search-result tbody tr", func(e *colly.HTMLElement) {
+		rows++
+	})
+
+	if err := c.Visit(s.getPageUrlWithPagination(lastPage, pagination)); err != nil {
+		return 0, err
+	}
+
+	return (lastPage-1)*int(pagination) + rows, nil
+}
+
 // getPageUrlWithPagination gets the url of the page with the pagination
 func (s *Scrapper) getPageUrlWithPagination(page int, pagination PaginationWikileaks) string {
 	return fmt.Sprintf("https://wikileaks.org/clinton-emails/?q=&mfrom=&mto=&title=&notitle=&date_from=&date_to=&nofrom=&noto=&sort=0&count=%d&page=%d#searchresult", pagination, page)
 }
 
+// ScrapeRow parses a row of the listing table and gets the content of the email
+// raw: HTML of the row, as it is stored in the dead letters
+func (s *Scrapper) ScrapeRow(raw string) (*models.Email, error) {
+	email, err := ParseRow(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	email.Content, err = newContentFetcher(context.Background(), s.delay).Fetch(email.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting email content: %w", err)
+	}
+
+	return email, nil
+}
+
+// ScrapeEmail gets an email by id from its page, it is used for the ids missing in the listing
+// the date, the sender, the recipient and the subject are read from the headers of the content
+func (s *Scrapper) ScrapeEmail(id uint32) (*models.Email, error) {
+	content, err := newContentFetcher(context.Background(), s.delay).Fetch(id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting email content: %w", err)
+	}
+
+	email := &models.Email{ID: id, Content: content}
+	if err := ParseContentHeaders(content, email); err != nil {
+		return nil, err
+	}
+
+	return email, nil
+}
+
+// contentDateLayouts are the formats of the Sent header of the emails
+var contentDateLayouts = []string{
+	"Monday, January 2, 2006 3:04 PM",
+	"Monday, January 2, 2006 15:04",
+	"January 2, 2006 3:04 PM",
+}
+
+// ParseContentHeaders fills the email with the From, Sent, To and Subject headers at the start of the content
+// returns an error if the date is not found
+func ParseContentHeaders(content string, email *models.Email) error {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("error parsing content: %w", err)
+	}
+
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(name) {
+		case "from":
+			if email.From == "" {
+				email.From = SanitizeHTML(value)
+			}
+		case "to":
+			if email.To == "" {
+				email.To = SanitizeHTML(value)
+			}
+		case "subject":
+			if email.Subject == "" {
+				email.Subject = SanitizeHTML(value)
+			}
+		case "sent", "date":
+			if !email.Date.IsZero() {
+				continue
+			}
+
+			for _, layout := range contentDateLayouts {
+				if t, err := time.Parse(layout, value); err == nil {
+					email.Date = t.UTC()
+					break
+				}
+			}
+		}
+	}
+
+	if email.Date.IsZero() {
+		return errors.New("the content has not a Sent header with the date")
+	}
+
+	return nil
+}
+
+// ParseRow parses the HTML of a row of the listing table without the content of the email
+func ParseRow(raw string) (*models.Email, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<table>" + raw + "</table>"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing row: %w", err)
+	}
+
+	email := &models.Email{}
+	columns := doc.Find("td")
+	if columns.Length() == 0 {
+		return nil, errors.New("the row has not columns")
+	}
+
+	columns.EachWithBreak(func(tdIndex int, column *goquery.Selection) bool {
+		err = processRow(tdIndex, column.Text(), email)
+		return err == nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return email, nil
+}
+
 // processRow processes a row of the table
 // tdIndex: index of the column
-// column: the column element
+// text: the text of the column
 // email: the email to fill
-func processRow(tdIndex int, column *colly.HTMLElement, email *models.Email) error {
+func processRow(tdIndex int, text string, email *models.Email) error {
 	switch tdIndex {
 	case 0:
-		id, err := strconv.Atoi(column.Text)
+		id, err := strconv.Atoi(text)
 		if err != nil {
-			log.Errorf("failed to convert '%s' to int: %v", column.Text, err)
+			log.Errorf("failed to convert '%s' to int: %v", text, err)
 			return err
 		}
 
 		email.ID = uint32(id)
 	case 1:
-		str := column.Text
+		str := text
 		layout := "2006-01-02 15:04"
 
 		t, err := time.Parse(layout, str)
@@ -274,11 +521,11 @@ func processRow(tdIndex int, column *colly.HTMLElement, email *models.Email) err
 
 		email.Date = t.UTC()
 	case 2:
-		email.Subject = SanitizeHTML(column.Text)
+		email.Subject = SanitizeHTML(text)
 	case 3:
-		email.From = SanitizeHTML(column.Text)
+		email.From = SanitizeHTML(text)
 	case 4:
-		email.To = SanitizeHTML(column.Text)
+		email.To = SanitizeHTML(text)
 	default:
 		log.Warnf("Unknown column index: %d", tdIndex)
 	}
@@ -286,40 +533,6 @@ func processRow(tdIndex int, column *colly.HTMLElement, email *models.Email) err
 	return nil
 }
 
-// Parse the mail content from the page
-// id: the id of the email
-// c: the collector to use
-// returns the content of the email and an error if any
-func getMailContent(id uint32, c *colly.Collector) (string, error) {
-	collectorContent := c.Clone()
-	collectorContent.Limit(&colly.LimitRule{
-		DomainGlob: "wikileaks.org",
-		Delay:      1 * time.Second,
-	})
-
-	collectorContent.Async = true
-
-	var err error
-	var html string
-	collectorContent.OnHTML("div#content", func(e *colly.HTMLElement) {
-		html, err = e.DOM.Html()
-		if err != nil {
-			log.Error("Error getting HTML:", err)
-			return
-		}
-	})
-
-	err = collectorContent.Visit("https://wikileaks.org/clinton-emails/emailid/" + fmt.Sprintf("%d", id))
-	if err != nil {
-		log.Error("Error visiting email content:", err)
-		return "", err
-	}
-
-	collectorContent.Wait()
-
-	return SanitizeHTML(html), nil
-}
-
 // validateParams validates the parameters for scraping
 // fromPage: page to start scraping from
 // toPage: page to end scraping at
@@ -345,16 +558,3 @@ func (s *Scrapper) validateParams(fromPage int, toPage int, pagination int) erro
 
 	return nil
 }
-
-func (s *Scrapper) updatePageState(pagesQueueUpdater chan<- models.PageResult, page int, total int, err error, state models.PageResultStateType) {
-	// update page state every intervalUpdate rows
-
-	strErr := ""
-	if err != nil {
-		strErr = err.Error()
-	}
-
-	if pagesQueueUpdater != nil {
-		pagesQueueUpdater <- models.PageResult{Page: page, Error: strErr, Total: total, State: state}
-	}
-}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRow(t *testing.T) {
+	row := `<tr><td>1234</td><td>2012-09-11 22:00</td><td>Benghazi</td><td>Cheryl Mills</td><td>H</td></tr>`
+
+	email, err := ParseRow(row)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1234), email.ID)
+	assert.Equal(t, time.Date(2012, 9, 11, 22, 0, 0, 0, time.UTC), email.Date)
+	assert.Equal(t, "Benghazi", email.Subject)
+	assert.Equal(t, "Cheryl Mills", email.From)
+	assert.Equal(t, "H", email.To)
+
+	_, err = ParseRow(`<tr><td>abc</td><td>2012-09-11 22:00</td></tr>`)
+	assert.Error(t, err)
+
+	_, err = ParseRow(`<p>no columns</p>`)
+	assert.Error(t, err)
+}
+
+func TestScrapePagesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	emailsQueue := make(chan models.EmailResult, 1)
+	pagesQueue := make(chan models.PageResult, 1)
+	err := NewScrapper(1, 0).ScrapePages(ctx, []int{1, 2}, PaginationWikileaks200, emailsQueue, pagesQueue, 50)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the queues are closed without results, the pages are left unfinished
+	_, ok := <-emailsQueue
+	assert.False(t, ok)
+	_, ok = <-pagesQueue
+	assert.False(t, ok)
+}
+
+func TestScrapePagesInvalid(t *testing.T) {
+	emailsQueue := make(chan models.EmailResult)
+	err := NewScrapper(1, 0).ScrapePages(context.Background(), nil, PaginationWikileaks200, emailsQueue, nil, 50)
+	assert.Error(t, err)
+
+	_, ok := <-emailsQueue
+	assert.False(t, ok)
+}
+
+func TestScrapePagesWithoutEmailsQueue(t *testing.T) {
+	pagesQueue := make(chan models.PageResult)
+	err := NewScrapper(1, 0).ScrapePages(context.Background(), []int{1}, PaginationWikileaks200, nil, pagesQueue, 50)
+	assert.Error(t, err)
+
+	// the reader of the pages is not blocked
+	_, ok := <-pagesQueue
+	assert.False(t, ok)
+}
+
+func TestParseContentHeaders(t *testing.T) {
+	content := `<pre>UNCLASSIFIED
+From: Sullivan, Jacob J
+Sent: Tuesday, September 11, 2012 10:42 PM
+To: H
+Subject: Benghazi
+Body of the email</pre>`
+
+	email := &models.Email{ID: 1234}
+	assert.NoError(t, ParseContentHeaders(content, email))
+	assert.Equal(t, "Sullivan, Jacob J", email.From)
+	assert.Equal(t, "H", email.To)
+	assert.Equal(t, "Benghazi", email.Subject)
+	assert.Equal(t, time.Date(2012, 9, 11, 22, 42, 0, 0, time.UTC), email.Date)
+
+	assert.Error(t, ParseContentHeaders("<p>no headers</p>", &models.Email{ID: 1}))
+}
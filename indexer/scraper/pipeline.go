@@ -0,0 +1,248 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"indexer/metrics"
+	"indexer/models"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Stages of the scrape pipeline, used as label of the metrics
+const (
+	StageListing = "listing" // fetches the listing pages
+	StageParse   = "parse"   // parses the rows of the listing pages
+	StageContent = "content" // fetches the content of the emails
+	StageInsert  = "insert"  // inserts the emails in batches, it is run by the indexer
+)
+
+// PipelineConfig represents the workers and the size of the queues of the scrape pipeline
+// listing fetch -> row parse -> content fetch -> batch insert
+// ListingWorkers: parallel requests to the listing pages
+// ParseWorkers: workers parsing the rows
+// ContentWorkers: parallel requests to the content of the emails
+// InsertWorkers: workers inserting the batches
+// QueueSize: buffer of the queue in front of every stage
+type PipelineConfig struct {
+	ListingWorkers int
+	ParseWorkers   int
+	ContentWorkers int
+	InsertWorkers  int
+	QueueSize      int
+}
+
+// DefaultPipelineConfig returns the pipeline with the parallelism of the scraper for the requests
+func DefaultPipelineConfig(parallelism int) PipelineConfig {
+	return PipelineConfig{
+		ListingWorkers: parallelism,
+		ParseWorkers:   4,
+		ContentWorkers: parallelism,
+		InsertWorkers:  1,
+		QueueSize:      200,
+	}
+}
+
+// normalize returns the config with at least one worker by stage
+func (p PipelineConfig) normalize() PipelineConfig {
+	p.ListingWorkers = max(p.ListingWorkers, 1)
+	p.ParseWorkers = max(p.ParseWorkers, 1)
+	p.ContentWorkers = max(p.ContentWorkers, 1)
+	p.InsertWorkers = max(p.InsertWorkers, 1)
+	p.QueueSize = max(p.QueueSize, 0)
+	return p
+}
+
+// rowTask is a row of a listing page waiting to be parsed
+type rowTask struct {
+	page int
+	raw  string
+}
+
+// contentTask is a parsed email waiting for its content
+type contentTask struct {
+	page  int
+	email *models.Email
+}
+
+// send puts the item in the queue of the stage and records the time blocked waiting for space
+func send[T any](queue chan<- T, item T, stage string) {
+	start := time.Now()
+	queue <- item
+	metrics.ObserveQueue(stage, len(queue), start)
+}
+
+// pageTracker counts the rows of every page that left the pipeline to send the state of the page
+// a page is finished when it is listed and all its rows are processed
+// the states are sent in order by its own goroutine, the workers don't wait for the reader of the queue
+type pageTracker struct {
+	mu             sync.Mutex
+	pages          map[int]*pageProgress
+	pending        []models.PageResult // states waiting to be sent, in the order they happened
+	closed         bool
+	wake           chan struct{} // wakes the sender when there are states or the tracker is closed
+	sent           chan struct{} // closed when the sender sent every state after close
+	queue          chan<- models.PageResult
+	intervalUpdate int
+}
+
+// pageProgress is the progress of a page
+type pageProgress struct {
+	listed bool
+	total  int
+	done   int
+	err    string
+}
+
+// newPageTracker creates a tracker that sends the states to the queue, queue can be nil
+// close must be called when the pipeline is finished to send the last states
+func newPageTracker(queue chan<- models.PageResult, intervalUpdate int) *pageTracker {
+	t := &pageTracker{
+		pages:          make(map[int]*pageProgress),
+		wake:           make(chan struct{}, 1),
+		sent:           make(chan struct{}),
+		queue:          queue,
+		intervalUpdate: intervalUpdate,
+	}
+	go t.sendStates()
+
+	return t
+}
+
+// progress returns the progress of the page, it must be called with the lock
+func (t *pageTracker) progress(page int) *pageProgress {
+	p, ok := t.pages[page]
+	if !ok {
+		p = &pageProgress{}
+		t.pages[page] = p
+	}
+	return p
+}
+
+// listed sets the number of rows of the page
+func (t *pageTracker) listed(page int, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.progress(page)
+	p.listed = true
+	p.total = total
+	t.finishIfDone(page, p)
+}
+
+// rowDone counts a row of the page that left the pipeline
+// err: error of the row, the last error is the error of the page
+func (t *pageTracker) rowDone(page int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.progress(page)
+	p.done++
+	if err != nil {
+		p.err = err.Error()
+	}
+
+	if t.intervalUpdate > 0 && p.done%t.intervalUpdate == 0 && (!p.listed || p.done < p.total) {
+		t.push(models.PageResult{Page: page, Error: p.err, Total: p.done, State: models.PageResultStateProcessing})
+	}
+
+	t.finishIfDone(page, p)
+}
+
+// finishIfDone sends the page as finished when all its rows are processed, it must be called with the lock
+func (t *pageTracker) finishIfDone(page int, p *pageProgress) {
+	if !p.listed || p.done < p.total {
+		return
+	}
+
+	delete(t.pages, page)
+	t.push(models.PageResult{Page: page, Error: p.err, Total: p.total, State: models.PageResultStateFinished})
+}
+
+// push adds a state to the states waiting to be sent, it must be called with the lock
+func (t *pageTracker) push(result models.PageResult) {
+	if t.queue == nil {
+		return
+	}
+
+	t.pending = append(t.pending, result)
+	t.signal()
+}
+
+// signal wakes the sender, it doesn't block if the sender is already woken
+func (t *pageTracker) signal() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sendStates sends the pending states to the queue without the lock until the tracker is closed
+func (t *pageTracker) sendStates() {
+	defer close(t.sent)
+
+	for range t.wake {
+		t.mu.Lock()
+		pending, closed := t.pending, t.closed
+		t.pending = nil
+		t.mu.Unlock()
+
+		for _, result := range pending {
+			t.queue <- result
+		}
+
+		if closed {
+			return
+		}
+	}
+}
+
+// close waits until the pending states are sent, the tracker can't be used after it
+func (t *pageTracker) close() {
+	t.mu.Lock()
+	t.closed = true
+	t.signal()
+	t.mu.Unlock()
+
+	<-t.sent
+}
+
+// contentFetcher gets the content of the emails with its own collector
+// it is used by a single content worker, the requests are sequential
+type contentFetcher struct {
+	collector *colly.Collector
+	html      string
+	err       error
+}
+
+// newContentFetcher creates a fetcher with a synchronous collector
+// ctx: cancels the request in flight
+// delay: delay between requests in seconds
+func newContentFetcher(ctx context.Context, delay int) *contentFetcher {
+	f := &contentFetcher{collector: SetupWikileaksCollector(1, delay, false)}
+	f.collector.Context = ctx
+	f.collector.OnHTML("div#content", func(e *colly.HTMLElement) {
+		f.html, f.err = e.DOM.Html()
+	})
+
+	return f
+}
+
+// Fetch returns the content of the email
+func (f *contentFetcher) Fetch(id uint32) (string, error) {
+	f.html, f.err = "", nil
+
+	if err := f.collector.Visit("https://wikileaks.org/clinton-emails/emailid/" + strconv.FormatUint(uint64(id), 10)); err != nil {
+		return "", fmt.Errorf("error visiting email content: %w", err)
+	}
+
+	if f.err != nil {
+		return "", fmt.Errorf("error getting HTML: %w", f.err)
+	}
+
+	return SanitizeHTML(f.html), nil
+}
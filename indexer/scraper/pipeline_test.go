@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"indexer/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageTracker(t *testing.T) {
+	queue := make(chan models.PageResult, 10)
+	tracker := newPageTracker(queue, 2)
+
+	// rows can leave the pipeline before the page is listed
+	tracker.rowDone(1, nil)
+	tracker.rowDone(1, errors.New("timeout"))
+	tracker.listed(1, 3)
+
+	processing := <-queue
+	assert.Equal(t, models.PageResultStateProcessing, processing.State)
+	assert.Equal(t, 2, processing.Total)
+	assert.Empty(t, queue)
+
+	tracker.rowDone(1, nil)
+	finished := <-queue
+	assert.Equal(t, models.PageResultStateFinished, finished.State)
+	assert.Equal(t, 3, finished.Total)
+	assert.Equal(t, "timeout", finished.Error)
+
+	// a page without rows is finished when it is listed
+	tracker.listed(2, 0)
+	empty := <-queue
+	assert.Equal(t, 2, empty.Page)
+	assert.Equal(t, models.PageResultStateFinished, empty.State)
+	tracker.close()
+}
+
+func TestPageTrackerSlowReader(t *testing.T) {
+	queue := make(chan models.PageResult)
+	tracker := newPageTracker(queue, 1)
+
+	// the reader saves every state in the database, it is slower than the workers
+	received := make([]models.PageResult, 0)
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		for result := range queue {
+			time.Sleep(20 * time.Millisecond)
+			received = append(received, result)
+		}
+	}()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				tracker.rowDone(1, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	tracker.listed(1, 20)
+
+	// the workers don't wait for the reader, 21 states take 420ms to read
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+
+	tracker.close()
+	close(queue)
+	<-read
+
+	// the states are read in order, the last one is the page finished
+	assert.Len(t, received, 21)
+	for i, result := range received[:20] {
+		assert.Equal(t, models.PageResultStateProcessing, result.State)
+		assert.Equal(t, i+1, result.Total)
+	}
+	assert.Equal(t, models.PageResultStateFinished, received[20].State)
+	assert.Equal(t, 20, received[20].Total)
+}
+
+func TestPipelineConfigNormalize(t *testing.T) {
+	config := PipelineConfig{QueueSize: -1}.normalize()
+	assert.Equal(t, PipelineConfig{ListingWorkers: 1, ParseWorkers: 1, ContentWorkers: 1, InsertWorkers: 1, QueueSize: 0}, config)
+}